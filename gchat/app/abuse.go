@@ -0,0 +1,332 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rilevamento abusi a finestra scorrevole con ban temporanei: centinaia
+// di messaggi identici o lo scraping scriptato degli endpoint dati
+// (chat-log, full_data) saturano il backend. Il gateway conta per chiave
+// utente/IP i messaggi identici e gli accessi agli endpoint dati in una
+// finestra scorrevole; al superamento delle soglie scatta un ban
+// temporaneo, registrato nel log di audit insieme a ogni rimozione.
+
+const (
+	abuseAuditFile         = "data/abuse_audit.json"
+	abuseDefaultWindowSecs = 60
+	abuseDefaultMaxSame    = 20  // messaggi identici nella finestra
+	abuseDefaultMaxData    = 120 // accessi endpoint dati nella finestra
+	abuseDefaultBanMinutes = 15
+	abuseAuditMaxEntries   = 1000
+)
+
+// AbuseConfig configura soglie e durata dei ban (sezione abuse)
+type AbuseConfig struct {
+	Enabled         bool `json:"enabled"`
+	WindowSeconds   int  `json:"window_seconds"`    // default 60
+	MaxIdentical    int  `json:"max_identical"`     // default 20
+	MaxDataRequests int  `json:"max_data_requests"` // default 120
+	BanMinutes      int  `json:"ban_minutes"`       // default 15
+}
+
+// AbuseBan è un ban temporaneo attivo
+type AbuseBan struct {
+	Key       string    `json:"key"` // user_id o IP
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AbuseAuditEntry traccia ogni azione di enforcement (ban o rimozione)
+type AbuseAuditEntry struct {
+	Action    string    `json:"action"` // ban | lift
+	Key       string    `json:"key"`
+	Reason    string    `json:"reason,omitempty"`
+	Actor     string    `json:"actor,omitempty"` // "system" o chi ha rimosso il ban
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type abuseState struct {
+	// timestamp per chiave+hash messaggio (messaggi identici)
+	identical map[string][]time.Time
+	// timestamp per chiave (accessi endpoint dati)
+	dataHits map[string][]time.Time
+	bans     map[string]*AbuseBan
+	audit    []AbuseAuditEntry
+	// ultima passata di pulizia dei bucket scaduti (vedi sweepStale)
+	lastSweep time.Time
+	mu        sync.Mutex
+	loaded    bool
+}
+
+var abuseGuard = &abuseState{
+	identical: map[string][]time.Time{},
+	dataHits:  map[string][]time.Time{},
+	bans:      map[string]*AbuseBan{},
+}
+
+func (s *abuseState) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(abuseAuditFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("ABUSE_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.audit); err != nil {
+		log.Printf("ABUSE_LOAD_ERROR: parse failed: %v", err)
+	}
+}
+
+// persistAudit salva il log di audit (chiamare con lock acquisito)
+func (s *abuseState) persistAudit() {
+	if len(s.audit) > abuseAuditMaxEntries {
+		s.audit = s.audit[len(s.audit)-abuseAuditMaxEntries:]
+	}
+	data, err := json.MarshalIndent(s.audit, "", "  ")
+	if err != nil {
+		log.Printf("ABUSE_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.WriteFile(abuseAuditFile, data, 0644); err != nil {
+		log.Printf("ABUSE_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// abuseWindow ritorna i parametri effettivi dalla config con i default
+func abuseWindow(config *Config) (time.Duration, int, int, time.Duration) {
+	window := config.Abuse.WindowSeconds
+	if window <= 0 {
+		window = abuseDefaultWindowSecs
+	}
+	maxSame := config.Abuse.MaxIdentical
+	if maxSame <= 0 {
+		maxSame = abuseDefaultMaxSame
+	}
+	maxData := config.Abuse.MaxDataRequests
+	if maxData <= 0 {
+		maxData = abuseDefaultMaxData
+	}
+	banMins := config.Abuse.BanMinutes
+	if banMins <= 0 {
+		banMins = abuseDefaultBanMinutes
+	}
+	return time.Duration(window) * time.Second, maxSame, maxData, time.Duration(banMins) * time.Minute
+}
+
+// abuseKey deriva la chiave di enforcement: user_id se noto, altrimenti IP
+func abuseKey(userID, clientIP string) string {
+	if userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + clientIP
+}
+
+// pruneWindow scarta i timestamp usciti dalla finestra
+func pruneWindow(hits []time.Time, window time.Duration) []time.Time {
+	cutoff := time.Now().Add(-window)
+	kept := hits[:0]
+	for _, t := range hits {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// sweepStale elimina i bucket il cui timestamp più recente è uscito dalla
+// finestra (lock acquisito). Senza questa passata ogni coppia distinta
+// utente/messaggio resterebbe in mappa per sempre; viene eseguita al più
+// una volta per finestra, durante le normali osservazioni.
+func (s *abuseState) sweepStale(window time.Duration) {
+	now := time.Now()
+	if now.Sub(s.lastSweep) < window {
+		return
+	}
+	s.lastSweep = now
+
+	cutoff := now.Add(-window)
+	removed := 0
+	for bucket, hits := range s.identical {
+		if len(hits) == 0 || hits[len(hits)-1].Before(cutoff) {
+			delete(s.identical, bucket)
+			removed++
+		}
+	}
+	for key, hits := range s.dataHits {
+		if len(hits) == 0 || hits[len(hits)-1].Before(cutoff) {
+			delete(s.dataHits, key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		log.Printf("ABUSE_SWEEP: rimossi %d bucket scaduti, attivi identical=%d data=%d",
+			removed, len(s.identical), len(s.dataHits))
+	}
+}
+
+// applyBan registra un ban temporaneo con voce di audit (lock acquisito)
+func (s *abuseState) applyBan(key, reason string, duration time.Duration) {
+	now := time.Now()
+	s.bans[key] = &AbuseBan{
+		Key:       key,
+		Reason:    reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(duration),
+	}
+	s.audit = append(s.audit, AbuseAuditEntry{
+		Action:    "ban",
+		Key:       key,
+		Reason:    reason,
+		Actor:     "system",
+		Timestamp: now,
+	})
+	s.persistAudit()
+	log.Printf("ABUSE_BAN: key=%s, reason=%s, expires=%s", key, reason, s.bans[key].ExpiresAt.Format(time.RFC3339))
+}
+
+// AbuseBanned verifica se la chiave è attualmente bannata
+func AbuseBanned(config *Config, userID, clientIP string) (time.Duration, bool) {
+	if !config.Abuse.Enabled {
+		return 0, false
+	}
+	key := abuseKey(userID, clientIP)
+
+	abuseGuard.mu.Lock()
+	defer abuseGuard.mu.Unlock()
+	abuseGuard.ensureLoaded()
+
+	ban, ok := abuseGuard.bans[key]
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(ban.ExpiresAt)
+	if remaining <= 0 {
+		delete(abuseGuard.bans, key)
+		return 0, false
+	}
+	return remaining, true
+}
+
+// AbuseObserveMessage conta il messaggio nella finestra scorrevole e
+// applica il ban se supera la soglia di messaggi identici. Ritorna true
+// se la richiesta va bloccata.
+func AbuseObserveMessage(config *Config, userID, clientIP, message string) bool {
+	if !config.Abuse.Enabled {
+		return false
+	}
+	window, maxSame, _, banDuration := abuseWindow(config)
+	key := abuseKey(userID, clientIP)
+	sum := sha256.Sum256([]byte(strings.TrimSpace(strings.ToLower(message))))
+	bucket := key + "|" + hex.EncodeToString(sum[:8])
+
+	abuseGuard.mu.Lock()
+	defer abuseGuard.mu.Unlock()
+	abuseGuard.ensureLoaded()
+	abuseGuard.sweepStale(window)
+
+	hits := pruneWindow(abuseGuard.identical[bucket], window)
+	hits = append(hits, time.Now())
+	abuseGuard.identical[bucket] = hits
+
+	if len(hits) > maxSame {
+		abuseGuard.applyBan(key, "messaggi identici ripetuti", banDuration)
+		return true
+	}
+	return false
+}
+
+// AbuseObserveDataRequest conta un accesso agli endpoint dati (chat-log,
+// full_data) e applica il ban in caso di scraping. Ritorna true se la
+// richiesta va bloccata.
+func AbuseObserveDataRequest(config *Config, userID, clientIP string) bool {
+	if !config.Abuse.Enabled {
+		return false
+	}
+	window, _, maxData, banDuration := abuseWindow(config)
+	key := abuseKey(userID, clientIP)
+
+	abuseGuard.mu.Lock()
+	defer abuseGuard.mu.Unlock()
+	abuseGuard.ensureLoaded()
+	abuseGuard.sweepStale(window)
+
+	hits := pruneWindow(abuseGuard.dataHits[key], window)
+	hits = append(hits, time.Now())
+	abuseGuard.dataHits[key] = hits
+
+	if len(hits) > maxData {
+		abuseGuard.applyBan(key, "scraping endpoint dati", banDuration)
+		return true
+	}
+	return false
+}
+
+// HandleListBans elenca i ban attivi e le ultime voci di audit
+// GET /admin/api/bans
+func HandleListBans(c *gin.Context) {
+	abuseGuard.mu.Lock()
+	abuseGuard.ensureLoaded()
+	now := time.Now()
+	var bans []*AbuseBan
+	for key, ban := range abuseGuard.bans {
+		if now.After(ban.ExpiresAt) {
+			delete(abuseGuard.bans, key)
+			continue
+		}
+		copied := *ban
+		bans = append(bans, &copied)
+	}
+	audit := make([]AbuseAuditEntry, len(abuseGuard.audit))
+	copy(audit, abuseGuard.audit)
+	abuseGuard.mu.Unlock()
+
+	sort.Slice(bans, func(i, j int) bool { return bans[i].CreatedAt.After(bans[j].CreatedAt) })
+	if len(audit) > 50 {
+		audit = audit[len(audit)-50:]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "bans": bans, "audit": audit})
+}
+
+// HandleLiftBan rimuove un ban attivo (con voce di audit)
+// DELETE /admin/api/bans/:key
+func HandleLiftBan(c *gin.Context) {
+	key := c.Param("key")
+
+	abuseGuard.mu.Lock()
+	defer abuseGuard.mu.Unlock()
+	abuseGuard.ensureLoaded()
+
+	if _, ok := abuseGuard.bans[key]; !ok {
+		AbortWithError(c, ErrNotFound, "ban non trovato")
+		return
+	}
+	delete(abuseGuard.bans, key)
+	abuseGuard.audit = append(abuseGuard.audit, AbuseAuditEntry{
+		Action:    "lift",
+		Key:       key,
+		Actor:     c.Query("actor"),
+		Timestamp: time.Now(),
+	})
+	abuseGuard.persistAudit()
+	log.Printf("ABUSE_LIFT: key=%s, actor=%s", key, c.Query("actor"))
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "key": key, "lifted": true})
+}
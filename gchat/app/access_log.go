@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Access log per il SIEM della security operations: log di accesso in
+// Combined Log Format (o JSON) su file dedicato e ruotato, separato dai
+// log applicativi. I campi identità nella query string vengono oscurati
+// prima della scrittura e il rumore degli health check viene escluso.
+
+const (
+	accessLogDefaultFile  = "log/access.log"
+	accessLogDefaultMaxMB = 50 // soglia di rotazione
+)
+
+// AccessLogConfig configura l'access log (sezione access_log)
+type AccessLogConfig struct {
+	Enabled bool   `json:"enabled"`
+	Format  string `json:"format"`      // "clf" (default) o "json"
+	File    string `json:"file"`        // default log/access.log
+	MaxMB   int    `json:"max_size_mb"` // rotazione a soglia (default 50)
+}
+
+// Parametri query con dati identità da oscurare nei log di accesso
+var accessLogSensitiveParams = []string{"codice_fiscale", "user_id", "username"}
+
+// Suffissi di path esclusi dall'access log (rumore degli health check),
+// indipendenti dal base path configurato
+var accessLogSkipSuffixes = []string{"/readyz"}
+
+type accessLogWriter struct {
+	file *os.File
+	path string
+	max  int64
+	mu   sync.Mutex
+}
+
+var accessLog = &accessLogWriter{}
+
+// write accoda una riga ruotando il file alla soglia configurata
+func (w *accessLogWriter) write(path string, maxMB int, line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil || w.path != path {
+		if w.file != nil {
+			w.file.Close()
+		}
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Printf("ACCESS_LOG_ERROR: apertura %s fallita: %v", path, err)
+			return
+		}
+		w.file = file
+		w.path = path
+	}
+	w.max = int64(maxMB) * 1024 * 1024
+
+	if info, err := w.file.Stat(); err == nil && info.Size() >= w.max {
+		// Rotazione semplice: il file corrente diventa .1 (sovrascritto)
+		w.file.Close()
+		w.file = nil
+		if err := os.Rename(path, path+".1"); err != nil {
+			log.Printf("ACCESS_LOG_ERROR: rotazione fallita: %v", err)
+		}
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Printf("ACCESS_LOG_ERROR: riapertura dopo rotazione fallita: %v", err)
+			return
+		}
+		w.file = file
+		log.Printf("ACCESS_LOG_ROTATED: file=%s", path)
+	}
+
+	fmt.Fprintln(w.file, line)
+}
+
+// sanitizeAccessURI oscura i valori dei parametri identità nella query
+func sanitizeAccessURI(uri string) string {
+	parsed, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return uri
+	}
+	query := parsed.Query()
+	changed := false
+	for _, param := range accessLogSensitiveParams {
+		if query.Has(param) {
+			query.Set(param, "***")
+			changed = true
+		}
+	}
+	if !changed {
+		return uri
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// AccessLogMiddleware scrive una riga di accesso per ogni richiesta
+// servita (formato CLF o JSON secondo configurazione)
+func AccessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		config := LoadConfig()
+		if !config.AccessLog.Enabled {
+			return
+		}
+		for _, suffix := range accessLogSkipSuffixes {
+			if strings.HasSuffix(c.Request.URL.Path, suffix) {
+				return
+			}
+		}
+
+		file := config.AccessLog.File
+		if file == "" {
+			file = accessLogDefaultFile
+		}
+		maxMB := config.AccessLog.MaxMB
+		if maxMB <= 0 {
+			maxMB = accessLogDefaultMaxMB
+		}
+
+		uri := sanitizeAccessURI(c.Request.RequestURI)
+		status := c.Writer.Status()
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+
+		var line string
+		if strings.EqualFold(config.AccessLog.Format, "json") {
+			entry := map[string]interface{}{
+				"time":        start.Format(time.RFC3339),
+				"remote_addr": c.ClientIP(),
+				"method":      c.Request.Method,
+				"uri":         uri,
+				"proto":       c.Request.Proto,
+				"status":      status,
+				"bytes":       size,
+				"duration_ms": time.Since(start).Milliseconds(),
+				"user_agent":  c.Request.UserAgent(),
+			}
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return
+			}
+			line = string(data)
+		} else {
+			// Combined Log Format (identità sempre "-": non logghiamo utenti)
+			line = fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %d \"%s\" \"%s\"",
+				c.ClientIP(), start.Format("02/Jan/2006:15:04:05 -0700"),
+				c.Request.Method, uri, c.Request.Proto, status, size,
+				c.Request.Referer(), c.Request.UserAgent())
+		}
+
+		accessLog.write(file, maxMB, line)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Autenticazione della superficie amministrativa: le route /admin/api/*
+// (impersonificazione, replay, archivio, log tail, supervisione) espongono
+// dati di conversazione e operazioni privilegiate e non possono restare
+// raggiungibili da chiunque veda il gateway. Il middleware richiede un
+// token condiviso fornito solo via env ADMIN_API_TOKEN (stessa regola
+// delle altre chiavi: mai nel config.json); senza token configurato la
+// superficie è chiusa, non aperta.
+
+// adminAuthToken ritorna il token amministrativo dall'ambiente
+func adminAuthToken() string {
+	return strings.TrimSpace(os.Getenv("ADMIN_API_TOKEN"))
+}
+
+// adminClientToken estrae il token dalla richiesta: header X-Admin-Token
+// o Authorization: Bearer
+func adminClientToken(c *gin.Context) string {
+	if token := c.GetHeader("X-Admin-Token"); token != "" {
+		return token
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// AdminAuthMiddleware protegge il gruppo /admin/api/*: confronto in tempo
+// costante con il token di ambiente, 401 su token assente o errato
+func AdminAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expected := adminAuthToken()
+		if expected == "" {
+			log.Printf("ADMIN_AUTH_NO_TOKEN: ADMIN_API_TOKEN non configurato, superficie admin chiusa - path=%s", c.Request.URL.Path)
+			AbortWithError(c, ErrUnauthorized, "superficie amministrativa non configurata su questa istanza")
+			return
+		}
+
+		provided := adminClientToken(c)
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) != 1 {
+			log.Printf("ADMIN_AUTH_FAILED: client_ip=%s, path=%s", c.ClientIP(), c.Request.URL.Path)
+			AbortWithError(c, ErrUnauthorized, "token amministrativo mancante o non valido")
+			return
+		}
+
+		log.Printf("ADMIN_REQUEST: client_ip=%s, method=%s, path=%s", c.ClientIP(), c.Request.Method, c.Request.URL.Path)
+		c.Next()
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stato degli alert per la pagina monitor: le violazioni SLO e i guasti
+// (circuito backend aperto, database degradato, whisper freddo) finiscono
+// nei log e nel pager, ma chi guarda il monitor non vede la stessa vista.
+// Il gateway mantiene un registro firing/resolved con annotazioni e
+// timestamp di transizione, esposto su /api/alerts: la dashboard mostra
+// così gli stessi alert che arrivano ai reperibili.
+
+// alertResolvedRetention: per quanto gli alert risolti restano visibili
+const alertResolvedRetention = 24 * time.Hour
+
+// Alert è una voce del registro con lo stato corrente
+type Alert struct {
+	Name        string            `json:"name"`
+	State       string            `json:"state"` // firing | resolved
+	Since       time.Time         `json:"since"`
+	ResolvedAt  *time.Time        `json:"resolved_at,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type alertRegistry struct {
+	alerts map[string]*Alert
+	mu     sync.Mutex
+}
+
+var alerts = &alertRegistry{alerts: map[string]*Alert{}}
+
+// alertSet registra la transizione di stato di un alert con log di
+// passaggio firing/resolved; le annotazioni vengono aggiornate a ogni
+// valutazione finché l'alert è attivo
+func alertSet(name string, firing bool, annotations map[string]string) {
+	alerts.mu.Lock()
+	defer alerts.mu.Unlock()
+
+	current, known := alerts.alerts[name]
+	now := time.Now()
+
+	if firing {
+		if !known || current.State != "firing" {
+			alerts.alerts[name] = &Alert{
+				Name:        name,
+				State:       "firing",
+				Since:       now,
+				Annotations: annotations,
+			}
+			log.Printf("ALERT_FIRING: name=%s, annotations=%v", name, annotations)
+			return
+		}
+		current.Annotations = annotations
+		return
+	}
+
+	if known && current.State == "firing" {
+		current.State = "resolved"
+		current.ResolvedAt = &now
+		log.Printf("ALERT_RESOLVED: name=%s, durata=%v", name, now.Sub(current.Since).Round(time.Second))
+	}
+}
+
+// evaluateAlerts rivaluta tutte le condizioni note sui segnali già
+// raccolti dal gateway (nessuna chiamata remota aggiuntiva)
+func evaluateAlerts() {
+	config := LoadConfig()
+
+	// Circuito verso il backend aperto
+	circuit, lastCheck := backendState()
+	alertSet("backend_circuit_open", circuit == "open", map[string]string{
+		"summary":    "Il circuit breaker verso il backend LLM è aperto: le richieste chat vengono rifiutate",
+		"last_check": lastCheck.Format(time.RFC3339),
+	})
+
+	// Database non raggiungibile (modalità degradata read-only)
+	alertSet("database_degraded", DegradedMode(), map[string]string{
+		"summary": "Database GIAS non raggiungibile: cronologia e ricerca sospese, chat attiva",
+	})
+
+	// Whisper freddo con trascrizione abilitata
+	if config.Transcription.Enabled {
+		warm, _, lastErr := WhisperStatus()
+		alertSet("whisper_cold", !warm, map[string]string{
+			"summary":    "Il server whisper non risponde al keep-warm: prima trascrizione lenta o in errore",
+			"last_error": lastErr,
+		})
+	}
+
+	// Eventi SSE fuori tassonomia: il backend emette tipi che il frontend
+	// non conosce (probabile release divergente, vedi event_taxonomy.go)
+	if unknown := UnknownEventCounts(); len(unknown) > 0 {
+		types := ""
+		for _, entry := range unknown {
+			if types != "" {
+				types += ", "
+			}
+			types += fmt.Sprintf("%v (%v)", entry["type"], entry["count"])
+		}
+		alertSet("unknown_sse_events", true, map[string]string{
+			"summary": "Il backend emette tipi di evento SSE sconosciuti, messi in quarantena",
+			"types":   types,
+		})
+	}
+
+	// Risposte in lingua sbagliata (deriva dei prompt backend)
+	if mismatches, translated := LanguageMismatchStats(); mismatches > 0 {
+		alertSet("language_mismatch", true, map[string]string{
+			"summary":    "Il backend risponde in una lingua diversa da quella attesa (deriva dei prompt)",
+			"mismatches": fmt.Sprintf("%d", mismatches),
+			"translated": fmt.Sprintf("%d", translated),
+		})
+	}
+
+	// Violazioni SLO per intent (p95 oltre il target sulla finestra corrente)
+	for _, entry := range SLOReport() {
+		intent, _ := entry["intent"].(string)
+		violated, _ := entry["violated"].(bool)
+		p95, _ := entry["p95_ms"].(float64)
+		target, _ := entry["target_ms"].(float64)
+		alertSet("slo_violation_"+intent, violated, map[string]string{
+			"summary":   fmt.Sprintf("Latenza p95 dell'intent %s oltre il target", intent),
+			"p95_ms":    fmt.Sprintf("%.0f", p95),
+			"target_ms": fmt.Sprintf("%.0f", target),
+		})
+	}
+}
+
+// HandleAlerts espone lo stato corrente degli alert per il monitor
+// GET /api/alerts
+func HandleAlerts(c *gin.Context) {
+	evaluateAlerts()
+
+	alerts.mu.Lock()
+	list := make([]Alert, 0, len(alerts.alerts))
+	firing := 0
+	for name, alert := range alerts.alerts {
+		// Gli alert risolti da troppo tempo escono dal registro
+		if alert.State == "resolved" && alert.ResolvedAt != nil && time.Since(*alert.ResolvedAt) > alertResolvedRetention {
+			delete(alerts.alerts, name)
+			continue
+		}
+		if alert.State == "firing" {
+			firing++
+		}
+		list = append(list, *alert)
+	}
+	alerts.mu.Unlock()
+
+	// Prima i firing, poi per transizione più recente
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].State != list[j].State {
+			return list[i].State == "firing"
+		}
+		return list[i].Since.After(list[j].Since)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "success",
+		"firing_count": firing,
+		"alerts":       list,
+	})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Anonimizzazione degli export analytics: prima che i dati lascino il
+// gateway per la reportistica regionale, gli identificativi utente vengono
+// pseudonimizzati con hash salato per deployment, i gruppi sotto la soglia
+// k vengono soppressi e i testi liberi rimossi. La policy è configurabile
+// (sezione anonymization); il salt arriva preferibilmente dalla variabile
+// d'ambiente ANONYMIZATION_SALT.
+
+const defaultKThreshold = 5
+
+// identifierFields: campi record trattati come identificativi da pseudonimizzare
+var identifierFields = []string{"codice_fiscale", "user_id", "sender", "session_id", "username"}
+
+// freeTextFields: campi a testo libero rimossi dalla versione esportata
+var freeTextFields = []string{"user_message", "bot_response", "message", "text", "question", "answer"}
+
+// anonymizationSalt ritorna il salt per l'hashing: env var > config
+func anonymizationSalt(config *Config) string {
+	if salt := os.Getenv("ANONYMIZATION_SALT"); salt != "" {
+		return salt
+	}
+	if config.Anonymization.Salt != "" {
+		return config.Anonymization.Salt
+	}
+	log.Printf("ANONYMIZE_WARNING: nessun salt configurato (ANONYMIZATION_SALT o anonymization.salt), hash non salato")
+	return ""
+}
+
+// AnonymizeIdentifier pseudonimizza un identificativo con hash salato
+func AnonymizeIdentifier(salt, value string) string {
+	sum := sha256.Sum256([]byte(salt + "|" + value))
+	return hex.EncodeToString(sum[:8])
+}
+
+// AnonymizeRecords applica la policy ai record: pseudonimizzazione degli
+// identificativi, rimozione dei testi liberi e soppressione dei gruppi ASL
+// con meno di k utenti distinti. Ritorna i record esportabili e il numero
+// di gruppi soppressi.
+func AnonymizeRecords(config *Config, records []map[string]interface{}) ([]map[string]interface{}, int) {
+	salt := anonymizationSalt(config)
+	k := config.Anonymization.KThreshold
+	if k <= 0 {
+		k = defaultKThreshold
+	}
+
+	// Primo passaggio: utenti distinti per gruppo ASL (pre-anonimizzazione)
+	usersPerASL := make(map[string]map[string]struct{})
+	for _, record := range records {
+		asl, _ := record["asl"].(string)
+		user := ""
+		for _, field := range identifierFields {
+			if v, ok := record[field].(string); ok && v != "" {
+				user = v
+				break
+			}
+		}
+		if usersPerASL[asl] == nil {
+			usersPerASL[asl] = make(map[string]struct{})
+		}
+		usersPerASL[asl][user] = struct{}{}
+	}
+
+	suppressed := make(map[string]struct{})
+	for asl, users := range usersPerASL {
+		if len(users) < k {
+			suppressed[asl] = struct{}{}
+		}
+	}
+
+	// Secondo passaggio: trasformazione dei record esportabili
+	out := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		asl, _ := record["asl"].(string)
+		if _, skip := suppressed[asl]; skip {
+			continue
+		}
+
+		clean := make(map[string]interface{}, len(record))
+		for key, value := range record {
+			clean[key] = value
+		}
+		for _, field := range identifierFields {
+			if v, ok := clean[field].(string); ok && v != "" {
+				clean[field] = AnonymizeIdentifier(salt, v)
+			}
+		}
+		if !config.Anonymization.KeepFreeText {
+			for _, field := range freeTextFields {
+				delete(clean, field)
+			}
+		}
+		out = append(out, clean)
+	}
+
+	return out, len(suppressed)
+}
+
+// HandleAnalyticsExport esporta i messaggi recenti dal backend applicando
+// la policy di anonimizzazione prima che i dati lascino il gateway
+// GET /admin/api/analytics/export?limit=N
+func HandleAnalyticsExport(c *gin.Context) {
+	config := LoadConfig()
+
+	limit := 500
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 5000 {
+		limit = l
+	}
+
+	backendURL := fmt.Sprintf("%s/api/chat-log/recent?limit=%d", config.LLMServer.URL, limit)
+	client := NewUpstreamClient("llm", time.Duration(config.LLMServer.Timeout)*time.Second)
+
+	resp, err := upstreamGet(client, backendURL)
+	if err != nil {
+		log.Printf("ANONYMIZE_EXPORT_ERROR: backend non raggiungibile - error=%v", err)
+		AbortWithError(c, ErrBackendUnavailable, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		AbortWithError(c, ErrBackendError, err.Error())
+		return
+	}
+
+	var backendData struct {
+		Records []map[string]interface{} `json:"records"`
+	}
+	if err := json.Unmarshal(body, &backendData); err != nil {
+		log.Printf("ANONYMIZE_EXPORT_ERROR: risposta backend non valida - error=%v", err)
+		AbortWithError(c, ErrBackendError, err.Error())
+		return
+	}
+
+	records, suppressedGroups := AnonymizeRecords(config, backendData.Records)
+
+	log.Printf("ANONYMIZE_EXPORT: client_ip=%s, records_in=%d, records_out=%d, suppressed_groups=%d",
+		c.ClientIP(), len(backendData.Records), len(records), suppressedGroups)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":            "success",
+		"records":           records,
+		"records_total":     len(records),
+		"suppressed_groups": suppressedGroups,
+		"policy": gin.H{
+			"k_threshold":    config.Anonymization.KThreshold,
+			"free_text_kept": config.Anonymization.KeepFreeText,
+		},
+	})
+}
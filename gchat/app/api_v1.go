@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Superficie API v1 per client SDK: le app interne di terze parti vogliono
+// interrogare l'assistente senza passare dalla web UI, ma le route
+// esistenti sono accoppiate a sessione cookie e template. Il gruppo
+// /api/v1/* riespone chat, stream, trascrizione e capabilities con
+// contratto versionato: autenticazione a API key (solo via env
+// API_V1_KEYS, formato "nome:chiave,nome2:chiave2"), header X-API-Version
+// su ogni risposta e header di deprecazione (Deprecation/Sunset) quando
+// la versione viene ritirata, così gli SDK hanno il preavviso nel
+// contratto invece che in una mail.
+
+const apiV1Version = "v1"
+
+// APIV1Config configura la superficie versionata (sezione api_v1)
+type APIV1Config struct {
+	Enabled bool `json:"enabled"`
+	// Deprecated: se true le risposte includono l'header Deprecation
+	Deprecated bool `json:"deprecated"`
+	// Sunset: data di ritiro della versione (RFC 1123 o YYYY-MM-DD),
+	// esposta nell'header Sunset quando deprecata
+	Sunset string `json:"sunset"`
+}
+
+// apiV1Keys ritorna le API key valide dall'ambiente: nome -> chiave
+func apiV1Keys() map[string]string {
+	keys := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv("API_V1_KEYS"), ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || name == "" || key == "" {
+			continue
+		}
+		keys[key] = name
+	}
+	return keys
+}
+
+// apiV1ClientKey estrae la chiave dalla richiesta: header X-API-Key o
+// Authorization: Bearer
+func apiV1ClientKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// APIV1Middleware autentica la richiesta con API key e applica gli header
+// di versione e deprecazione del contratto
+func APIV1Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		config := LoadConfig()
+		if !config.APIV1.Enabled {
+			AbortWithError(c, ErrNotFound, "API v1 non abilitata su questa istanza")
+			return
+		}
+
+		c.Header("X-API-Version", apiV1Version)
+		if config.APIV1.Deprecated {
+			c.Header("Deprecation", "true")
+			if config.APIV1.Sunset != "" {
+				c.Header("Sunset", config.APIV1.Sunset)
+			}
+		}
+
+		keys := apiV1Keys()
+		if len(keys) == 0 {
+			log.Printf("APIV1_NO_KEYS: API v1 abilitata ma API_V1_KEYS non configurata")
+			AbortWithError(c, ErrUnauthorized, "nessuna API key configurata sul gateway")
+			return
+		}
+
+		// Confronto in tempo costante su ogni chiave configurata (stessa
+		// policy del token admin, vedi admin_auth.go)
+		provided := []byte(apiV1ClientKey(c))
+		name, ok := "", false
+		for key, client := range keys {
+			if subtle.ConstantTimeCompare(provided, []byte(key)) == 1 {
+				name, ok = client, true
+			}
+		}
+		if !ok {
+			log.Printf("APIV1_AUTH_FAILED: client_ip=%s, path=%s", c.ClientIP(), c.Request.URL.Path)
+			AbortWithError(c, ErrUnauthorized, "API key mancante o non valida")
+			return
+		}
+
+		c.Set("api_client", name)
+		log.Printf("APIV1_REQUEST: client=%s, path=%s", name, c.Request.URL.Path)
+		c.Next()
+	}
+}
+
+// RegisterAPIV1Routes registra la superficie versionata sugli stessi
+// handler della web UI (il contratto request/response è già stabile e
+// tipizzato; cambia solo l'autenticazione)
+func RegisterAPIV1Routes(api *gin.RouterGroup) {
+	v1 := api.Group("/api/v1")
+	v1.Use(APIV1Middleware())
+
+	v1.POST("/chat", HandleChat)
+	v1.POST("/chat/stream", HandleChatStream)
+	v1.POST("/transcribe", TranscribeHandler)
+	v1.GET("/capabilities", HandleCapabilities)
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Archiviazione cold-storage delle conversazioni: la retention lunga non
+// deve gonfiare il PostgreSQL primario. Un job schedulato esporta dal
+// backend le conversazioni più vecchie di N giorni, le comprime in JSON
+// gzip e le carica su un bucket S3-compatibile (MinIO on-prem, firma AWS
+// SigV4 senza SDK); un catalogo locale tiene l'indice degli oggetti e un
+// endpoint admin permette il restore on-demand. Le credenziali arrivano
+// solo da env (S3_ACCESS_KEY / S3_SECRET_KEY).
+
+const (
+	archiveCatalogFile        = "data/archive_catalog.json"
+	archiveDefaultOlderDays   = 180
+	archiveDefaultIntervalHrs = 24
+)
+
+// ArchiveConfig configura il job di archiviazione (sezione archive)
+type ArchiveConfig struct {
+	Enabled       bool   `json:"enabled"`
+	Endpoint      string `json:"endpoint"` // es. http://minio:9000
+	Bucket        string `json:"bucket"`
+	Region        string `json:"region"`          // default us-east-1 (MinIO la ignora)
+	OlderThanDays int    `json:"older_than_days"` // default 180
+	IntervalHours int    `json:"interval_hours"`  // default 24
+}
+
+// archiveEntry è una voce del catalogo degli oggetti archiviati
+type archiveEntry struct {
+	Key        string    `json:"key"`
+	SizeBytes  int       `json:"size_bytes"`
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+type archiveCatalog struct {
+	entries map[string]*archiveEntry // session_id -> oggetto
+	loaded  bool
+	mu      sync.Mutex
+}
+
+var archived = &archiveCatalog{}
+
+func (s *archiveCatalog) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.entries = map[string]*archiveEntry{}
+
+	data, err := os.ReadFile(archiveCatalogFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("ARCHIVE_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		log.Printf("ARCHIVE_LOAD_ERROR: parse: %v", err)
+		s.entries = map[string]*archiveEntry{}
+	}
+}
+
+func (s *archiveCatalog) persist() {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		log.Printf("ARCHIVE_PERSIST_ERROR: marshal: %v", err)
+		return
+	}
+	if err := os.WriteFile(archiveCatalogFile, data, 0644); err != nil {
+		log.Printf("ARCHIVE_PERSIST_ERROR: write: %v", err)
+	}
+}
+
+// s3SigningKey deriva la chiave di firma SigV4 per il giorno corrente
+func s3SigningKey(secret, dateStamp, region string) []byte {
+	sign := func(key []byte, msg string) []byte {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(msg))
+		return mac.Sum(nil)
+	}
+	kDate := sign([]byte("AWS4"+secret), dateStamp)
+	kRegion := sign(kDate, region)
+	kService := sign(kRegion, "s3")
+	return sign(kService, "aws4_request")
+}
+
+// s3Request esegue una richiesta firmata SigV4 verso il bucket (path-style,
+// come si usa con MinIO). Le chiavi oggetto devono essere già url-safe.
+func s3Request(ctx context.Context, config ArchiveConfig, method, key string, body []byte) (*http.Response, error) {
+	accessKey := os.Getenv("S3_ACCESS_KEY")
+	secretKey := os.Getenv("S3_SECRET_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("credenziali S3 mancanti (S3_ACCESS_KEY / S3_SECRET_KEY)")
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("endpoint S3 non valido: %s", config.Endpoint)
+	}
+	canonicalURI := "/" + config.Bucket + "/" + key
+	fullURL := strings.TrimSuffix(config.Endpoint, "/") + canonicalURI
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	payloadHash := sha256.Sum256(body)
+	payloadHex := hex.EncodeToString(payloadHash[:])
+
+	canonicalHeaders := "host:" + endpoint.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHex + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{
+		method, canonicalURI, "", canonicalHeaders, signedHeaders, payloadHex,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := dateStamp + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, s3SigningKey(secretKey, dateStamp, region))
+	mac.Write([]byte(stringToSign))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHex)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+
+	client := NewUpstreamClient("s3", 30*time.Second)
+	return client.Do(req)
+}
+
+// archiveRunOnce esporta dal backend le conversazioni oltre soglia e le
+// carica sul bucket, aggiornando il catalogo. Ritorna il numero di
+// conversazioni archiviate.
+func archiveRunOnce(config *Config) (int, error) {
+	cutoff := time.Now().AddDate(0, 0, -archiveOlderDays(config)).Format("2006-01-02")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var export struct {
+		Conversations []struct {
+			SessionID string          `json:"session_id"`
+			Messages  json.RawMessage `json:"messages"`
+		} `json:"conversations"`
+	}
+	exportURL := config.LLMServer.URL + "/api/chat-log/export?before=" + cutoff
+	if err := fetchBackendJSON(ctx, exportURL, &export); err != nil {
+		return 0, fmt.Errorf("export backend: %v", err)
+	}
+
+	count := 0
+	for _, conv := range export.Conversations {
+		if conv.SessionID == "" {
+			continue
+		}
+
+		archived.mu.Lock()
+		archived.ensureLoaded()
+		_, already := archived.entries[conv.SessionID]
+		archived.mu.Unlock()
+		if already {
+			continue
+		}
+
+		payload, err := json.Marshal(gin.H{
+			"session_id":  conv.SessionID,
+			"messages":    conv.Messages,
+			"archived_at": time.Now().Format(time.RFC3339),
+		})
+		if err != nil {
+			continue
+		}
+
+		var compressed bytes.Buffer
+		gz := gzip.NewWriter(&compressed)
+		gz.Write(payload)
+		gz.Close()
+
+		key := fmt.Sprintf("conversations/%s/%s.json.gz", time.Now().Format("2006"), url.PathEscape(conv.SessionID))
+		resp, err := s3Request(ctx, config.Archive, "PUT", key, compressed.Bytes())
+		if err != nil {
+			return count, fmt.Errorf("upload %s: %v", key, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return count, fmt.Errorf("upload %s: status %d", key, resp.StatusCode)
+		}
+
+		archived.mu.Lock()
+		archived.entries[conv.SessionID] = &archiveEntry{
+			Key:        key,
+			SizeBytes:  compressed.Len(),
+			ArchivedAt: time.Now(),
+		}
+		archived.persist()
+		archived.mu.Unlock()
+		count++
+	}
+
+	if count > 0 {
+		log.Printf("ARCHIVE_RUN_OK: archiviate %d conversazioni anteriori a %s", count, cutoff)
+	}
+	return count, nil
+}
+
+func archiveOlderDays(config *Config) int {
+	if config.Archive.OlderThanDays > 0 {
+		return config.Archive.OlderThanDays
+	}
+	return archiveDefaultOlderDays
+}
+
+// StartArchiveScheduler avvia il job periodico di archiviazione. Da
+// chiamare in una goroutine all'avvio; no-op se l'archiviazione è spenta.
+func StartArchiveScheduler(config *Config) {
+	if !config.Archive.Enabled {
+		log.Printf("ARCHIVE_SKIP: archiviazione disabilitata")
+		return
+	}
+
+	interval := time.Duration(config.Archive.IntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = archiveDefaultIntervalHrs * time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		if _, err := archiveRunOnce(LoadConfig()); err != nil {
+			log.Printf("ARCHIVE_RUN_ERROR: %v", err)
+		}
+	}
+}
+
+// HandleArchiveCatalog elenca gli oggetti archiviati noti al catalogo
+// GET /admin/api/archive
+func HandleArchiveCatalog(c *gin.Context) {
+	archived.mu.Lock()
+	archived.ensureLoaded()
+	entries := make(map[string]archiveEntry, len(archived.entries))
+	for sid, entry := range archived.entries {
+		entries[sid] = *entry
+	}
+	archived.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":        "success",
+		"count":         len(entries),
+		"conversations": entries,
+	})
+}
+
+// HandleArchiveRestore recupera una conversazione archiviata dal bucket
+// GET /admin/api/archive/restore/:sessionId
+func HandleArchiveRestore(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+
+	archived.mu.Lock()
+	archived.ensureLoaded()
+	entry, ok := archived.entries[sessionID]
+	archived.mu.Unlock()
+	if !ok {
+		AbortWithError(c, ErrNotFound, "conversazione non presente nel catalogo archivio")
+		return
+	}
+
+	resp, err := s3Request(c.Request.Context(), LoadConfig().Archive, "GET", entry.Key, nil)
+	if err != nil {
+		log.Printf("ARCHIVE_RESTORE_ERROR: sid=%s, error=%v", sessionID, err)
+		AbortWithError(c, ErrBackendUnavailable, "object storage non raggiungibile")
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("ARCHIVE_RESTORE_ERROR: sid=%s, status=%d", sessionID, resp.StatusCode)
+		AbortWithError(c, ErrBackendError, fmt.Sprintf("object storage status %d", resp.StatusCode))
+		return
+	}
+
+	gz, err := gzip.NewReader(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		AbortWithError(c, ErrInternal, "oggetto archiviato corrotto")
+		return
+	}
+	defer gz.Close()
+	payload, err := io.ReadAll(gz)
+	if err != nil {
+		AbortWithError(c, ErrInternal, "oggetto archiviato corrotto")
+		return
+	}
+
+	log.Printf("ARCHIVE_RESTORE_OK: sid=%s, key=%s, bytes=%d", sessionID, entry.Key, len(payload))
+	c.Data(http.StatusOK, "application/json", payload)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Normalizzazione dei nomi ASL: il parametro asl_name arriva dai portali
+// in formati incoerenti ("ASL NAPOLI 1 CENTRO", "Na1", "asl na 1 centro").
+// Qui viene canonicalizzato prima di entrare nella sessione/contesto, così
+// il backend riceve sempre la stessa forma. I valori sconosciuti vengono
+// scartati (il backend farebbe fallback su asl_id) e loggati per analisi.
+
+// canonicalASLs sono le ASL della Regione Campania nella forma canonica
+// usata dal backend e da personale.csv
+var canonicalASLs = []string{
+	"AVELLINO",
+	"BENEVENTO",
+	"CASERTA",
+	"NAPOLI 1 CENTRO",
+	"NAPOLI 2 NORD",
+	"NAPOLI 3 SUD",
+	"SALERNO",
+}
+
+// aslAliases mappa le abbreviazioni note alla forma canonica (chiavi già
+// normalizzate: maiuscole, senza prefisso ASL, senza spazi)
+var aslAliases = map[string]string{
+	"AV":        "AVELLINO",
+	"BN":        "BENEVENTO",
+	"CE":        "CASERTA",
+	"SA":        "SALERNO",
+	"NA1":       "NAPOLI 1 CENTRO",
+	"NA1C":      "NAPOLI 1 CENTRO",
+	"NAPOLI1":   "NAPOLI 1 CENTRO",
+	"NA2":       "NAPOLI 2 NORD",
+	"NA2N":      "NAPOLI 2 NORD",
+	"NAPOLI2":   "NAPOLI 2 NORD",
+	"NA3":       "NAPOLI 3 SUD",
+	"NA3S":      "NAPOLI 3 SUD",
+	"NAPOLI3":   "NAPOLI 3 SUD",
+	"NA1CENTRO": "NAPOLI 1 CENTRO",
+	"NA2NORD":   "NAPOLI 2 NORD",
+	"NA3SUD":    "NAPOLI 3 SUD",
+}
+
+// aslCompactKey normalizza un nome ASL in chiave compatta di confronto:
+// maiuscole, prefisso "ASL" rimosso, spazi e punteggiatura eliminati
+func aslCompactKey(raw string) string {
+	up := strings.ToUpper(strings.TrimSpace(raw))
+	up = strings.TrimPrefix(up, "ASL")
+	var sb strings.Builder
+	for _, r := range up {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// levenshtein calcola la distanza di edit tra due stringhe (per il fuzzy
+// match di errori di battitura tipo "BENVENTO")
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := 0; j <= len(rb); j++ {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(curr[j-1]+1, prev[j]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// NormalizeASLName canonicalizza un nome ASL. Ritorna la forma canonica e
+// true se riconosciuto; stringa vuota e false per valori sconosciuti.
+func NormalizeASLName(raw string) (string, bool) {
+	if strings.TrimSpace(raw) == "" {
+		return "", false
+	}
+
+	key := aslCompactKey(raw)
+	if key == "" {
+		return "", false
+	}
+
+	// 1. Match esatto sulla forma canonica compattata
+	for _, canonical := range canonicalASLs {
+		if key == aslCompactKey(canonical) {
+			return canonical, true
+		}
+	}
+
+	// 2. Alias/abbreviazioni note
+	if canonical, ok := aslAliases[key]; ok {
+		return canonical, true
+	}
+
+	// 3. Fuzzy match per errori di battitura (distanza massima 2)
+	best := ""
+	bestDist := 3
+	for _, canonical := range canonicalASLs {
+		if d := levenshtein(key, aslCompactKey(canonical)); d < bestDist {
+			bestDist = d
+			best = canonical
+		}
+	}
+	if best != "" {
+		log.Printf("ASL_NORMALIZE_FUZZY: '%s' -> '%s' (distanza=%d)", raw, best, bestDist)
+		return best, true
+	}
+
+	return "", false
+}
+
+// ResolveContextASL normalizza il nome ASL per il contesto backend.
+// I valori sconosciuti vengono scartati con log di mismatch (incluso il
+// confronto con l'ASL risultante da personale.csv, se user_id è noto).
+func ResolveContextASL(raw, userID string) (string, bool) {
+	if strings.TrimSpace(raw) == "" {
+		return "", false
+	}
+
+	canonical, ok := NormalizeASLName(raw)
+	if !ok {
+		log.Printf("ASL_NORMALIZE_REJECTED: valore ASL sconosciuto scartato - raw='%s', user_id=%s", raw, userID)
+		return "", false
+	}
+
+	if canonical != strings.ToUpper(strings.TrimSpace(raw)) {
+		log.Printf("ASL_NORMALIZE: '%s' -> '%s'", raw, canonical)
+	}
+
+	// Verifica incrociata con personale.csv: un mismatch indica parametri
+	// URL manomessi o dati personale disallineati
+	if userID != "" {
+		if uid, err := strconv.Atoi(userID); err == nil {
+			if record, err := GetPersonaleByUserID(uid); err == nil && record.ASL != "" {
+				if personaleCanonical, pok := NormalizeASLName(record.ASL); pok && personaleCanonical != canonical {
+					log.Printf("ASL_NORMALIZE_MISMATCH: asl richiesta='%s' ma personale riporta '%s' - user_id=%s",
+						canonical, personaleCanonical, userID)
+				}
+			}
+		}
+	}
+
+	return canonical, true
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/crewjam/saml/samlsp"
+	"github.com/gin-gonic/gin"
+)
+
+// Autenticazione SAML/SPID: la policy regionale richiede SSO SPID/CIE o
+// SAML per gli strumenti rivolti ai cittadini. Con auth.mode="saml" il
+// gateway agisce da Service Provider (crewjam/saml): gli utenti non
+// autenticati vengono rediretti all'Identity Provider e gli attributi
+// dell'asserzione vengono mappati su user_id/codice_fiscale/ASL nella
+// sessione esistente. Con auth.mode="portal" (default) resta il flusso
+// attuale a parametri dal portale.
+
+// InitSAML costruisce il middleware Service Provider dalla configurazione
+func InitSAML(config *Config) (*samlsp.Middleware, error) {
+	cfg := config.Auth.SAML
+
+	if cfg.IDPMetadataURL == "" || cfg.CertFile == "" || cfg.KeyFile == "" || cfg.RootURL == "" {
+		return nil, fmt.Errorf("configurazione auth.saml incompleta (servono idp_metadata_url, cert_file, key_file, root_url)")
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("impossibile caricare la coppia di chiavi SP: %v", err)
+	}
+	keyPair.Leaf, err = x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("certificato SP non valido: %v", err)
+	}
+
+	idpMetadataURL, err := url.Parse(cfg.IDPMetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("idp_metadata_url non valido: %v", err)
+	}
+	idpMetadata, err := samlsp.FetchMetadata(context.Background(), http.DefaultClient, *idpMetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("impossibile scaricare i metadata IdP: %v", err)
+	}
+
+	rootURL, err := url.Parse(cfg.RootURL)
+	if err != nil {
+		return nil, fmt.Errorf("root_url non valido: %v", err)
+	}
+
+	sp, err := samlsp.New(samlsp.Options{
+		URL:         *rootURL,
+		Key:         keyPair.PrivateKey.(*rsa.PrivateKey),
+		Certificate: keyPair.Leaf,
+		IDPMetadata: idpMetadata,
+		EntityID:    cfg.EntityID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("inizializzazione SP fallita: %v", err)
+	}
+
+	log.Printf("AUTH_SAML_INIT: Service Provider attivo - entity_id=%s, idp=%s", cfg.EntityID, cfg.IDPMetadataURL)
+	return sp, nil
+}
+
+// samlAttribute legge un attributo dall'asserzione usando il nome mappato
+// in config, con fallback al nome di default
+func samlAttribute(attrs samlsp.Attributes, configured, fallback string) string {
+	name := configured
+	if name == "" {
+		name = fallback
+	}
+	return attrs.Get(name)
+}
+
+// SAMLAuthMiddleware protegge le route del gateway: utenti senza sessione
+// SAML vengono rediretti all'IdP; per quelli autenticati gli attributi
+// dell'asserzione vengono riversati nella sessione utente (stessi campi
+// del flusso a parametri portale, così il resto del codice non cambia)
+func SAMLAuthMiddleware(sp *samlsp.Middleware, config *Config) gin.HandlerFunc {
+	mapping := config.Auth.SAML.AttributeMapping
+
+	return func(c *gin.Context) {
+		// Gli endpoint del flusso SAML stesso non vanno protetti
+		if strings.Contains(c.Request.URL.Path, "/saml/") {
+			c.Next()
+			return
+		}
+
+		session, err := sp.Session.GetSession(c.Request)
+		if err != nil {
+			log.Printf("AUTH_SAML_REDIRECT: utente non autenticato, redirect a IdP - path=%s, client_ip=%s",
+				c.Request.URL.Path, c.ClientIP())
+			sp.HandleStartAuthFlow(c.Writer, c.Request)
+			c.Abort()
+			return
+		}
+
+		if sa, ok := session.(samlsp.SessionWithAttributes); ok {
+			attrs := sa.GetAttributes()
+			userID := samlAttribute(attrs, mapping.UserID, "user_id")
+			cf := samlAttribute(attrs, mapping.CodiceFiscale, "fiscalNumber")
+			asl := samlAttribute(attrs, mapping.ASL, "asl")
+			username := samlAttribute(attrs, mapping.Username, "name")
+
+			// SPID prefissa il codice fiscale con "TINIT-"
+			cf = strings.TrimPrefix(cf, "TINIT-")
+
+			if canonical, ok := NormalizeASLName(asl); ok {
+				asl = canonical
+			}
+
+			if err := SaveUserSession(c, userID, "", asl, cf, username); err == nil {
+				log.Printf("AUTH_SAML_SESSION: attributi mappati in sessione - user_id=%s, asl=%s", userID, asl)
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Servizio cache per le informazioni del backend (anno corrente, status):
+// GetCurrentYearFromServer e GetBackendStatus aprivano client freschi
+// senza context e bloccavano il rendering della pagina quando il backend
+// era lento. Le informazioni sono ora cachate con semantica
+// stale-while-revalidate: il valore in cache (anche scaduto) viene
+// restituito subito e il refresh avviene in background, così il
+// caricamento pagina non attende mai il backend.
+
+const (
+	backendInfoTTL          = 60 * time.Second // validità della cache
+	backendInfoFetchTimeout = 2 * time.Second  // budget del fetch sincrono iniziale
+)
+
+type backendInfoCache struct {
+	mu             sync.Mutex
+	year           int
+	dataSourceType string
+	status         *ServerStatusResponse
+	whisper        *WhisperHealth
+	fetchedAt      time.Time
+	refreshing     bool
+}
+
+// WhisperHealth descrive lo stato del server di trascrizione whisper
+type WhisperHealth struct {
+	Available   bool   `json:"available"`
+	Model       string `json:"model,omitempty"`
+	QueueLength int    `json:"queue_length,omitempty"`
+	LatencyMs   int64  `json:"latency_ms,omitempty"`
+}
+
+var backendInfo = &backendInfoCache{}
+
+// fetchBackendJSON esegue una GET con context e decodifica il JSON
+func fetchBackendJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	ApplyUpstreamHeaders(req, nil)
+
+	client := NewUpstreamClient("llm", 5*time.Second)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// whisperHealthURL deriva l'endpoint di health dal URL di inference
+// (env WHISPER_URL > config transcription.url)
+func whisperHealthURL(config *Config) string {
+	base := os.Getenv("WHISPER_URL")
+	if base == "" {
+		base = config.Transcription.URL
+	}
+	if base == "" {
+		return ""
+	}
+	return strings.TrimSuffix(base, "/inference") + "/health"
+}
+
+// probeWhisper verifica il server whisper e ne estrae modello, coda e
+// latenza (campi opzionali: dipendono dalla versione del server)
+func probeWhisper(ctx context.Context, config *Config) *WhisperHealth {
+	if !config.Transcription.Enabled {
+		return nil
+	}
+	healthURL := whisperHealthURL(config)
+	if healthURL == "" {
+		return nil
+	}
+
+	start := time.Now()
+	var health struct {
+		Model       string `json:"model"`
+		QueueLength int    `json:"queue_length"`
+	}
+	if err := fetchBackendJSON(ctx, healthURL, &health); err != nil {
+		log.Printf("BACKEND_INFO_WHISPER_ERROR: %v", err)
+		return &WhisperHealth{Available: false}
+	}
+	return &WhisperHealth{
+		Available:   true,
+		Model:       health.Model,
+		QueueLength: health.QueueLength,
+		LatencyMs:   time.Since(start).Milliseconds(),
+	}
+}
+
+// refresh aggiorna anno e status dal backend (chiamata bloccante)
+func (c *backendInfoCache) refresh(ctx context.Context, llmServerURL string) {
+	year := 0
+
+	// Anno corrente: prima /config, fallback /status
+	var configResp ServerConfigResponse
+	dataSourceType := ""
+	if err := fetchBackendJSON(ctx, llmServerURL+"/config", &configResp); err == nil && configResp.CurrentYear > 0 {
+		year = configResp.CurrentYear
+		dataSourceType = configResp.DataSourceType
+	}
+
+	var statusResp ServerStatusResponse
+	status := &statusResp
+	if err := fetchBackendJSON(ctx, llmServerURL+"/status", &statusResp); err != nil {
+		log.Printf("BACKEND_INFO_ERROR: fetch status fallito: %v", err)
+		status = nil
+	} else if year == 0 && statusResp.CurrentYear > 0 {
+		year = statusResp.CurrentYear
+	}
+
+	// Health del server whisper (trascrizione), se abilitato
+	whisper := probeWhisper(ctx, LoadConfig())
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if year > 0 {
+		c.year = year
+	}
+	if dataSourceType != "" {
+		c.dataSourceType = dataSourceType
+	}
+	if status != nil {
+		c.status = status
+	}
+	if whisper != nil {
+		c.whisper = whisper
+	}
+	if year > 0 || status != nil {
+		c.fetchedAt = time.Now()
+		log.Printf("BACKEND_INFO_REFRESHED: year=%d, status_ok=%v", c.year, status != nil)
+	}
+}
+
+// get ritorna anno e status con semantica stale-while-revalidate: cache
+// scaduta viene servita comunque mentre il refresh parte in background;
+// solo la primissima chiamata (cache vuota) fa un fetch sincrono breve.
+func (c *backendInfoCache) get(llmServerURL string) (int, *ServerStatusResponse) {
+	c.mu.Lock()
+	year, status := c.year, c.status
+	age := time.Since(c.fetchedAt)
+	empty := c.fetchedAt.IsZero()
+	needsRefresh := (empty || age > backendInfoTTL) && !c.refreshing
+	if needsRefresh {
+		c.refreshing = true
+	}
+	c.mu.Unlock()
+
+	if empty && needsRefresh {
+		// Cache vuota: unico caso in cui si attende, con budget limitato
+		ctx, cancel := context.WithTimeout(context.Background(), backendInfoFetchTimeout)
+		c.refresh(ctx, llmServerURL)
+		cancel()
+		c.mu.Lock()
+		c.refreshing = false
+		year, status = c.year, c.status
+		c.mu.Unlock()
+		return year, status
+	}
+
+	if needsRefresh {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			c.refresh(ctx, llmServerURL)
+			c.mu.Lock()
+			c.refreshing = false
+			c.mu.Unlock()
+		}()
+	}
+	return year, status
+}
+
+// GetCurrentYearFromServer ritorna l'anno corrente del backend (cachato)
+func GetCurrentYearFromServer(llmServerURL string) (int, error) {
+	year, _ := backendInfo.get(llmServerURL)
+	if year == 0 {
+		return 0, fmt.Errorf("impossibile ottenere anno corrente dal server")
+	}
+	return year, nil
+}
+
+// HandleContext espone al frontend le variabili di contesto del backend
+// (anno di riferimento, tipo sorgente dati, stato) in un contratto JSON
+// stabile, servito dalla cache stale-while-revalidate
+// GET /api/context
+func HandleContext(c *gin.Context) {
+	config := LoadConfig()
+
+	backendInfo.get(config.LLMServer.URL)
+	backendInfo.mu.Lock()
+	year := backendInfo.year
+	dataSourceType := backendInfo.dataSourceType
+	status := backendInfo.status
+	fetchedAt := backendInfo.fetchedAt
+	backendInfo.mu.Unlock()
+
+	resp := gin.H{
+		"status":           "success",
+		"reference_year":   year,
+		"data_source_type": dataSourceType,
+		"backend_status":   "unavailable",
+		"fetched_at":       fetchedAt.Unix(),
+	}
+	if status != nil {
+		resp["backend_status"] = status.Status
+		resp["model_loaded"] = status.ModelLoaded
+		resp["framework"] = status.Framework
+		resp["llm"] = status.LLM
+		resp["data_loaded"] = status.DataLoaded
+	}
+	if whisper := GetWhisperHealth(); whisper != nil {
+		resp["whisper"] = whisper
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetWhisperHealth ritorna lo stato cachato del server whisper (nil se la
+// trascrizione è disabilitata o mai verificata)
+func GetWhisperHealth() *WhisperHealth {
+	backendInfo.mu.Lock()
+	defer backendInfo.mu.Unlock()
+	return backendInfo.whisper
+}
+
+// GetBackendStatus ritorna lo status completo del backend incluso il nome
+// del modello LLM (cachato, mai bloccante oltre il primo fetch)
+func GetBackendStatus() *ServerStatusResponse {
+	cfg := LoadConfig()
+	llmServerURL := cfg.LLMServer.URL
+	if llmServerURL == "" {
+		llmServerURL = "http://localhost:5005"
+	}
+
+	_, status := backendInfo.get(llmServerURL)
+	if status == nil {
+		return &ServerStatusResponse{
+			Status:    "error",
+			Framework: "LangGraph",
+			LLM:       "unavailable",
+		}
+	}
+	return status
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Base path configurabile: "/gias/webchat" era cablato in main.go e in
+// ogni URL costruito lato server. Il prefisso primario arriva ora da
+// server.base_path e gli eventuali mount aggiuntivi (URL legacy) da
+// server.extra_mounts: le richieste sui prefissi extra vengono riscritte
+// internamente sul prefisso primario e ridispatchate, così template,
+// cookie e asset restano coerenti senza duplicare le route.
+
+const defaultBasePath = "/gias/webchat"
+
+// BasePath ritorna il prefisso primario dell'applicazione
+func BasePath(config *Config) string {
+	path := strings.TrimSuffix(config.Server.BasePath, "/")
+	if path == "" {
+		return defaultBasePath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// sessionCookiePath ritorna il path del cookie di sessione: il prefisso
+// primario, oppure "/" quando esistono mount aggiuntivi da coprire
+func sessionCookiePath(config *Config) string {
+	if len(config.Server.ExtraMounts) > 0 {
+		return "/"
+	}
+	return BasePath(config)
+}
+
+// RegisterExtraMounts registra i prefissi legacy: ogni richiesta viene
+// riscritta sul base path primario e ridispatchata sul router
+func RegisterExtraMounts(r *gin.Engine, config *Config, basePath string) {
+	for _, mount := range config.Server.ExtraMounts {
+		mount = strings.TrimSuffix(strings.TrimSpace(mount), "/")
+		if mount == "" || mount == basePath {
+			continue
+		}
+		if !strings.HasPrefix(mount, "/") {
+			mount = "/" + mount
+		}
+		group := r.Group(mount)
+		group.Any("/*path", func(c *gin.Context) {
+			c.Request.URL.Path = basePath + c.Param("path")
+			r.HandleContext(c)
+		})
+	}
+}
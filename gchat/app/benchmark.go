@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Benchmark NLU in blocco: un insieme di frasi con intent atteso viene
+// eseguito attraverso ParseMessage con concorrenza limitata, producendo
+// accuratezza e matrice di confusione. Serve come test di regressione
+// quando il modello del backend viene riaddestrato.
+
+const benchmarkDefaultWorkers = 4
+const benchmarkMaxWorkers = 16
+const benchmarkMaxCases = 1000
+
+// BenchmarkCase è una singola frase con l'intent atteso
+type BenchmarkCase struct {
+	Text           string `json:"text"`
+	ExpectedIntent string `json:"expected_intent"`
+}
+
+// benchmarkCaseResult è l'esito di un singolo caso
+type benchmarkCaseResult struct {
+	Text            string  `json:"text"`
+	ExpectedIntent  string  `json:"expected_intent"`
+	PredictedIntent string  `json:"predicted_intent"`
+	Confidence      float64 `json:"confidence"`
+	Correct         bool    `json:"correct"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// parseBenchmarkCases legge i casi dal body: JSON ({"cases":[...]}) oppure
+// CSV con colonne text,expected_intent (header opzionale)
+func parseBenchmarkCases(contentType string, body []byte) ([]BenchmarkCase, error) {
+	if strings.Contains(contentType, "text/csv") {
+		reader := csv.NewReader(strings.NewReader(string(body)))
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("CSV non valido: %v", err)
+		}
+		var cases []BenchmarkCase
+		for i, record := range records {
+			if len(record) < 2 {
+				return nil, fmt.Errorf("riga %d: attese 2 colonne (text,expected_intent)", i+1)
+			}
+			// Salta l'eventuale header
+			if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "text") {
+				continue
+			}
+			cases = append(cases, BenchmarkCase{
+				Text:           strings.TrimSpace(record[0]),
+				ExpectedIntent: strings.TrimSpace(record[1]),
+			})
+		}
+		return cases, nil
+	}
+
+	var payload struct {
+		Cases []BenchmarkCase `json:"cases"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("JSON non valido: %v", err)
+	}
+	return payload.Cases, nil
+}
+
+// HandleBenchmark esegue il benchmark NLU e restituisce il report
+// POST /admin/api/benchmark?workers=N
+func HandleBenchmark(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	cases, err := parseBenchmarkCases(c.GetHeader("Content-Type"), body)
+	if err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	if len(cases) == 0 {
+		AbortWithError(c, ErrInvalidRequest, "nessun caso di test nel body")
+		return
+	}
+	if len(cases) > benchmarkMaxCases {
+		AbortWithError(c, ErrInvalidParameter, fmt.Sprintf("massimo %d casi per esecuzione", benchmarkMaxCases))
+		return
+	}
+
+	workers := benchmarkDefaultWorkers
+	if w, werr := strconv.Atoi(c.Query("workers")); werr == nil && w > 0 && w <= benchmarkMaxWorkers {
+		workers = w
+	}
+
+	config := LoadConfig()
+	log.Printf("BENCHMARK_START: client_ip=%s, cases=%d, workers=%d", c.ClientIP(), len(cases), workers)
+	start := time.Now()
+
+	results := make([]benchmarkCaseResult, len(cases))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+
+	for i, bc := range cases {
+		wg.Add(1)
+		go func(idx int, tc BenchmarkCase) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result := benchmarkCaseResult{
+				Text:           tc.Text,
+				ExpectedIntent: tc.ExpectedIntent,
+			}
+			parseResp, perr := ParseMessage(tc.Text, config.LLMServer.URL, config.LLMServer.Timeout, nil)
+			if perr != nil {
+				result.Error = perr.Error()
+			} else {
+				result.PredictedIntent = parseResp.Intent
+				result.Confidence = parseResp.Confidence
+				result.Correct = parseResp.Intent == tc.ExpectedIntent
+			}
+			results[idx] = result
+		}(i, bc)
+	}
+	wg.Wait()
+
+	// Aggregazione: accuratezza e matrice di confusione atteso->predetto
+	correct := 0
+	failed := 0
+	confusion := make(map[string]map[string]int)
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+			continue
+		}
+		if result.Correct {
+			correct++
+		}
+		if confusion[result.ExpectedIntent] == nil {
+			confusion[result.ExpectedIntent] = make(map[string]int)
+		}
+		confusion[result.ExpectedIntent][result.PredictedIntent]++
+	}
+
+	evaluated := len(cases) - failed
+	accuracy := 0.0
+	if evaluated > 0 {
+		accuracy = float64(correct) / float64(evaluated)
+	}
+
+	duration := time.Since(start)
+	log.Printf("BENCHMARK_DONE: cases=%d, correct=%d, failed=%d, accuracy=%.3f, duration=%v",
+		len(cases), correct, failed, accuracy, duration)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":           "success",
+		"cases_total":      len(cases),
+		"cases_correct":    correct,
+		"cases_failed":     failed,
+		"accuracy":         accuracy,
+		"confusion_matrix": confusion,
+		"duration_ms":      duration.Milliseconds(),
+		"results":          results,
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Build info per il fleet tooling: con le istanze distribuite una per ASL
+// (Terraform/Kubernetes) serve verificare da remoto quale build e quale
+// configurazione gira davvero su ogni nodo, senza SSH. GET
+// /admin/api/buildinfo espone versione, commit, data di build (iniettati
+// via ldflags), versione Go, feature abilitate e checksum del config.json
+// su disco, così un rollout incompleto o una config divergente si vedono
+// con una GET.
+
+// Valorizzati in build con:
+//
+//	go build -ldflags "-X main.buildVersion=1.4.0 \
+//	    -X main.buildCommit=$(git rev-parse --short HEAD) \
+//	    -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// In assenza di ldflags (build locale) restano i default "dev".
+var (
+	buildVersion = "dev"
+	buildCommit  = ""
+	buildTime    = ""
+)
+
+// configChecksum calcola lo sha256 del config.json così come scritto su
+// disco (prima dell'applicazione del profilo GIAS_PROFILE)
+func configChecksum() string {
+	data, err := os.ReadFile("config/config.json")
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// enabledFeatures ritorna i nomi delle feature attive in ordine alfabetico
+func enabledFeatures(config *Config) []string {
+	features := []string{}
+	for name, enabled := range config.Features {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+// HandleBuildInfo espone build e configurazione runtime dell'istanza
+// GET /admin/api/buildinfo
+func HandleBuildInfo(c *gin.Context) {
+	config := LoadConfig()
+	c.JSON(http.StatusOK, gin.H{
+		"version":          buildVersion,
+		"commit":           buildCommit,
+		"build_time":       buildTime,
+		"go_version":       runtime.Version(),
+		"profile":          os.Getenv("GIAS_PROFILE"),
+		"features_enabled": enabledFeatures(config),
+		"config_checksum":  configChecksum(),
+	})
+}
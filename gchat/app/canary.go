@@ -0,0 +1,133 @@
+package main
+
+import (
+	"hash/fnv"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Canary routing verso una nuova versione del backend: quando il team
+// backend rilascia un nuovo modello, una percentuale configurabile di
+// conversazioni (sticky per sender, così un dialogo non cambia variante a
+// metà) viene instradata sull'URL canary. I record analytics vengono
+// taggati con la variante e un endpoint admin permette di aggiustare lo
+// split o abortire istantaneamente senza riavvio.
+
+// CanaryConfig configura il canary (sezione canary)
+type CanaryConfig struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url"`
+	Percent int    `json:"percent"` // 0-100, quota di sender sul canary
+}
+
+type canaryState struct {
+	percentOverride int // -1 = usa il valore di config
+	aborted         bool
+	mu              sync.Mutex
+}
+
+var canary = &canaryState{percentOverride: -1}
+
+// Contatori turni per variante (esposti sull'endpoint admin)
+var (
+	canaryStableTurns int64
+	canaryCanaryTurns int64
+)
+
+// canaryEffectivePercent ritorna lo split effettivo (override runtime > config)
+func canaryEffectivePercent(config *Config) int {
+	canary.mu.Lock()
+	defer canary.mu.Unlock()
+	if canary.aborted {
+		return 0
+	}
+	if canary.percentOverride >= 0 {
+		return canary.percentOverride
+	}
+	return config.Canary.Percent
+}
+
+// canaryBucket assegna il sender a un bucket stabile 0-99
+func canaryBucket(sender string) int {
+	h := fnv.New32a()
+	h.Write([]byte(sender))
+	return int(h.Sum32() % 100)
+}
+
+// CanaryRoute decide l'URL backend e la variante per una conversazione.
+// Sticky per sender: lo stesso sender finisce sempre nello stesso bucket.
+func CanaryRoute(config *Config, sender string) (string, string) {
+	if !config.Canary.Enabled || config.Canary.URL == "" {
+		return config.LLMServer.URL, "stable"
+	}
+	percent := canaryEffectivePercent(config)
+	if percent <= 0 || canaryBucket(sender) >= percent {
+		atomic.AddInt64(&canaryStableTurns, 1)
+		return config.LLMServer.URL, "stable"
+	}
+	atomic.AddInt64(&canaryCanaryTurns, 1)
+	return config.Canary.URL, "canary"
+}
+
+// HandleCanaryStatus espone lo stato corrente del canary
+// GET /admin/api/canary
+func HandleCanaryStatus(c *gin.Context) {
+	config := LoadConfig()
+
+	canary.mu.Lock()
+	override := canary.percentOverride
+	aborted := canary.aborted
+	canary.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":            "success",
+		"enabled":           config.Canary.Enabled && config.Canary.URL != "",
+		"url":               config.Canary.URL,
+		"config_percent":    config.Canary.Percent,
+		"override_percent":  override,
+		"effective_percent": canaryEffectivePercent(config),
+		"aborted":           aborted,
+		"stable_turns":      atomic.LoadInt64(&canaryStableTurns),
+		"canary_turns":      atomic.LoadInt64(&canaryCanaryTurns),
+	})
+}
+
+// HandleCanaryAdjust aggiusta lo split a runtime o aborta il canary
+// POST /admin/api/canary {"percent": 10} oppure {"abort": true}
+func HandleCanaryAdjust(c *gin.Context) {
+	var req struct {
+		Percent *int `json:"percent"`
+		Abort   bool `json:"abort"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	canary.mu.Lock()
+	if req.Abort {
+		canary.aborted = true
+		canary.percentOverride = -1
+		log.Printf("CANARY_ABORTED: tutto il traffico torna sul backend stabile")
+	} else if req.Percent != nil {
+		if *req.Percent < 0 || *req.Percent > 100 {
+			canary.mu.Unlock()
+			AbortWithError(c, ErrInvalidParameter, "percent deve essere tra 0 e 100")
+			return
+		}
+		canary.aborted = false
+		canary.percentOverride = *req.Percent
+		log.Printf("CANARY_ADJUSTED: split portato al %d%%", *req.Percent)
+	} else {
+		canary.mu.Unlock()
+		AbortWithError(c, ErrInvalidRequest, "indicare percent oppure abort")
+		return
+	}
+	canary.mu.Unlock()
+
+	HandleCanaryStatus(c)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Probing delle capacità del backend: verifica una volta all'avvio se il
+// backend riporta execution_path/node_timings nelle risposte V1, così da
+// evitare di fabbricare dati di debug simulati spacciandoli per reali.
+
+type backendCapabilities struct {
+	ExecutionTracking bool // il backend riporta execution_path/node_timings
+	Probed            bool // probe completato (con successo o meno)
+	mu                sync.RWMutex
+}
+
+var backendCaps = &backendCapabilities{}
+
+// GetBackendCapabilities ritorna lo stato corrente delle capacità rilevate
+func GetBackendCapabilities() (executionTracking, probed bool) {
+	backendCaps.mu.RLock()
+	defer backendCaps.mu.RUnlock()
+	return backendCaps.ExecutionTracking, backendCaps.Probed
+}
+
+// ProbeBackendCapabilities interroga il backend con un messaggio minimo e
+// registra se la risposta include il tracking di esecuzione. Da chiamare
+// in una goroutine all'avvio: non blocca il server se il backend è giù.
+func ProbeBackendCapabilities(llmServerURL string, timeout int) {
+	log.Printf("CAPABILITY_PROBE: probing backend execution tracking - url=%s", llmServerURL)
+
+	// Attendi che il backend sia raggiungibile (retry con backoff limitato)
+	var resp *NativeChatResponse
+	var err error
+	for attempt := 1; attempt <= 3; attempt++ {
+		resp, err = SendToLLMV1("ciao", "capability_probe", llmServerURL, timeout, map[string]interface{}{})
+		if err == nil {
+			break
+		}
+		log.Printf("CAPABILITY_PROBE_RETRY: attempt=%d, error=%v", attempt, err)
+		time.Sleep(time.Duration(attempt*5) * time.Second)
+	}
+
+	backendCaps.mu.Lock()
+	defer backendCaps.mu.Unlock()
+	backendCaps.Probed = true
+
+	if err != nil {
+		log.Printf("CAPABILITY_PROBE_ERROR: backend non raggiungibile, capacità sconosciute: %v", err)
+		return
+	}
+
+	backendCaps.ExecutionTracking = resp.Result.Execution != nil &&
+		len(resp.Result.Execution.ExecutionPath) > 0
+	log.Printf("CAPABILITY_PROBE_OK: execution_tracking=%v", backendCaps.ExecutionTracking)
+}
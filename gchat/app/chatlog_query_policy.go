@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Policy dichiarativa sulle query del proxy chat-log: le dashboard possono
+// comporre query arbitrarie e una richiesta senza limiti (intervalli di
+// date aperti, page size enormi, filtri costosi) si traduce in una query
+// pesante sul PostgreSQL dietro il backend. Oltre all'allowlist dei path,
+// il proxy normalizza i parametri prima dell'inoltro: page size e ampiezza
+// dell'intervallo date vengono limitate, i parametri vietati rimossi. I
+// limiti si dichiarano in config.json (sezione chatlog_proxy).
+
+// ChatLogProxyConfig definisce i limiti sui parametri del proxy chat-log
+type ChatLogProxyConfig struct {
+	// MaxPageSize: cap su limit/page_size/per_page (default 100)
+	MaxPageSize int `json:"max_page_size"`
+	// MaxRangeDays: ampiezza massima dell'intervallo date (default 90)
+	MaxRangeDays int `json:"max_range_days"`
+	// ForbiddenParams: parametri rimossi prima dell'inoltro
+	ForbiddenParams []string `json:"forbidden_params"`
+}
+
+const (
+	chatlogDefaultMaxPageSize  = 100
+	chatlogDefaultMaxRangeDays = 90
+)
+
+// chi sono i parametri di paginazione e di intervallo date nelle API chat-log
+var (
+	chatlogPageSizeParams = []string{"limit", "page_size", "per_page"}
+	chatlogDateRangePairs = [][2]string{{"from", "to"}, {"date_from", "date_to"}, {"start_date", "end_date"}}
+	chatlogDateFormats    = []string{"2006-01-02", time.RFC3339}
+)
+
+func parseChatlogDate(value string) (time.Time, bool) {
+	for _, format := range chatlogDateFormats {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SanitizeChatLogQuery applica la policy ai parametri e ritorna la query
+// normalizzata più l'elenco delle correzioni applicate (per il log)
+func SanitizeChatLogQuery(config *Config, query url.Values) (url.Values, []string) {
+	maxPageSize := config.ChatLogProxy.MaxPageSize
+	if maxPageSize <= 0 {
+		maxPageSize = chatlogDefaultMaxPageSize
+	}
+	maxRangeDays := config.ChatLogProxy.MaxRangeDays
+	if maxRangeDays <= 0 {
+		maxRangeDays = chatlogDefaultMaxRangeDays
+	}
+
+	var adjustments []string
+
+	// Parametri vietati: rimossi senza inoltro
+	for _, param := range config.ChatLogProxy.ForbiddenParams {
+		if query.Has(param) {
+			query.Del(param)
+			adjustments = append(adjustments, "rimosso parametro vietato "+param)
+		}
+	}
+
+	// Page size: cap al massimo dichiarato, valori non numerici rimossi
+	for _, param := range chatlogPageSizeParams {
+		if !query.Has(param) {
+			continue
+		}
+		size, err := strconv.Atoi(query.Get(param))
+		if err != nil || size <= 0 {
+			query.Del(param)
+			adjustments = append(adjustments, "rimosso "+param+" non valido")
+			continue
+		}
+		if size > maxPageSize {
+			query.Set(param, strconv.Itoa(maxPageSize))
+			adjustments = append(adjustments, param+" limitato a "+strconv.Itoa(maxPageSize))
+		}
+	}
+
+	// Intervalli di date: ampiezza massima, il limite inferiore viene
+	// avvicinato al superiore se l'intervallo eccede
+	for _, pair := range chatlogDateRangePairs {
+		fromRaw, toRaw := query.Get(pair[0]), query.Get(pair[1])
+		if fromRaw == "" || toRaw == "" {
+			continue
+		}
+		from, okFrom := parseChatlogDate(fromRaw)
+		to, okTo := parseChatlogDate(toRaw)
+		if !okFrom || !okTo {
+			continue
+		}
+		if to.Sub(from) > time.Duration(maxRangeDays)*24*time.Hour {
+			capped := to.AddDate(0, 0, -maxRangeDays)
+			query.Set(pair[0], capped.Format("2006-01-02"))
+			adjustments = append(adjustments, pair[0]+" limitato a "+strconv.Itoa(maxRangeDays)+" giorni dall'estremo superiore")
+		}
+	}
+
+	return query, adjustments
+}
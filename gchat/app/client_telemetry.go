@@ -0,0 +1,170 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Telemetria degli errori lato client: il frontend JS non ha modo di
+// segnalare i propri fallimenti (fetch fallite, eccezioni nei renderer).
+// POST /api/telemetry/client-errors accetta eventi strutturati con
+// campionamento e rate limit per IP; l'aggregazione per stack hash è
+// esposta al monitor qualità.
+
+const (
+	telemetryDefaultRateLimit = 10  // eventi per minuto per IP
+	telemetryMaxGroups        = 500 // gruppi di errore tracciati
+	telemetryMaxMessageLen    = 500
+)
+
+// ClientTelemetryConfig configura l'ingestione (sezione client_telemetry)
+type ClientTelemetryConfig struct {
+	Enabled bool `json:"enabled"`
+	// SampleRate: frazione di eventi accettati (0 = tutti)
+	SampleRate float64 `json:"sample_rate"`
+	// RateLimitPerMin: eventi per minuto per IP (default 10)
+	RateLimitPerMin int `json:"rate_limit_per_min"`
+}
+
+// ClientErrorEvent è un evento di errore segnalato dal frontend
+type ClientErrorEvent struct {
+	Message   string `json:"message"`
+	StackHash string `json:"stack_hash"`
+	Browser   string `json:"browser"`
+	Feature   string `json:"feature"` // es. "chat", "streaming", "history"
+	URL       string `json:"url,omitempty"`
+}
+
+type clientErrorGroup struct {
+	Message   string         `json:"message"`
+	StackHash string         `json:"stack_hash"`
+	Feature   string         `json:"feature"`
+	Count     int            `json:"count"`
+	Browsers  map[string]int `json:"browsers"`
+	FirstSeen time.Time      `json:"first_seen"`
+	LastSeen  time.Time      `json:"last_seen"`
+}
+
+var (
+	telemetryGroups  = map[string]*clientErrorGroup{}
+	telemetryWindows = map[string][]time.Time{}
+	telemetryMu      sync.Mutex
+)
+
+// telemetryAllow applica il rate limit per IP (finestra mobile di un minuto)
+func telemetryAllow(clientIP string, limit int) bool {
+	now := time.Now()
+	window := telemetryWindows[clientIP][:0]
+	for _, t := range telemetryWindows[clientIP] {
+		if now.Sub(t) < time.Minute {
+			window = append(window, t)
+		}
+	}
+	if len(window) >= limit {
+		telemetryWindows[clientIP] = window
+		return false
+	}
+	telemetryWindows[clientIP] = append(window, now)
+	return true
+}
+
+// HandleClientErrors ingerisce un evento di errore dal frontend
+// POST /api/telemetry/client-errors
+func HandleClientErrors(c *gin.Context) {
+	config := LoadConfig()
+	if !config.ClientTelemetry.Enabled {
+		AbortWithError(c, ErrNotFound, "telemetria client disabilitata")
+		return
+	}
+
+	var event ClientErrorEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(event.Message) == "" {
+		AbortWithError(c, ErrInvalidParameter, "campo message mancante")
+		return
+	}
+	if len(event.Message) > telemetryMaxMessageLen {
+		event.Message = event.Message[:telemetryMaxMessageLen]
+	}
+
+	limit := config.ClientTelemetry.RateLimitPerMin
+	if limit <= 0 {
+		limit = telemetryDefaultRateLimit
+	}
+
+	telemetryMu.Lock()
+	defer telemetryMu.Unlock()
+
+	if !telemetryAllow(c.ClientIP(), limit) {
+		AbortWithError(c, ErrRateLimited, "limite eventi telemetria raggiunto")
+		return
+	}
+
+	// Campionamento: l'evento viene comunque confermato al client
+	if rate := config.ClientTelemetry.SampleRate; rate > 0 && rate < 1 && rand.Float64() >= rate {
+		c.JSON(http.StatusOK, gin.H{"status": "success", "sampled": false})
+		return
+	}
+
+	key := event.StackHash
+	if key == "" {
+		key = event.Feature + "|" + event.Message
+	}
+	group, ok := telemetryGroups[key]
+	if !ok {
+		if len(telemetryGroups) >= telemetryMaxGroups {
+			c.JSON(http.StatusOK, gin.H{"status": "success", "sampled": false})
+			return
+		}
+		group = &clientErrorGroup{
+			Message:   event.Message,
+			StackHash: event.StackHash,
+			Feature:   event.Feature,
+			Browsers:  map[string]int{},
+			FirstSeen: time.Now(),
+		}
+		telemetryGroups[key] = group
+		log.Printf("CLIENT_ERROR_NEW: feature=%s, message=\"%s\"", event.Feature, event.Message)
+	}
+	group.Count++
+	group.LastSeen = time.Now()
+	if event.Browser != "" {
+		group.Browsers[event.Browser]++
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "sampled": true})
+}
+
+// HandleClientErrorReport espone l'aggregazione per il monitor qualità
+// GET /api/monitor/client-errors
+func HandleClientErrorReport(c *gin.Context) {
+	telemetryMu.Lock()
+	groups := make([]clientErrorGroup, 0, len(telemetryGroups))
+	for _, group := range telemetryGroups {
+		copied := *group
+		copied.Browsers = make(map[string]int, len(group.Browsers))
+		for browser, count := range group.Browsers {
+			copied.Browsers[browser] = count
+		}
+		groups = append(groups, copied)
+	}
+	telemetryMu.Unlock()
+
+	// Gruppi più frequenti per primi
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Count > groups[j].Count })
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"errors": groups,
+	})
+}
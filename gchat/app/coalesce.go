@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+// Coalescing delle richieste identiche in volo (stile singleflight): un
+// doppio click su "invia" produce due chiamate backend identiche; qui la
+// seconda si aggancia alla prima e riceve la stessa risposta. La chiave è
+// sender + hash del messaggio, oppure l'header X-Idempotency-Key se fornito
+// dagli integratori. Le voci restano valide per una piccola finestra dopo
+// il completamento per coprire anche i duplicati appena successivi.
+
+const coalesceWindow = 2 * time.Second
+
+type inflightCall struct {
+	done chan struct{}
+	resp *NativeChatResponse
+	err  error
+}
+
+type coalescer struct {
+	calls map[string]*inflightCall
+	mu    sync.Mutex
+}
+
+var chatCoalescer = &coalescer{
+	calls: make(map[string]*inflightCall),
+}
+
+// CoalesceKey calcola la chiave di coalescing per una richiesta chat.
+// idempotencyKey (header X-Idempotency-Key) ha priorità se presente; la
+// chiave è comunque scoped al sender, così due utenti che forniscono lo
+// stesso valore non condividono mai la risposta dell'altro
+func CoalesceKey(sender, message, idempotencyKey string) string {
+	if idempotencyKey != "" {
+		return "idem:" + sender + "|" + idempotencyKey
+	}
+	sum := sha256.Sum256([]byte(sender + "|" + message))
+	return "msg:" + hex.EncodeToString(sum[:8])
+}
+
+// Do esegue fn garantendo che chiamate concorrenti con la stessa chiave
+// condividano la singola esecuzione. Ritorna anche se la risposta è stata
+// coalescata (per logging).
+func (co *coalescer) Do(key string, fn func() (*NativeChatResponse, error)) (*NativeChatResponse, bool, error) {
+	co.mu.Lock()
+	if call, ok := co.calls[key]; ok {
+		co.mu.Unlock()
+		<-call.done
+		return call.resp, true, call.err
+	}
+
+	call := &inflightCall{done: make(chan struct{})}
+	co.calls[key] = call
+	co.mu.Unlock()
+
+	call.resp, call.err = fn()
+	close(call.done)
+
+	// Mantieni la voce per la finestra di coalescing, poi rimuovila
+	time.AfterFunc(coalesceWindow, func() {
+		co.mu.Lock()
+		delete(co.calls, key)
+		co.mu.Unlock()
+	})
+
+	return call.resp, false, call.err
+}
+
+// CoalescedSendToLLMV1 è il wrapper di SendToLLMV1 con coalescing
+func CoalescedSendToLLMV1(key, message, sender, llmServerURL string, timeout int, context map[string]interface{}) (*NativeChatResponse, error) {
+	resp, coalesced, err := chatCoalescer.Do(key, func() (*NativeChatResponse, error) {
+		return SendToLLMV1(message, sender, llmServerURL, timeout, context)
+	})
+	if coalesced {
+		log.Printf("COALESCE_HIT: richiesta duplicata servita dalla chiamata in volo - sender=%s, key=%s", sender, key)
+	}
+	return resp, err
+}
@@ -2,26 +2,131 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
-	"time"
 )
 
 type Config struct {
-	Server             ServerConfig       `json:"server"`
-	LLMServer          LLMServerConfig    `json:"llm_server"`
-	Log                LogConfig          `json:"log"`
-	PredefinedQuestions []PredefinedQuestion `json:"predefined_questions"`
-	UI                 UIConfig           `json:"ui"`
-	Transcription      TranscriptionConfig `json:"transcription"`
+	Server              ServerConfig           `json:"server"`
+	LLMServer           LLMServerConfig        `json:"llm_server"`
+	Log                 LogConfig              `json:"log"`
+	PredefinedQuestions []PredefinedQuestion   `json:"predefined_questions"`
+	UI                  UIConfig               `json:"ui"`
+	Transcription       TranscriptionConfig    `json:"transcription"`
+	Debug               DebugConfig            `json:"debug"`
+	Translation         TranslationConfig      `json:"translation"`
+	ResponseLimits      ResponseLimitsConfig   `json:"response_limits"`
+	Auth                AuthConfig             `json:"auth"`
+	Anonymization       AnonymizationConfig    `json:"anonymization"`
+	Features            map[string]bool        `json:"features"`
+	HTTPCache           HTTPCacheConfig        `json:"http_cache"`
+	IntakeForm          IntakeFormConfig       `json:"intake_form"`
+	SLO                 SLOConfig              `json:"slo"`
+	Outbound            OutboundConfig         `json:"outbound"`
+	UpstreamHeaders     UpstreamHeadersConfig  `json:"upstream_headers"`
+	Retention           RetentionConfig        `json:"retention"`
+	SchemaRecorder      *SchemaRecorderConfig  `json:"schema_recorder,omitempty"`
+	Share               ShareConfig            `json:"share"`
+	Preprocess          PreprocessConfig       `json:"preprocess"`
+	Demo                DemoConfig             `json:"demo"`
+	SSEBatching         SSEBatchingConfig      `json:"sse_batching"`
+	Escalation          EscalationConfig       `json:"escalation"`
+	ClientTelemetry     ClientTelemetryConfig  `json:"client_telemetry"`
+	Confirmation        ConfirmationConfig     `json:"confirmation"`
+	LoadShedding        LoadSheddingConfig     `json:"load_shedding"`
+	AccessLog           AccessLogConfig        `json:"access_log"`
+	TTS                 TTSConfig              `json:"tts"`
+	MetadataPolicy      MetadataPolicyConfig   `json:"metadata_policy"`
+	Costs               CostsConfig            `json:"costs"`
+	Canary              CanaryConfig           `json:"canary"`
+	Abuse               AbuseConfig            `json:"abuse"`
+	NormalizeText       NormalizeTextConfig    `json:"normalize_text"`
+	Suggestions         SuggestionsConfig      `json:"suggestions"`
+	ChatLogProxy        ChatLogProxyConfig     `json:"chatlog_proxy"`
+	Middleware          MiddlewareConfig       `json:"middleware"`
+	Archive             ArchiveConfig          `json:"archive"`
+	IntentTimeouts      IntentTimeoutsConfig   `json:"intent_timeouts"`
+	SlowMode            SlowModeConfig         `json:"slow_mode"`
+	Scope               ScopeConfig            `json:"scope"`
+	Consent             ConsentConfig          `json:"consent"`
+	APIV1               APIV1Config            `json:"api_v1"`
+	LanguageGuard       LanguageGuardConfig    `json:"language_guard"`
+	SSEChannel          SSEChannelConfig       `json:"sse_channel"`
+	SecurityHeaders     *SecurityHeadersConfig `json:"security_headers,omitempty"`
+	Session             SessionConfig          `json:"session"`
+}
+
+type SessionConfig struct {
+	TTLSeconds int `json:"ttl_seconds"` // TTL sessione in secondi (default 300)
+}
+
+// AuthConfig seleziona la modalità di autenticazione del gateway:
+// "portal" (default, parametri dal portale GISA) o "saml" (SSO SPID/SAML)
+type AuthConfig struct {
+	Mode string     `json:"mode"`
+	SAML SAMLConfig `json:"saml"`
+}
+
+// SAMLConfig configura il Service Provider SAML (crewjam/saml)
+type SAMLConfig struct {
+	IDPMetadataURL   string `json:"idp_metadata_url"`
+	EntityID         string `json:"entity_id"`
+	RootURL          string `json:"root_url"`
+	CertFile         string `json:"cert_file"`
+	KeyFile          string `json:"key_file"`
+	AttributeMapping struct {
+		UserID        string `json:"user_id"`
+		CodiceFiscale string `json:"codice_fiscale"`
+		ASL           string `json:"asl"`
+		Username      string `json:"username"`
+	} `json:"attribute_mapping"`
+}
+
+// AnonymizationConfig definisce la policy di anonimizzazione degli export
+// analytics (il salt arriva preferibilmente da env ANONYMIZATION_SALT)
+type AnonymizationConfig struct {
+	Salt         string `json:"salt"`
+	KThreshold   int    `json:"k_threshold"`    // gruppi con meno utenti vengono soppressi (default 5)
+	KeepFreeText bool   `json:"keep_free_text"` // default false: i testi liberi vengono rimossi
+}
+
+// HTTPCacheConfig: policy Cache-Control per endpoint (secondi di max-age)
+type HTTPCacheConfig struct {
+	PredefinedQuestionsMaxAge int `json:"predefined_questions_max_age"`
+}
+
+// ResponseLimitsConfig controlla il troncamento lato server delle risposte
+// molto lunghe (0 o assente = nessun limite)
+type ResponseLimitsConfig struct {
+	MaxChars int `json:"max_chars"`
+}
+
+type TranslationConfig struct {
+	Enabled    bool   `json:"enabled"`
+	URL        string `json:"url"`         // endpoint compatibile LibreTranslate (/translate)
+	SourceLang string `json:"source_lang"` // lingua delle risposte backend (default "it")
+	Timeout    int    `json:"timeout"`     // secondi
+}
+
+type DebugConfig struct {
+	// SimulateExecutionPath: se true, quando il backend non riporta il tracking
+	// di esecuzione il debug mostra un percorso simulato marcato "simulated".
+	// Se false (default) i campi vengono semplicemente omessi.
+	SimulateExecutionPath bool `json:"simulate_execution_path"`
+	// LLMParamRanges: limiti ammessi per gli override llm_params in debug
+	LLMParamRanges LLMParamRanges `json:"llm_param_ranges"`
+	// CompareBackend: backend secondario per la modalità compare A/B
+	// (timeout 0 = stesso timeout di llm_server)
+	CompareBackend LLMServerConfig `json:"compare_backend"`
 }
 
 type ServerConfig struct {
 	Port string `json:"port"`
 	Host string `json:"host"`
+	// BasePath: prefisso primario dell'app (default /gias/webchat)
+	BasePath string `json:"base_path"`
+	// ExtraMounts: prefissi aggiuntivi per URL legacy, riscritti sul primario
+	ExtraMounts []string `json:"extra_mounts"`
 }
 
 type LLMServerConfig struct {
@@ -30,8 +135,8 @@ type LLMServerConfig struct {
 }
 
 type LogConfig struct {
-	Level      string `json:"level"`
-	File       string `json:"file"`
+	Level       string `json:"level"`
+	File        string `json:"file"`
 	EnableDebug bool   `json:"enable_debug"`
 	DebugFile   string `json:"debug_file"`
 }
@@ -54,21 +159,25 @@ type UIConfig struct {
 type TranscriptionConfig struct {
 	Enabled bool   `json:"enabled"`
 	URL     string `json:"url"`
+	// MaskPII: maschera telefoni/CF/email/nomi del personale nel testo trascritto
+	MaskPII bool `json:"mask_pii"`
+	// KeepWarmMinutes: intervallo del ping keep-warm verso whisper (default 10)
+	KeepWarmMinutes int `json:"keep_warm_minutes"`
 }
 
 type ServerConfigResponse struct {
-	CurrentYear      int    `json:"current_year"`
-	DataSourceType   string `json:"data_source_type"`
-	Status          string `json:"status"`
+	CurrentYear    int    `json:"current_year"`
+	DataSourceType string `json:"data_source_type"`
+	Status         string `json:"status"`
 }
 
 type ServerStatusResponse struct {
-	Status        string                 `json:"status"`
-	ModelLoaded   bool                   `json:"model_loaded"`
-	CurrentYear   int                    `json:"current_year"`
-	DataLoaded    map[string]interface{} `json:"data_loaded"`
-	Framework     string                 `json:"framework"`
-	LLM           string                 `json:"llm"`
+	Status      string                 `json:"status"`
+	ModelLoaded bool                   `json:"model_loaded"`
+	CurrentYear int                    `json:"current_year"`
+	DataLoaded  map[string]interface{} `json:"data_loaded"`
+	Framework   string                 `json:"framework"`
+	LLM         string                 `json:"llm"`
 }
 
 func LoadConfig() *Config {
@@ -79,12 +188,15 @@ func LoadConfig() *Config {
 		return getDefaultConfig()
 	}
 
-	data, err := ioutil.ReadFile(configPath)
+	data, err := os.ReadFile(configPath)
 	if err != nil {
 		log.Printf("Error reading config file: %v, using defaults", err)
 		return getDefaultConfig()
 	}
 
+	// Applica il profilo selezionato da GIAS_PROFILE (vedi profiles.go)
+	data = applyActiveProfile(data)
+
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
 		log.Printf("Error parsing config file: %v, using defaults", err)
@@ -94,46 +206,6 @@ func LoadConfig() *Config {
 	return &config
 }
 
-func GetCurrentYearFromServer(llmServerURL string) (int, error) {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	// Prova prima l'endpoint /config
-	resp, err := client.Get(llmServerURL + "/config")
-	if err == nil {
-		defer resp.Body.Close()
-		if resp.StatusCode == 200 {
-			body, err := ioutil.ReadAll(resp.Body)
-			if err == nil {
-				var configResp ServerConfigResponse
-				if json.Unmarshal(body, &configResp) == nil {
-					log.Printf("SERVER_CONFIG: Anno corrente dal server: %d", configResp.CurrentYear)
-					return configResp.CurrentYear, nil
-				}
-			}
-		}
-	}
-
-	// Fallback: prova endpoint /status
-	resp, err = client.Get(llmServerURL + "/status")
-	if err == nil {
-		defer resp.Body.Close()
-		if resp.StatusCode == 200 {
-			body, err := ioutil.ReadAll(resp.Body)
-			if err == nil {
-				var statusResp ServerStatusResponse
-				if json.Unmarshal(body, &statusResp) == nil && statusResp.CurrentYear > 0 {
-					log.Printf("SERVER_STATUS: Anno corrente dal server: %d", statusResp.CurrentYear)
-					return statusResp.CurrentYear, nil
-				}
-			}
-		}
-	}
-
-	return 0, fmt.Errorf("impossibile ottenere anno corrente dal server")
-}
-
 func getDefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
@@ -194,53 +266,3 @@ func getDefaultConfig() *Config {
 		},
 	}
 }
-
-// GetBackendStatus ritorna lo status completo del backend incluso il nome del modello LLM
-func GetBackendStatus() *ServerStatusResponse {
-	cfg := LoadConfig()
-	llmServerURL := cfg.LLMServer.URL
-	if llmServerURL == "" {
-		llmServerURL = "http://localhost:5005"
-	}
-
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(llmServerURL + "/status")
-	if err != nil {
-		log.Printf("BACKEND_STATUS_ERROR: %v", err)
-		return &ServerStatusResponse{
-			Status:    "error",
-			Framework: "LangGraph",
-			LLM:       "unavailable",
-		}
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return &ServerStatusResponse{
-			Status:    "error",
-			Framework: "LangGraph",
-			LLM:       "unavailable",
-		}
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return &ServerStatusResponse{
-			Status:    "error",
-			Framework: "LangGraph",
-			LLM:       "unavailable",
-		}
-	}
-
-	var statusResp ServerStatusResponse
-	if err := json.Unmarshal(body, &statusResp); err != nil {
-		return &ServerStatusResponse{
-			Status:    "error",
-			Framework: "LangGraph",
-			LLM:       "unavailable",
-		}
-	}
-
-	log.Printf("BACKEND_STATUS_OK: framework=%s, llm=%s", statusResp.Framework, statusResp.LLM)
-	return &statusResp
-}
\ No newline at end of file
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Conferma pre-esecuzione per gli intent che modificano dati: quando il
+// backend marca un'azione con requires_confirmation (es. programmazione di
+// un controllo), il gateway la trattiene, risponde con un token d'azione
+// firmato HMAC e inoltra l'esecuzione solo alla conferma esplicita su
+// POST /api/actions/confirm. Il segreto di firma arriva dalla variabile
+// d'ambiente ACTION_TOKEN_SECRET (fallback confirmation.secret in config).
+
+const (
+	confirmationDefaultTTLSec = 300 // validità del token (default 5 minuti)
+	confirmationEnvSecret     = "ACTION_TOKEN_SECRET"
+)
+
+// ConfirmationConfig abilita il flusso in config.json (sezione confirmation)
+type ConfirmationConfig struct {
+	Enabled    bool   `json:"enabled"`
+	TTLSeconds int    `json:"ttl_seconds"` // validità del token (default 300)
+	Secret     string `json:"secret"`      // preferire env ACTION_TOKEN_SECRET
+}
+
+// pendingAction è un'azione trattenuta in attesa di conferma (monouso)
+type pendingAction struct {
+	ID        string                 `json:"id"`
+	Sender    string                 `json:"sender"`
+	Intent    string                 `json:"intent"`
+	Summary   string                 `json:"summary"`
+	Slots     map[string]interface{} `json:"-"`
+	expiresAt time.Time
+}
+
+var (
+	pendingActions = map[string]*pendingAction{}
+	pendingMu      sync.Mutex
+)
+
+// confirmationSecret ritorna il segreto di firma: env var > config
+func confirmationSecret(config *Config) string {
+	if secret := os.Getenv(confirmationEnvSecret); secret != "" {
+		return secret
+	}
+	return config.Confirmation.Secret
+}
+
+// signActionToken firma id azione e scadenza con HMAC-SHA256
+func signActionToken(secret, actionID string, exp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "action|%s|%d", actionID, exp)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// prunePendingActions rimuove le azioni scadute (lock già acquisito)
+func prunePendingActions() {
+	now := time.Now()
+	for id, action := range pendingActions {
+		if now.After(action.expiresAt) {
+			delete(pendingActions, id)
+		}
+	}
+}
+
+// ExtractPendingAction trattiene un'azione marcata requires_confirmation
+// dal backend e ritorna il payload di conferma da esporre alla UI
+// (nil se la risposta non richiede conferma o il flusso è disabilitato)
+func ExtractPendingAction(config *Config, sender string, result *ChatResultV1) gin.H {
+	if !config.Confirmation.Enabled || result.Slots == nil {
+		return nil
+	}
+	required, _ := result.Slots["requires_confirmation"].(bool)
+	if !required {
+		return nil
+	}
+	secret := confirmationSecret(config)
+	if secret == "" {
+		log.Printf("CONFIRM_ERROR: nessun segreto di firma configurato (%s o confirmation.secret)", confirmationEnvSecret)
+		return nil
+	}
+
+	ttl := config.Confirmation.TTLSeconds
+	if ttl <= 0 {
+		ttl = confirmationDefaultTTLSec
+	}
+	expiresAt := time.Now().Add(time.Duration(ttl) * time.Second)
+
+	summary, _ := result.Slots["action_summary"].(string)
+	if summary == "" {
+		summary = result.Text
+	}
+
+	action := &pendingAction{
+		ID:        newContinuationHandle(),
+		Sender:    sender,
+		Intent:    result.Intent,
+		Summary:   summary,
+		Slots:     result.Slots,
+		expiresAt: expiresAt,
+	}
+
+	pendingMu.Lock()
+	prunePendingActions()
+	pendingActions[action.ID] = action
+	pendingMu.Unlock()
+
+	exp := expiresAt.Unix()
+	token := fmt.Sprintf("%s.%d.%s", action.ID, exp, signActionToken(secret, action.ID, exp))
+
+	log.Printf("CONFIRM_PENDING: action=%s, sender=%s, intent=%s, expires=%s",
+		action.ID, sender, result.Intent, expiresAt.Format("15:04:05"))
+
+	return gin.H{
+		"action_token": token,
+		"summary":      summary,
+		"intent":       result.Intent,
+		"expires_at":   exp,
+	}
+}
+
+// parseActionToken verifica firma e scadenza e ritorna l'id azione
+func parseActionToken(secret, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("formato token non valido")
+	}
+	actionID := parts[0]
+	var exp int64
+	if _, err := fmt.Sscanf(parts[1], "%d", &exp); err != nil {
+		return "", fmt.Errorf("scadenza token non valida")
+	}
+	expected := signActionToken(secret, actionID, exp)
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return "", fmt.Errorf("firma token non valida")
+	}
+	if time.Now().Unix() > exp {
+		return "", fmt.Errorf("token scaduto")
+	}
+	return actionID, nil
+}
+
+// executeConfirmedAction inoltra l'esecuzione al backend sul canale chat V1
+// (stesso pattern delle submit dei form dinamici)
+func executeConfirmedAction(config *Config, action *pendingAction) (*NativeChatResponse, error) {
+	slots := make(map[string]interface{}, len(action.Slots)+1)
+	for key, value := range action.Slots {
+		slots[key] = value
+	}
+	delete(slots, "requires_confirmation")
+	slots["confirmed"] = true
+
+	payload := map[string]interface{}{
+		"sender":  action.Sender,
+		"message": "/execute_action{\"intent\": \"" + action.Intent + "\"}",
+		"slots":   slots,
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewUpstreamClient("llm", time.Duration(config.LLMServer.Timeout)*time.Second)
+	req, err := http.NewRequest("POST", config.LLMServer.URL+"/api/v1/chat", strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	ApplyUpstreamHeaders(req, nil)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("backend status %d: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var chatResp NativeChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, err
+	}
+	return &chatResp, nil
+}
+
+// HandleActionConfirm esegue o annulla un'azione trattenuta
+// POST /api/actions/confirm {"token": "...", "confirm": true}
+func HandleActionConfirm(c *gin.Context) {
+	config := LoadConfig()
+	if !config.Confirmation.Enabled {
+		AbortWithError(c, ErrNotFound, "flusso di conferma disabilitato")
+		return
+	}
+	secret := confirmationSecret(config)
+	if secret == "" {
+		AbortWithError(c, ErrInternal, "flusso di conferma non configurato sul server")
+		return
+	}
+
+	var req struct {
+		Token   string `json:"token"`
+		Confirm bool   `json:"confirm"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	actionID, err := parseActionToken(secret, req.Token)
+	if err != nil {
+		AbortWithError(c, ErrForbidden, err.Error())
+		return
+	}
+
+	// Token monouso: l'azione viene consumata sia su conferma che su annullo
+	pendingMu.Lock()
+	prunePendingActions()
+	action, ok := pendingActions[actionID]
+	delete(pendingActions, actionID)
+	pendingMu.Unlock()
+	if !ok {
+		AbortWithError(c, ErrNotFound, "azione scaduta o già eseguita")
+		return
+	}
+
+	if !req.Confirm {
+		log.Printf("CONFIRM_DECLINED: action=%s, sender=%s, intent=%s", action.ID, action.Sender, action.Intent)
+		c.JSON(http.StatusOK, ChatResponse{
+			Message: "Operazione annullata, non è stata apportata alcuna modifica.",
+			Status:  "success",
+		})
+		return
+	}
+
+	v1Resp, err := executeConfirmedAction(config, action)
+	if err != nil {
+		log.Printf("CONFIRM_ERROR: esecuzione fallita - action=%s, error=%v", action.ID, err)
+		AbortWithError(c, ErrBackendError, err.Error())
+		return
+	}
+
+	log.Printf("CONFIRM_EXECUTED: action=%s, sender=%s, intent=%s", action.ID, action.Sender, action.Intent)
+
+	c.JSON(http.StatusOK, ChatResponse{
+		Message: v1Resp.Result.Text,
+		Status:  "success",
+	})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Consenso esplicito all'informativa privacy: prima del primo uso l'utente
+// deve accettare l'informativa (obbligo GDPR, i messaggi chat finiscono
+// nel chat-log). Il testo e la versione dell'informativa stanno in config
+// (sezione consent); l'accettazione è persistita per utente con la
+// versione accettata, e a ogni bump di versione il consenso va rinnovato.
+// Con il consenso mancante la chat viene bloccata finché l'utente non
+// accetta via POST /api/consent.
+
+const consentFile = "data/consents.json"
+
+// ConsentConfig configura l'informativa privacy (sezione consent)
+type ConsentConfig struct {
+	Enabled bool `json:"enabled"`
+	// Version: identificativo della versione corrente dell'informativa
+	// (es. "2026-01"); al cambio il consenso va rinnovato
+	Version string `json:"version"`
+	// NoticeText: testo dell'informativa mostrato dalla UI
+	NoticeText string `json:"notice_text"`
+}
+
+// ConsentRecord è l'accettazione registrata per un utente
+type ConsentRecord struct {
+	UserKey    string    `json:"user_key"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+type consentStore struct {
+	records map[string]*ConsentRecord // user key -> ultima accettazione
+	loaded  bool
+	mu      sync.Mutex
+}
+
+var consents = &consentStore{}
+
+func (s *consentStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.records = map[string]*ConsentRecord{}
+
+	data, err := os.ReadFile(consentFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("CONSENT_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		log.Printf("CONSENT_LOAD_ERROR: parse: %v", err)
+		s.records = map[string]*ConsentRecord{}
+	}
+}
+
+func (s *consentStore) persist() {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		log.Printf("CONSENT_PERSIST_ERROR: marshal: %v", err)
+		return
+	}
+	if err := os.WriteFile(consentFile, data, 0644); err != nil {
+		log.Printf("CONSENT_PERSIST_ERROR: write: %v", err)
+	}
+}
+
+// consentUserKey deriva la chiave utente per il consenso: user_id se noto,
+// altrimenti codice fiscale, altrimenti il sender di sessione
+func consentUserKey(userID, codiceFiscale, sender string) string {
+	if userID != "" {
+		return "user:" + userID
+	}
+	if codiceFiscale != "" {
+		return "cf:" + codiceFiscale
+	}
+	if sender != "" {
+		return "sender:" + sender
+	}
+	return ""
+}
+
+// ConsentAccepted verifica se la chiave utente ha accettato la versione
+// corrente dell'informativa
+func ConsentAccepted(config *Config, userKey string) bool {
+	if userKey == "" {
+		return false
+	}
+	consents.mu.Lock()
+	defer consents.mu.Unlock()
+	consents.ensureLoaded()
+
+	record, ok := consents.records[userKey]
+	return ok && record.Version == config.Consent.Version
+}
+
+// RequireConsent blocca la richiesta chat se il consenso alla versione
+// corrente dell'informativa manca. Ritorna false se la richiesta è stata
+// abortita.
+func RequireConsent(c *gin.Context, config *Config, userID, codiceFiscale, sender string) bool {
+	if !config.Consent.Enabled {
+		return true
+	}
+	userKey := consentUserKey(userID, codiceFiscale, sender)
+	if ConsentAccepted(config, userKey) {
+		return true
+	}
+	log.Printf("CONSENT_BLOCKED: user_key=%s, version=%s", userKey, config.Consent.Version)
+	c.Header("X-Consent-Required", config.Consent.Version)
+	AbortWithError(c, ErrForbidden, "è necessario accettare l'informativa privacy prima di usare la chat")
+	return false
+}
+
+// HandleConsentNotice ritorna versione e testo dell'informativa corrente,
+// con lo stato di accettazione dell'utente in sessione
+// GET /api/consent
+func HandleConsentNotice(c *gin.Context) {
+	config := LoadConfig()
+
+	session := sessions.Default(c)
+	userID, _ := session.Get("user_id").(string)
+	cf, _ := session.Get("codice_fiscale").(string)
+	userKey := consentUserKey(userID, cf, "")
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"required": config.Consent.Enabled,
+		"version":  config.Consent.Version,
+		"notice":   config.Consent.NoticeText,
+		"accepted": !config.Consent.Enabled || ConsentAccepted(config, userKey),
+	})
+}
+
+// HandleConsentAccept registra l'accettazione dell'informativa corrente
+// POST /api/consent {"sender": "...", "version": "..."}
+func HandleConsentAccept(c *gin.Context) {
+	var req struct {
+		Sender  string `json:"sender"`
+		Version string `json:"version"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	config := LoadConfig()
+	if req.Version != "" && req.Version != config.Consent.Version {
+		// L'utente ha accettato una versione superata (informativa cambiata
+		// mentre il banner era aperto): la UI deve ripresentarla
+		AbortWithError(c, ErrInvalidParameter, "versione informativa superata, ricaricare il testo corrente")
+		return
+	}
+
+	session := sessions.Default(c)
+	userID, _ := session.Get("user_id").(string)
+	cf, _ := session.Get("codice_fiscale").(string)
+	userKey := consentUserKey(userID, cf, req.Sender)
+	if userKey == "" {
+		AbortWithError(c, ErrInvalidParameter, "nessuna identità a cui associare il consenso")
+		return
+	}
+
+	consents.mu.Lock()
+	consents.ensureLoaded()
+	consents.records[userKey] = &ConsentRecord{
+		UserKey:    userKey,
+		Version:    config.Consent.Version,
+		AcceptedAt: time.Now(),
+	}
+	consents.persist()
+	consents.mu.Unlock()
+
+	log.Printf("CONSENT_ACCEPTED: user_key=%s, version=%s", userKey, config.Consent.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"version": config.Consent.Version,
+	})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Codici conversazione leggibili al telefono: le chiamate al supporto
+// citano "la risposta di ieri" senza alcun identificatore. Ogni
+// conversazione riceve un codice pronunciabile (es. GIAS-7F3K, alfabeto
+// senza caratteri ambigui) e ogni risposta riporta il numero di turno;
+// l'associazione codice → conversazione è persistita e interrogabile dal
+// supporto.
+
+const (
+	// Alfabeto senza caratteri ambigui al telefono (niente 0/O, 1/I/L, B/8)
+	codeAlphabet    = "23456789ACDEFGHJKMNPQRSTUVWXYZ"
+	codeLength      = 4
+	codePrefix      = "GIAS-"
+	codeRegistryFil = "data/conversation_codes.json"
+)
+
+// codeEntry è una riga del registro codici persistito
+type codeEntry struct {
+	ConversationID string    `json:"conversation_id"`
+	Turns          int       `json:"turns"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type codeRegistryStore struct {
+	entries map[string]*codeEntry
+	mu      sync.Mutex
+	loaded  bool
+}
+
+var codeRegistry = &codeRegistryStore{}
+
+func (s *codeRegistryStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.entries = map[string]*codeEntry{}
+
+	data, err := os.ReadFile(codeRegistryFil)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("CONV_CODE_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		log.Printf("CONV_CODE_PARSE_ERROR: %v", err)
+	}
+}
+
+// persist scrive su file (da chiamare con il lock già acquisito)
+func (s *codeRegistryStore) persist() {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		log.Printf("CONV_CODE_PERSIST_ERROR: marshal fallito: %v", err)
+		return
+	}
+	if err := os.WriteFile(codeRegistryFil, data, 0644); err != nil {
+		log.Printf("CONV_CODE_PERSIST_ERROR: scrittura fallita: %v", err)
+	}
+}
+
+// ConversationCode deriva il codice pronunciabile dall'ID conversazione
+// (deterministico: stesso ID → stesso codice)
+func ConversationCode(convID string) string {
+	sum := sha256.Sum256([]byte(convID))
+	code := make([]byte, codeLength)
+	for i := 0; i < codeLength; i++ {
+		code[i] = codeAlphabet[int(sum[i])%len(codeAlphabet)]
+	}
+	return codePrefix + string(code)
+}
+
+// ConversationRecordTurn registra il turno nel registro codici e ritorna
+// codice e numero di turno da esporre nella risposta
+func ConversationRecordTurn(convID string) (string, int) {
+	code := ConversationCode(convID)
+
+	codeRegistry.mu.Lock()
+	defer codeRegistry.mu.Unlock()
+	codeRegistry.ensureLoaded()
+
+	entry, ok := codeRegistry.entries[code]
+	if !ok || entry.ConversationID != convID {
+		if ok {
+			// Collisione (improbabile con 810k combinazioni): vince l'ultima
+			log.Printf("CONV_CODE_COLLISION: code=%s, old=%s, new=%s", code, entry.ConversationID, convID)
+		}
+		entry = &codeEntry{ConversationID: convID}
+		codeRegistry.entries[code] = entry
+	}
+	entry.Turns++
+	entry.UpdatedAt = time.Now()
+	codeRegistry.persist()
+
+	return code, entry.Turns
+}
+
+// HandleConversationLookup risolve un codice pronunciabile per il supporto
+// GET /api/conversations/lookup?code=GIAS-7F3K
+func HandleConversationLookup(c *gin.Context) {
+	code := strings.ToUpper(strings.TrimSpace(c.Query("code")))
+	if code == "" {
+		AbortWithError(c, ErrInvalidParameter, "parametro code mancante")
+		return
+	}
+	if !strings.HasPrefix(code, codePrefix) {
+		code = codePrefix + code
+	}
+
+	codeRegistry.mu.Lock()
+	codeRegistry.ensureLoaded()
+	entry, ok := codeRegistry.entries[code]
+	codeRegistry.mu.Unlock()
+	if !ok {
+		AbortWithError(c, ErrNotFound, "nessuna conversazione con questo codice")
+		return
+	}
+
+	log.Printf("CONV_CODE_LOOKUP: code=%s, conversation=%s, client_ip=%s", code, entry.ConversationID, c.ClientIP())
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":          "success",
+		"code":            code,
+		"conversation_id": entry.ConversationID,
+		"turns":           entry.Turns,
+		"updated_at":      entry.UpdatedAt,
+	})
+}
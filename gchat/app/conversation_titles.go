@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Titoli delle conversazioni per la sidebar della cronologia: il titolo
+// viene generato automaticamente dal primo messaggio utente e può essere
+// rinominato via PUT /api/conversations/:id/title. La lista conversazioni
+// proxata dal backend viene arricchita con i titoli noti al gateway.
+
+const (
+	conversationTitlesFile = "data/conversation_titles.json"
+	titleMaxChars          = 60
+)
+
+type conversationTitleStore struct {
+	titles map[string]string // conversation/sender id -> titolo
+	mu     sync.Mutex
+	loaded bool
+}
+
+var conversationTitles = &conversationTitleStore{
+	titles: make(map[string]string),
+}
+
+// ensureLoaded carica i titoli persistiti al primo accesso (lazy loading)
+func (s *conversationTitleStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(conversationTitlesFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("TITLE_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.titles); err != nil {
+		log.Printf("TITLE_LOAD_ERROR: parse failed: %v", err)
+		s.titles = make(map[string]string)
+	}
+}
+
+// persist salva lo stato corrente su file (chiamare con lock acquisito)
+func (s *conversationTitleStore) persist() {
+	data, err := json.MarshalIndent(s.titles, "", "  ")
+	if err != nil {
+		log.Printf("TITLE_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.MkdirAll("data", 0755); err != nil {
+		log.Printf("TITLE_PERSIST_ERROR: cannot create data directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(conversationTitlesFile, data, 0644); err != nil {
+		log.Printf("TITLE_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// deriveTitle ricava un titolo leggibile dal primo messaggio (taglio a
+// parola intera entro titleMaxChars)
+func deriveTitle(message string) string {
+	title := strings.Join(strings.Fields(message), " ")
+	if len([]rune(title)) <= titleMaxChars {
+		return title
+	}
+	runes := []rune(title)[:titleMaxChars]
+	cut := strings.LastIndex(string(runes), " ")
+	if cut > titleMaxChars/2 {
+		runes = []rune(string(runes)[:cut])
+	}
+	return string(runes) + "…"
+}
+
+// AutoTitleConversation assegna un titolo automatico alla prima domanda
+// della conversazione (no-op se già presente)
+func AutoTitleConversation(convID, firstMessage string) {
+	if convID == "" || strings.TrimSpace(firstMessage) == "" {
+		return
+	}
+
+	conversationTitles.mu.Lock()
+	defer conversationTitles.mu.Unlock()
+	conversationTitles.ensureLoaded()
+
+	if _, ok := conversationTitles.titles[convID]; ok {
+		return
+	}
+	title := deriveTitle(firstMessage)
+	conversationTitles.titles[convID] = title
+	conversationTitles.persist()
+	log.Printf("TITLE_AUTO: conversation=%s, title=%q", convID, title)
+}
+
+// GetConversationTitle ritorna il titolo noto per la conversazione
+func GetConversationTitle(convID string) (string, bool) {
+	conversationTitles.mu.Lock()
+	defer conversationTitles.mu.Unlock()
+	conversationTitles.ensureLoaded()
+
+	title, ok := conversationTitles.titles[convID]
+	return title, ok
+}
+
+// HandleSetConversationTitle rinomina una conversazione
+// PUT /api/conversations/:id/title  body: {"title": "..."}
+func HandleSetConversationTitle(c *gin.Context) {
+	convID := c.Param("id")
+
+	var req struct {
+		Title string `json:"title"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	title := strings.Join(strings.Fields(req.Title), " ")
+	if title == "" || len([]rune(title)) > 200 {
+		AbortWithError(c, ErrInvalidParameter, "titolo vuoto o troppo lungo (max 200 caratteri)")
+		return
+	}
+
+	conversationTitles.mu.Lock()
+	conversationTitles.ensureLoaded()
+	conversationTitles.titles[convID] = title
+	conversationTitles.persist()
+	conversationTitles.mu.Unlock()
+
+	log.Printf("TITLE_SET: conversation=%s, title=%q, client_ip=%s", convID, title, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"status": "success", "id": convID, "title": title})
+}
+
+// enrichConversationList aggiunge il campo title alle conversazioni della
+// lista proxata dal backend (best effort: in caso di schema inatteso il
+// corpo passa invariato)
+func enrichConversationList(body []byte) []byte {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+
+	enriched := false
+	for _, key := range []string{"conversations", "results", "data"} {
+		list, ok := payload[key].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, item := range list {
+			conv, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, idKey := range []string{"session_id", "sid", "id", "sender"} {
+				if id, ok := conv[idKey].(string); ok && id != "" {
+					if title, found := GetConversationTitle(id); found {
+						conv["title"] = title
+						enriched = true
+					}
+					break
+				}
+			}
+		}
+	}
+
+	if !enriched {
+		return body
+	}
+	out, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// proxyConversationListWithTitles bufferizza la lista conversazioni del
+// backend (limite 4MB) e la arricchisce con i titoli prima di inoltrarla
+// al browser
+func proxyConversationListWithTitles(c *gin.Context, resp *http.Response, backendURL string) {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		log.Printf("CHATLOG_PROXY_ERROR: lettura lista conversazioni fallita - url=%s, error=%v", backendURL, err)
+		AbortWithError(c, ErrBackendError, err.Error())
+		return
+	}
+
+	enriched := enrichConversationList(body)
+	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), enriched)
+	log.Printf("CHATLOG_PROXY_OK: status=%d, bytes=%d (titoli arricchiti), url=%s",
+		resp.StatusCode, len(enriched), backendURL)
+}
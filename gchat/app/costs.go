@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Contabilità dei costi per turno dai token riportati dal backend: il
+// metering mensile per ASL non basta per capire chi consuma cosa giorno
+// per giorno. I token vengono aggregati per utente/ASL/giorno, esposti su
+// GET /admin/api/costs e confrontati con un budget giornaliero
+// configurabile che genera un alert al superamento.
+
+const (
+	costsFile = "data/cost_accounting.json"
+	dayFormat = "2006-01-02"
+)
+
+// CostsConfig configura tariffa e budget (sezione costs)
+type CostsConfig struct {
+	Enabled bool `json:"enabled"`
+	// CostPerMilleTokens: costo per 1000 token nella valuta indicata
+	CostPerMilleTokens float64 `json:"cost_per_mille_tokens"`
+	// DailyBudget: budget giornaliero oltre il quale scatta l'alert (0 = nessuno)
+	DailyBudget float64 `json:"daily_budget"`
+	Currency    string  `json:"currency"` // default "EUR"
+}
+
+// CostRecord è l'aggregato giornaliero per utente e ASL
+type CostRecord struct {
+	Day    string `json:"day"` // formato YYYY-MM-DD
+	ASL    string `json:"asl"`
+	UserID string `json:"user_id"`
+	Turns  int64  `json:"turns"`
+	Tokens int64  `json:"tokens"`
+}
+
+type costStore struct {
+	records    map[string]*CostRecord // chiave: day|asl|user
+	alertedDay string                 // giorno per cui l'alert è già partito
+	mu         sync.Mutex
+	loaded     bool
+}
+
+var costAccounting = &costStore{records: map[string]*CostRecord{}}
+
+func costKey(day, asl, userID string) string {
+	return day + "|" + asl + "|" + userID
+}
+
+func (s *costStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(costsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("COST_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	var records []*CostRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		log.Printf("COST_LOAD_ERROR: parse failed: %v", err)
+		return
+	}
+	for _, r := range records {
+		s.records[costKey(r.Day, r.ASL, r.UserID)] = r
+	}
+}
+
+// persist salva su file (chiamare con lock acquisito)
+func (s *costStore) persist() {
+	records := make([]*CostRecord, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		log.Printf("COST_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.WriteFile(costsFile, data, 0644); err != nil {
+		log.Printf("COST_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// costOf converte i token in costo secondo la tariffa configurata
+func costOf(config *Config, tokens int64) float64 {
+	return float64(tokens) / 1000.0 * config.Costs.CostPerMilleTokens
+}
+
+// CostRecordTurn registra un turno con i token riportati dal backend e
+// verifica il budget giornaliero
+func CostRecordTurn(asl, userID string, tokens int64) {
+	config := LoadConfig()
+	if !config.Costs.Enabled {
+		return
+	}
+	if asl == "" {
+		asl = meterNoASL
+	}
+	day := time.Now().Format(dayFormat)
+
+	costAccounting.mu.Lock()
+	defer costAccounting.mu.Unlock()
+	costAccounting.ensureLoaded()
+
+	key := costKey(day, asl, userID)
+	rec, ok := costAccounting.records[key]
+	if !ok {
+		rec = &CostRecord{Day: day, ASL: asl, UserID: userID}
+		costAccounting.records[key] = rec
+	}
+	rec.Turns++
+	rec.Tokens += tokens
+	costAccounting.persist()
+
+	// Alert al superamento del budget giornaliero (una volta al giorno)
+	if config.Costs.DailyBudget > 0 && costAccounting.alertedDay != day {
+		var dayTokens int64
+		for _, r := range costAccounting.records {
+			if r.Day == day {
+				dayTokens += r.Tokens
+			}
+		}
+		if cost := costOf(config, dayTokens); cost > config.Costs.DailyBudget {
+			costAccounting.alertedDay = day
+			currency := config.Costs.Currency
+			if currency == "" {
+				currency = "EUR"
+			}
+			log.Printf("COST_BUDGET_ALERT: day=%s, cost=%.2f %s, budget=%.2f %s",
+				day, cost, currency, config.Costs.DailyBudget, currency)
+		}
+	}
+}
+
+// HandleCosts espone gli aggregati di costo giornalieri
+// GET /admin/api/costs?day=YYYY-MM-DD (default: oggi)
+func HandleCosts(c *gin.Context) {
+	config := LoadConfig()
+	day := c.Query("day")
+	if day == "" {
+		day = time.Now().Format(dayFormat)
+	}
+	if _, err := time.Parse(dayFormat, day); err != nil {
+		AbortWithError(c, ErrInvalidParameter, "parametro day non valido, formato atteso YYYY-MM-DD")
+		return
+	}
+
+	costAccounting.mu.Lock()
+	costAccounting.ensureLoaded()
+	var records []*CostRecord
+	var dayTokens int64
+	for _, r := range costAccounting.records {
+		if r.Day == day {
+			copied := *r
+			records = append(records, &copied)
+			dayTokens += r.Tokens
+		}
+	}
+	costAccounting.mu.Unlock()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Tokens > records[j].Tokens })
+
+	currency := config.Costs.Currency
+	if currency == "" {
+		currency = "EUR"
+	}
+	totalCost := costOf(config, dayTokens)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "success",
+		"day":          day,
+		"records":      records,
+		"total_tokens": dayTokens,
+		"total_cost":   totalCost,
+		"currency":     currency,
+		"daily_budget": config.Costs.DailyBudget,
+		"over_budget":  config.Costs.DailyBudget > 0 && totalCost > config.Costs.DailyBudget,
+	})
+}
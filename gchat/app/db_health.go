@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Salute del database nella readiness: il gateway non ha un suo SQL store,
+// ma la cronologia e il chat-log dipendono dal PostgreSQL GIAS dietro il
+// backend. /readyz ora verifica anche lo stato del database (derivato da
+// data_loaded dello /status backend); quando il DB è giù il gateway entra
+// in modalità degradata read-only: la chat continua a funzionare, mentre
+// cronologia e ricerca vengono sospese con un 503 esplicativo.
+
+// degradedSince: 0 = non degradato, altrimenti unix del passaggio in degraded
+var degradedSince int64
+
+// DatabaseHealth deriva lo stato del database dal backend /status
+// (data_loaded vuoto o status non-ok = DB non raggiungibile)
+func DatabaseHealth() (gin.H, bool) {
+	status := GetBackendStatus()
+
+	dbUp := status != nil && status.Status == "ok" && len(status.DataLoaded) > 0
+	detail := gin.H{
+		"connected": dbUp,
+	}
+	if status != nil {
+		detail["backend_status"] = status.Status
+		detail["tables_loaded"] = len(status.DataLoaded)
+	}
+
+	// Aggiorna lo stato degradato con log di transizione
+	if dbUp {
+		if atomic.SwapInt64(&degradedSince, 0) != 0 {
+			log.Printf("DB_HEALTH_RECOVERED: database raggiungibile, modalità degradata disattivata")
+		}
+	} else {
+		if atomic.CompareAndSwapInt64(&degradedSince, 0, time.Now().Unix()) {
+			log.Printf("DB_HEALTH_DEGRADED: database non raggiungibile, cronologia e ricerca sospese")
+		}
+		detail["degraded_since"] = atomic.LoadInt64(&degradedSince)
+	}
+	return detail, dbUp
+}
+
+// DegradedMode ritorna true se il gateway è in modalità read-only
+// (ultimo check del database fallito)
+func DegradedMode() bool {
+	return atomic.LoadInt64(&degradedSince) != 0
+}
+
+// RequireDatabase blocca gli endpoint che dipendono dal database quando
+// il gateway è in modalità degradata. Da usare come guard nei handler di
+// cronologia e chat-log.
+func RequireDatabase(c *gin.Context) bool {
+	if !DegradedMode() {
+		return true
+	}
+	c.Header("Retry-After", "60")
+	AbortWithError(c, ErrBackendUnavailable, "cronologia temporaneamente non disponibile (database non raggiungibile), la chat resta attiva")
+	return false
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Modalità compare per il debug: gli sviluppatori NLU devono confrontare
+// sulla stessa frase il vecchio backend e quello nuovo LangGraph. Con
+// compare=true nel DebugChatRequest il gateway invia il messaggio in
+// parallelo al backend primario (llm_server) e a quello secondario
+// configurato in debug.compare_backend, e riporta risposte, intent e
+// tempi affiancati nel DebugChatResponse.
+
+// CompareBackendResult è l'esito di un singolo backend nel confronto A/B
+type CompareBackendResult struct {
+	BackendURL string  `json:"backend_url"`
+	Text       string  `json:"text,omitempty"`
+	Intent     string  `json:"intent,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// CompareReport affianca gli esiti dei due backend
+type CompareReport struct {
+	Primary   CompareBackendResult `json:"primary"`
+	Secondary CompareBackendResult `json:"secondary"`
+}
+
+// compareCall invia il messaggio a un backend misurando la durata
+func compareCall(message, sender, backendURL string, timeout int, context map[string]interface{}) (*NativeChatResponse, CompareBackendResult) {
+	start := time.Now()
+	resp, err := SendToLLMV1(message, sender, backendURL, timeout, context)
+	result := CompareBackendResult{
+		BackendURL: backendURL,
+		DurationMs: float64(time.Since(start).Milliseconds()),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return nil, result
+	}
+	result.Text = resp.Result.Text
+	result.Intent = resp.Result.Intent
+	return resp, result
+}
+
+// RunCompare invia lo stesso messaggio ai due backend in parallelo.
+// Ritorna la risposta del backend primario (nil se fallito) e il report
+// affiancato; il fallimento del secondario non blocca il flusso debug.
+func RunCompare(message, sender string, config *Config, context map[string]interface{}) (*NativeChatResponse, *CompareReport) {
+	secondaryTimeout := config.Debug.CompareBackend.Timeout
+	if secondaryTimeout <= 0 {
+		secondaryTimeout = config.LLMServer.Timeout
+	}
+
+	var (
+		wg          sync.WaitGroup
+		primaryResp *NativeChatResponse
+		report      CompareReport
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		primaryResp, report.Primary = compareCall(message, sender, config.LLMServer.URL, config.LLMServer.Timeout, context)
+	}()
+	go func() {
+		defer wg.Done()
+		_, report.Secondary = compareCall(message, sender, config.Debug.CompareBackend.URL, secondaryTimeout, context)
+	}()
+	wg.Wait()
+
+	log.Printf("DEBUG_COMPARE: primary=%s (%.0fms, intent=%s), secondary=%s (%.0fms, intent=%s)",
+		report.Primary.BackendURL, report.Primary.DurationMs, report.Primary.Intent,
+		report.Secondary.BackendURL, report.Secondary.DurationMs, report.Secondary.Intent)
+
+	return primaryResp, &report
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Tracker leggero dello stato conversazione per il debug: HandleDebugChat
+// usa un sender proprio che diverge dallo stato della conversazione reale.
+// Il gateway tiene per ogni sender l'ultimo messaggio, l'ultimo intent e
+// il conteggio turni; il debug può agganciarsi in sola lettura al tracker
+// del sender reale (attach_sender) e ricevere un warning quando l'intent
+// del parse e il latest_message del tracker non combaciano.
+
+const trackerTTL = 30 * time.Minute
+
+type conversationTracker struct {
+	LatestMessage string    `json:"latest_message"`
+	LatestIntent  string    `json:"latest_intent"`
+	LatestBotText string    `json:"latest_bot_text"`
+	Turns         int       `json:"turns"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+var (
+	trackers   = map[string]*conversationTracker{}
+	trackersMu sync.Mutex
+)
+
+// TrackerRecordTurn aggiorna il tracker del sender dopo un turno completato
+func TrackerRecordTurn(sender, message, intent, botText string) {
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+
+	now := time.Now()
+	for id, tracker := range trackers {
+		if now.Sub(tracker.UpdatedAt) > trackerTTL {
+			delete(trackers, id)
+		}
+	}
+
+	tracker, ok := trackers[sender]
+	if !ok {
+		tracker = &conversationTracker{}
+		trackers[sender] = tracker
+	}
+	tracker.LatestMessage = message
+	tracker.LatestIntent = intent
+	tracker.LatestBotText = botText
+	tracker.Turns++
+	tracker.UpdatedAt = now
+}
+
+// TrackerSnapshot ritorna una copia del tracker del sender (nil se assente)
+func TrackerSnapshot(sender string) *conversationTracker {
+	trackersMu.Lock()
+	defer trackersMu.Unlock()
+
+	tracker, ok := trackers[sender]
+	if !ok || time.Since(tracker.UpdatedAt) > trackerTTL {
+		return nil
+	}
+	snapshot := *tracker
+	return &snapshot
+}
+
+// TrackerConflicts confronta l'esito del parse con lo stato del tracker e
+// ritorna i warning per la vista debug
+func TrackerConflicts(tracker *conversationTracker, message, parseIntent string) []string {
+	if tracker == nil {
+		return []string{"nessuno stato conversazione registrato per il sender indicato"}
+	}
+	var warnings []string
+	if tracker.LatestMessage != message {
+		warnings = append(warnings, fmt.Sprintf(
+			"il messaggio ispezionato non coincide con latest_message del tracker (\"%s\")", tracker.LatestMessage))
+	}
+	if tracker.LatestIntent != "" && parseIntent != tracker.LatestIntent {
+		warnings = append(warnings, fmt.Sprintf(
+			"intent del parse (%s) diverso dall'ultimo intent del tracker (%s)", parseIntent, tracker.LatestIntent))
+	}
+	return warnings
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Collasso dei paragrafi duplicati consecutivi: per un difetto noto del
+// backend la stessa porzione di risposta arriva a volte ripetuta due
+// volte nello stesso turno. Un post-processing confronta i paragrafi
+// consecutivi (uguaglianza esatta e quasi-duplicati via testo
+// normalizzato) e tiene solo la prima occorrenza, con un contatore
+// esposto nei gauge di carico per quantificare il fenomeno.
+
+// Contatore dei paragrafi collassati dall'avvio
+var collapsedDuplicates int64
+
+// CollapseDuplicateParts rimuove i paragrafi duplicati consecutivi dal
+// testo di risposta e ritorna il numero di parti rimosse
+func CollapseDuplicateParts(text string) (string, int) {
+	parts := strings.Split(text, "\n\n")
+	if len(parts) < 2 {
+		return text, 0
+	}
+
+	kept := parts[:0]
+	removed := 0
+	prevNorm := ""
+	for _, part := range parts {
+		norm := normalizeFAQText(part)
+		// I separatori vuoti non contano come duplicati
+		if norm != "" && norm == prevNorm {
+			removed++
+			continue
+		}
+		kept = append(kept, part)
+		if norm != "" {
+			prevNorm = norm
+		}
+	}
+
+	if removed == 0 {
+		return text, 0
+	}
+	atomic.AddInt64(&collapsedDuplicates, int64(removed))
+	log.Printf("DEDUP_COLLAPSED: parts=%d, total=%d", removed, atomic.LoadInt64(&collapsedDuplicates))
+	return strings.Join(kept, "\n\n"), removed
+}
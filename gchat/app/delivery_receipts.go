@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ricevute di consegna delle risposte: per handoff operatore e broadcast
+// serve sapere cosa l'utente ha davvero ricevuto (un tab chiuso a metà
+// streaming perde l'evento final senza che nessuno se ne accorga). Ogni
+// risposta finale riceve un delivery_id; il client ne conferma la
+// ricezione su POST /api/receipts e alla riapertura dello stream le
+// risposte mai confermate vengono riproposte come eventi "redelivery".
+
+const (
+	deliveryFile         = "data/delivery_receipts.json"
+	deliveryMaxPerSender = 20
+	// deliveryRedeliverAfter: grazia prima di considerare una risposta non
+	// consegnata (l'ack del turno in corso può ancora arrivare)
+	deliveryRedeliverAfter = 30 * time.Second
+)
+
+// DeliveryRecord è lo stato di consegna di una risposta
+type DeliveryRecord struct {
+	ID            string     `json:"id"`
+	Sender        string     `json:"sender"`
+	Text          string     `json:"text"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Delivered     bool       `json:"delivered"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	RedeliveredAt *time.Time `json:"redelivered_at,omitempty"`
+}
+
+type deliveryStore struct {
+	records map[string]*DeliveryRecord // delivery_id -> record
+	loaded  bool
+	mu      sync.Mutex
+}
+
+var deliveries = &deliveryStore{}
+
+func (s *deliveryStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.records = map[string]*DeliveryRecord{}
+
+	data, err := os.ReadFile(deliveryFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("DELIVERY_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		log.Printf("DELIVERY_LOAD_ERROR: parse: %v", err)
+		s.records = map[string]*DeliveryRecord{}
+	}
+}
+
+func (s *deliveryStore) persist() {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		log.Printf("DELIVERY_PERSIST_ERROR: marshal: %v", err)
+		return
+	}
+	if err := os.WriteFile(deliveryFile, data, 0644); err != nil {
+		log.Printf("DELIVERY_PERSIST_ERROR: write: %v", err)
+	}
+}
+
+// pruneSender mantiene solo le voci più recenti del sender (chiamare con il lock)
+func (s *deliveryStore) pruneSender(sender string) {
+	var ids []string
+	for id, record := range s.records {
+		if record.Sender == sender {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) <= deliveryMaxPerSender {
+		return
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return s.records[ids[i]].CreatedAt.Before(s.records[ids[j]].CreatedAt)
+	})
+	for _, id := range ids[:len(ids)-deliveryMaxPerSender] {
+		delete(s.records, id)
+	}
+}
+
+// DeliveryRegister registra una risposta in attesa di conferma e ritorna
+// il delivery_id da esporre al client
+func DeliveryRegister(sender, text string) string {
+	if sender == "" || text == "" {
+		return ""
+	}
+	id := newContinuationHandle()
+	if id == "" {
+		return ""
+	}
+
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+	deliveries.ensureLoaded()
+	deliveries.records[id] = &DeliveryRecord{
+		ID:        id,
+		Sender:    sender,
+		Text:      text,
+		CreatedAt: time.Now(),
+	}
+	deliveries.pruneSender(sender)
+	deliveries.persist()
+	return id
+}
+
+// UndeliveredFor ritorna le risposte del sender mai confermate oltre il
+// periodo di grazia, marcandole come riproposte (una sola redelivery)
+func UndeliveredFor(sender string) []DeliveryRecord {
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+	deliveries.ensureLoaded()
+
+	var pending []DeliveryRecord
+	now := time.Now()
+	for _, record := range deliveries.records {
+		if record.Sender != sender || record.Delivered || record.RedeliveredAt != nil {
+			continue
+		}
+		if now.Sub(record.CreatedAt) < deliveryRedeliverAfter {
+			continue
+		}
+		record.RedeliveredAt = &now
+		pending = append(pending, *record)
+	}
+	if len(pending) > 0 {
+		deliveries.persist()
+		sort.Slice(pending, func(i, j int) bool {
+			return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+		})
+		log.Printf("DELIVERY_REDELIVER: sender=%s, count=%d", sender, len(pending))
+	}
+	return pending
+}
+
+// HandleDeliveryReceipt conferma la ricezione di una risposta
+// POST /api/receipts {"sender": "...", "delivery_id": "..."}
+func HandleDeliveryReceipt(c *gin.Context) {
+	var req struct {
+		Sender     string `json:"sender"`
+		DeliveryID string `json:"delivery_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	if req.DeliveryID == "" {
+		AbortWithError(c, ErrInvalidParameter, "delivery_id mancante")
+		return
+	}
+
+	deliveries.mu.Lock()
+	defer deliveries.mu.Unlock()
+	deliveries.ensureLoaded()
+
+	record, ok := deliveries.records[req.DeliveryID]
+	if !ok || (req.Sender != "" && record.Sender != req.Sender) {
+		AbortWithError(c, ErrNotFound, "delivery_id sconosciuto")
+		return
+	}
+	if !record.Delivered {
+		now := time.Now()
+		record.Delivered = true
+		record.DeliveredAt = &now
+		deliveries.persist()
+		log.Printf("DELIVERY_ACK: sender=%s, delivery_id=%s, latenza=%v",
+			record.Sender, record.ID, now.Sub(record.CreatedAt).Round(time.Millisecond))
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
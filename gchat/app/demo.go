@@ -0,0 +1,102 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Modalità demo pubblica: il marketing vuole una demo senza esporre i dati
+// reali del personale né il backend di produzione. Con demo.enabled attivo
+// tutte le richieste chat usano un'identità sintetica fissa, vengono
+// instradate sul backend sandbox configurato (demo.backend_url), sono
+// soggette a un rate limit aggressivo per IP e le risposte vengono
+// contrassegnate con un watermark.
+
+const (
+	demoDefaultRateLimit = 5 // richieste per minuto per IP
+	demoDefaultWatermark = "⚠️ Risposta generata in modalità demo su dati sintetici, non utilizzabile per finalità operative."
+)
+
+// Identità sintetica usata al posto dei parametri reali
+const (
+	demoASL           = "DEMO"
+	demoUserID        = "0"
+	demoCodiceFiscale = "DMODMO00A01H501X"
+	demoUsername      = "utente.demo"
+)
+
+// DemoConfig configura la modalità demo in config.json (sezione demo)
+type DemoConfig struct {
+	Enabled bool `json:"enabled"`
+	// BackendURL: backend sandbox/mock (vuoto = backend configurato)
+	BackendURL string `json:"backend_url"`
+	// RateLimitPerMin: richieste chat per minuto per IP (default 5)
+	RateLimitPerMin int `json:"rate_limit_per_min"`
+	// Watermark: testo accodato alle risposte (vuoto = default)
+	Watermark string `json:"watermark"`
+}
+
+var (
+	demoRateMu      sync.Mutex
+	demoRateWindows = map[string][]time.Time{}
+)
+
+// DemoModeActive indica se la modalità demo è attiva
+func DemoModeActive(config *Config) bool {
+	return config.Demo.Enabled
+}
+
+// DemoAllow applica il rate limit per IP (finestra mobile di un minuto)
+func DemoAllow(clientIP string) bool {
+	limit := LoadConfig().Demo.RateLimitPerMin
+	if limit <= 0 {
+		limit = demoDefaultRateLimit
+	}
+
+	demoRateMu.Lock()
+	defer demoRateMu.Unlock()
+
+	now := time.Now()
+	window := demoRateWindows[clientIP][:0]
+	for _, t := range demoRateWindows[clientIP] {
+		if now.Sub(t) < time.Minute {
+			window = append(window, t)
+		}
+	}
+	if len(window) >= limit {
+		demoRateWindows[clientIP] = window
+		log.Printf("DEMO_RATE_LIMITED: client_ip=%s, limit=%d/min", clientIP, limit)
+		return false
+	}
+	demoRateWindows[clientIP] = append(window, now)
+	return true
+}
+
+// ApplyDemoMode sovrascrive l'identità della richiesta con quella
+// sintetica e instrada la config del handler sul backend sandbox
+func ApplyDemoMode(config *Config, req *ChatRequest) {
+	req.ASL = demoASL
+	req.ASLID = ""
+	req.UserID = demoUserID
+	req.CodiceFiscale = demoCodiceFiscale
+	req.Username = demoUsername
+	req.UOC = ""
+
+	if config.Demo.BackendURL != "" {
+		config.LLMServer.URL = config.Demo.BackendURL
+	}
+	log.Printf("DEMO_REQUEST: identità sintetica applicata, backend=%s", config.LLMServer.URL)
+}
+
+// DemoWatermark accoda il watermark demo al testo della risposta
+func DemoWatermark(config *Config, text string) string {
+	watermark := config.Demo.Watermark
+	if watermark == "" {
+		watermark = demoDefaultWatermark
+	}
+	if text == "" {
+		return watermark
+	}
+	return text + "\n\n" + watermark
+}
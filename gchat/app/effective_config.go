@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Config effettiva senza SSH: per capire con quale configurazione gira
+// davvero un'istanza (profilo GIAS_PROFILE applicato, default, sezioni
+// mancanti) serve un endpoint. GET /admin/api/config/effective ritorna la
+// config risolta con i segreti mascherati e il diff rispetto al file su
+// disco così come scritto, per evidenziare la deriva introdotta dal
+// profilo attivo.
+
+// Chiavi JSON i cui valori non devono mai uscire dall'endpoint
+var configSecretKeys = map[string]bool{
+	"secret":   true,
+	"salt":     true,
+	"password": true,
+	"api_key":  true,
+}
+
+// maskConfigSecrets maschera ricorsivamente i valori delle chiavi sensibili
+func maskConfigSecrets(node interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if configSecretKeys[strings.ToLower(key)] {
+				if s, ok := child.(string); ok && s != "" {
+					v[key] = "***"
+				}
+				continue
+			}
+			v[key] = maskConfigSecrets(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = maskConfigSecrets(child)
+		}
+		return v
+	default:
+		return node
+	}
+}
+
+// flattenConfig appiattisce la mappa in percorsi puntati per il diff
+func flattenConfig(prefix string, node interface{}, out map[string]string) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flattenConfig(path, child, out)
+		}
+	default:
+		data, err := json.Marshal(node)
+		if err != nil {
+			return
+		}
+		out[prefix] = string(data)
+	}
+}
+
+// configAsMaskedMap serializza una Config in mappa con segreti mascherati
+func configAsMaskedMap(config *Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	maskConfigSecrets(m)
+	return m, nil
+}
+
+// HandleEffectiveConfig espone la config risolta e la deriva dal file
+// GET /admin/api/config/effective
+func HandleEffectiveConfig(c *gin.Context) {
+	effective, err := configAsMaskedMap(LoadConfig())
+	if err != nil {
+		AbortWithError(c, ErrInternal, err.Error())
+		return
+	}
+
+	// Config su disco così come scritta (senza profilo né default)
+	var onDisk map[string]interface{}
+	diskAvailable := false
+	if data, err := os.ReadFile("config/config.json"); err == nil {
+		var raw Config
+		if err := json.Unmarshal(data, &raw); err == nil {
+			if onDisk, err = configAsMaskedMap(&raw); err == nil {
+				diskAvailable = true
+			}
+		} else {
+			log.Printf("EFFECTIVE_CONFIG_ERROR: parse config su disco fallito: %v", err)
+		}
+	}
+
+	// Diff per percorso puntato: valori effettivi diversi dal file su disco
+	var drift []gin.H
+	if diskAvailable {
+		effectiveFlat := map[string]string{}
+		diskFlat := map[string]string{}
+		flattenConfig("", effective, effectiveFlat)
+		flattenConfig("", onDisk, diskFlat)
+
+		var paths []string
+		for path := range effectiveFlat {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			if effectiveFlat[path] != diskFlat[path] {
+				drift = append(drift, gin.H{
+					"path":      path,
+					"effective": json.RawMessage(effectiveFlat[path]),
+					"on_disk":   json.RawMessage(diskValueOrNull(diskFlat, path)),
+				})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "success",
+		"active_profile": ActiveProfile(),
+		"config":         effective,
+		"disk_available": diskAvailable,
+		"drift":          drift,
+		"drift_count":    len(drift),
+	})
+}
+
+// diskValueOrNull ritorna il valore su disco o "null" se il percorso manca
+func diskValueOrNull(diskFlat map[string]string, path string) string {
+	if v, ok := diskFlat[path]; ok {
+		return v
+	}
+	return "null"
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Renderer condiviso di trascrizioni conversazione in HTML email-safe:
+// l'export via email e le escalation al supporto devono allegare la
+// conversazione, ma i client di posta (Outlook, Gmail, webmail aziendali)
+// ignorano i fogli di stile e bloccano gli script. Il renderer produce
+// solo tabelle con stili inline, niente <style> né JavaScript, converte
+// il markdown minimo usato dal backend (grassetto, elenchi, a capo) e
+// include il manifest degli allegati.
+
+// TranscriptTurn è un singolo turno della conversazione da rendere
+type TranscriptTurn struct {
+	Role      string    // "user" | "bot"
+	Text      string    // testo raw (può contenere markdown del backend)
+	Timestamp time.Time // zero value = omesso
+}
+
+// TranscriptAttachment descrive un allegato nel manifest in coda all'email
+type TranscriptAttachment struct {
+	Name      string
+	MimeType  string
+	SizeBytes int64
+}
+
+// Palette inline coerente con la UI (oro per l'utente, bianco per il bot)
+const (
+	transcriptUserStyle = "background-color:#fef3c7;border:1px solid #fcd34d;border-radius:8px;padding:8px 12px;font-family:Arial,sans-serif;font-size:14px;color:#1f2937;"
+	transcriptBotStyle  = "background-color:#ffffff;border:1px solid #e5e7eb;border-radius:8px;padding:8px 12px;font-family:Arial,sans-serif;font-size:14px;color:#1f2937;"
+	transcriptMetaStyle = "font-family:Arial,sans-serif;font-size:11px;color:#6b7280;padding:2px 12px;"
+)
+
+var (
+	transcriptBoldRe = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	transcriptListRe = regexp.MustCompile(`^(\d+\.|[-*])\s+`)
+)
+
+// markdownToEmailHTML converte il markdown minimo del backend in HTML
+// inline-safe: il testo viene prima escapato, poi si applicano grassetto,
+// elenchi puntati/numerati e a capo
+func markdownToEmailHTML(text string) string {
+	escaped := html.EscapeString(text)
+	escaped = transcriptBoldRe.ReplaceAllString(escaped, "<strong>$1</strong>")
+
+	var out []string
+	for _, line := range strings.Split(escaped, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if transcriptListRe.MatchString(trimmed) {
+			// Elenchi resi come righe rientrate: gli <ul> annidati sono
+			// resi in modo incoerente dai client di posta
+			out = append(out, "&nbsp;&nbsp;&bull; "+transcriptListRe.ReplaceAllString(trimmed, ""))
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "<br>")
+}
+
+// RenderEmailTranscript produce l'HTML email-safe della conversazione:
+// una tabella a larghezza fissa con un blocco per turno e, se presente,
+// il manifest degli allegati in coda
+func RenderEmailTranscript(title string, turns []TranscriptTurn, attachments []TranscriptAttachment) string {
+	var b strings.Builder
+
+	b.WriteString(`<table width="600" cellpadding="0" cellspacing="0" border="0" style="margin:0 auto;">`)
+	b.WriteString(fmt.Sprintf(
+		`<tr><td style="font-family:Arial,sans-serif;font-size:16px;font-weight:bold;color:#92400e;padding:12px;">%s</td></tr>`,
+		html.EscapeString(title)))
+
+	for _, turn := range turns {
+		style := transcriptBotStyle
+		label := "Assistente"
+		if turn.Role == "user" {
+			style = transcriptUserStyle
+			label = "Utente"
+		}
+		meta := label
+		if !turn.Timestamp.IsZero() {
+			meta = fmt.Sprintf("%s &middot; %s", label, turn.Timestamp.Format("02/01/2006 15:04"))
+		}
+		b.WriteString(fmt.Sprintf(`<tr><td style="%s">%s</td></tr>`, transcriptMetaStyle, meta))
+		b.WriteString(fmt.Sprintf(`<tr><td style="%s">%s</td></tr>`, style, markdownToEmailHTML(turn.Text)))
+		b.WriteString(`<tr><td style="height:8px;line-height:8px;font-size:8px;">&nbsp;</td></tr>`)
+	}
+
+	if len(attachments) > 0 {
+		b.WriteString(fmt.Sprintf(
+			`<tr><td style="%s"><strong>Allegati</strong></td></tr>`, transcriptMetaStyle))
+		for _, att := range attachments {
+			b.WriteString(fmt.Sprintf(
+				`<tr><td style="%s">%s (%s, %d byte)</td></tr>`,
+				transcriptMetaStyle, html.EscapeString(att.Name), html.EscapeString(att.MimeType), att.SizeBytes))
+		}
+	}
+
+	b.WriteString(`</table>`)
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderEmailTranscriptEscapesUserContent(t *testing.T) {
+	// Il contenuto utente non deve mai finire nell'HTML senza escape
+	turns := []TranscriptTurn{
+		{Role: "user", Text: `<script>alert("xss")</script>`},
+	}
+	out := RenderEmailTranscript("Conversazione", turns, nil)
+
+	if strings.Contains(out, "<script>") {
+		t.Error("il contenuto utente deve essere escapato, trovato <script>")
+	}
+	if !strings.Contains(out, "&lt;script&gt;") {
+		t.Error("atteso contenuto escapato &lt;script&gt;")
+	}
+}
+
+func TestRenderEmailTranscriptIsEmailClientSafe(t *testing.T) {
+	// Vincoli dei client di posta: niente <style>, niente JS, solo stili inline
+	turns := []TranscriptTurn{
+		{Role: "user", Text: "domanda"},
+		{Role: "bot", Text: "risposta"},
+	}
+	out := RenderEmailTranscript("Conversazione", turns, nil)
+
+	for _, forbidden := range []string{"<style", "<script", "onclick", "javascript:"} {
+		if strings.Contains(strings.ToLower(out), forbidden) {
+			t.Errorf("l'HTML email-safe non deve contenere %q", forbidden)
+		}
+	}
+	if !strings.Contains(out, `style="`) {
+		t.Error("attesi stili inline negli elementi")
+	}
+	if !strings.Contains(out, "<table") {
+		t.Error("atteso layout a tabella per compatibilità email")
+	}
+}
+
+func TestMarkdownToEmailHTMLConversion(t *testing.T) {
+	out := markdownToEmailHTML("**Piano A22**\n- voce uno\n1. voce due\nriga normale")
+
+	if !strings.Contains(out, "<strong>Piano A22</strong>") {
+		t.Errorf("grassetto non convertito: %s", out)
+	}
+	if !strings.Contains(out, "&bull; voce uno") {
+		t.Errorf("elenco puntato non convertito: %s", out)
+	}
+	if !strings.Contains(out, "&bull; voce due") {
+		t.Errorf("elenco numerato non convertito: %s", out)
+	}
+	if !strings.Contains(out, "<br>") {
+		t.Errorf("a capo non convertiti: %s", out)
+	}
+}
+
+func TestRenderEmailTranscriptAttachmentManifest(t *testing.T) {
+	turns := []TranscriptTurn{{Role: "bot", Text: "report pronto"}}
+	attachments := []TranscriptAttachment{
+		{Name: "report.pdf", MimeType: "application/pdf", SizeBytes: 2048},
+	}
+	out := RenderEmailTranscript("Conversazione", turns, attachments)
+
+	if !strings.Contains(out, "Allegati") {
+		t.Error("manifest allegati mancante")
+	}
+	if !strings.Contains(out, "report.pdf") || !strings.Contains(out, "application/pdf") {
+		t.Errorf("dettagli allegato mancanti: %s", out)
+	}
+}
+
+func TestRenderEmailTranscriptRolesAndTimestamps(t *testing.T) {
+	ts := time.Date(2025, 3, 14, 10, 30, 0, 0, time.UTC)
+	turns := []TranscriptTurn{
+		{Role: "user", Text: "domanda", Timestamp: ts},
+		{Role: "bot", Text: "risposta"},
+	}
+	out := RenderEmailTranscript("Conversazione", turns, nil)
+
+	if !strings.Contains(out, "Utente") || !strings.Contains(out, "Assistente") {
+		t.Error("etichette dei ruoli mancanti")
+	}
+	if !strings.Contains(out, "14/03/2025 10:30") {
+		t.Errorf("timestamp non formattato: %s", out)
+	}
+}
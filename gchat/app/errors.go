@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Envelope di errore unificato per tutte le API del gateway.
+// I client possono fare branching sul campo "code" invece di interpretare
+// i messaggi in italiano. Il request_id permette la correlazione con i log.
+
+type ErrorEnvelope struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"request_id"`
+}
+
+// Catalogo interno dei codici di errore: codice → status HTTP + messaggio utente
+type errorDef struct {
+	HTTPStatus int
+	Message    string
+}
+
+const (
+	ErrInvalidRequest       = "invalid_request"
+	ErrInvalidParameter     = "invalid_parameter"
+	ErrBackendUnavailable   = "backend_unavailable"
+	ErrBackendError         = "backend_error"
+	ErrTranscription        = "transcription_error"
+	ErrUnauthorized         = "unauthorized"
+	ErrForbidden            = "forbidden"
+	ErrNotFound             = "not_found"
+	ErrStreamingUnsupported = "streaming_unsupported"
+	ErrRateLimited          = "rate_limited"
+	ErrInternal             = "internal_error"
+)
+
+var errorCatalog = map[string]errorDef{
+	ErrInvalidRequest:       {http.StatusBadRequest, "Formato della richiesta non valido"},
+	ErrInvalidParameter:     {http.StatusBadRequest, "Parametro della richiesta non valido"},
+	ErrBackendUnavailable:   {http.StatusServiceUnavailable, "Il server LLM non è disponibile al momento"},
+	ErrBackendError:         {http.StatusBadGateway, "Errore di comunicazione con il server LLM"},
+	ErrTranscription:        {http.StatusInternalServerError, "Errore durante la trascrizione audio"},
+	ErrUnauthorized:         {http.StatusUnauthorized, "Autenticazione richiesta"},
+	ErrForbidden:            {http.StatusForbidden, "Operazione non consentita per questo utente"},
+	ErrNotFound:             {http.StatusNotFound, "Risorsa non trovata"},
+	ErrStreamingUnsupported: {http.StatusInternalServerError, "Streaming non supportato dal client"},
+	ErrRateLimited:          {http.StatusTooManyRequests, "Troppe richieste, riprovare più tardi"},
+	ErrInternal:             {http.StatusInternalServerError, "Errore interno del server"},
+}
+
+const requestIDKey = "request_id"
+
+// generateRequestID genera un identificatore univoco per la richiesta
+func generateRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestIDMiddleware assegna un request_id a ogni richiesta (o riusa quello
+// fornito dal client via X-Request-ID) e lo espone nell'header di risposta.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" {
+			requestID = generateRequestID()
+		}
+		c.Set(requestIDKey, requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Next()
+	}
+}
+
+// GetRequestID recupera il request_id assegnato dal middleware
+func GetRequestID(c *gin.Context) string {
+	if v, ok := c.Get(requestIDKey); ok {
+		if id, ok := v.(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// AbortWithError risponde con l'envelope di errore unificato usando il catalogo.
+// details è opzionale e contiene informazioni aggiuntive (es. errore originale).
+func AbortWithError(c *gin.Context, code string, details string) {
+	def, ok := errorCatalog[code]
+	if !ok {
+		log.Printf("ERROR_CATALOG_MISS: codice non catalogato %q, uso internal_error", code)
+		code = ErrInternal
+		def = errorCatalog[ErrInternal]
+	}
+
+	envelope := ErrorEnvelope{
+		Code:      code,
+		Message:   def.Message,
+		Details:   details,
+		RequestID: GetRequestID(c),
+	}
+
+	log.Printf("API_ERROR: code=%s, status=%d, request_id=%s, details=%s, client_ip=%s",
+		code, def.HTTPStatus, envelope.RequestID, details, c.ClientIP())
+
+	c.AbortWithStatusJSON(def.HTTPStatus, gin.H{"error": envelope})
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Escalation delle domande senza risposta: quando il backend risponde in
+// fallback N volte nella stessa conversazione la UI propone "invia la tua
+// domanda al supporto". POST /api/escalations impacchetta domanda e
+// contesto, la recapita alla casella di supporto via SMTP o apre un
+// ticket via webhook, e restituisce un numero di riferimento. Le
+// escalation sono persistite su file per l'audit.
+
+const (
+	escalationFile             = "data/escalations.json"
+	escalationDefaultThreshold = 3 // fallback consecutivi prima dell'offerta
+)
+
+// EscalationConfig configura il canale di recapito (sezione escalation).
+// Le credenziali SMTP arrivano dalle env SMTP_USER / SMTP_PASSWORD.
+type EscalationConfig struct {
+	Enabled           bool   `json:"enabled"`
+	FallbackThreshold int    `json:"fallback_threshold"` // default 3
+	WebhookURL        string `json:"webhook_url"`        // ticket via webhook (prioritario)
+	SMTPHost          string `json:"smtp_host"`
+	SMTPPort          int    `json:"smtp_port"`
+	EmailFrom         string `json:"email_from"`
+	EmailTo           string `json:"email_to"` // casella del supporto
+}
+
+// EscalationRecord è una escalation registrata
+type EscalationRecord struct {
+	Reference     string    `json:"reference"`
+	Sender        string    `json:"sender"`
+	Message       string    `json:"message"`
+	ASL           string    `json:"asl,omitempty"`
+	UserID        string    `json:"user_id,omitempty"`
+	CodiceFiscale string    `json:"codice_fiscale,omitempty"`
+	Contact       string    `json:"contact,omitempty"` // recapito per la risposta
+	Channel       string    `json:"channel"`           // email | webhook
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type escalationStore struct {
+	records []EscalationRecord
+	mu      sync.Mutex
+	loaded  bool
+}
+
+var escalations = &escalationStore{}
+
+// Contatore dei fallback consecutivi per conversazione
+var (
+	fallbackCounts   = map[string]int{}
+	fallbackCountsMu sync.Mutex
+)
+
+func (s *escalationStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(escalationFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("ESCALATION_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		log.Printf("ESCALATION_PARSE_ERROR: %v", err)
+	}
+}
+
+// persist scrive su file (da chiamare con il lock già acquisito)
+func (s *escalationStore) persist() {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		log.Printf("ESCALATION_PERSIST_ERROR: marshal fallito: %v", err)
+		return
+	}
+	if err := os.WriteFile(escalationFile, data, 0644); err != nil {
+		log.Printf("ESCALATION_PERSIST_ERROR: scrittura fallita: %v", err)
+	}
+}
+
+// EscalationObserveIntent aggiorna il contatore dei fallback consecutivi
+// della conversazione e ritorna true quando è il momento di offrire
+// l'escalation (soglia raggiunta)
+func EscalationObserveIntent(sender, intent string) bool {
+	config := LoadConfig()
+	if !config.Escalation.Enabled {
+		return false
+	}
+	threshold := config.Escalation.FallbackThreshold
+	if threshold <= 0 {
+		threshold = escalationDefaultThreshold
+	}
+
+	fallbackCountsMu.Lock()
+	defer fallbackCountsMu.Unlock()
+
+	if !strings.Contains(intent, "fallback") {
+		delete(fallbackCounts, sender)
+		return false
+	}
+	fallbackCounts[sender]++
+	if fallbackCounts[sender] >= threshold {
+		log.Printf("ESCALATION_OFFER: sender=%s, fallback_consecutivi=%d", sender, fallbackCounts[sender])
+		return true
+	}
+	return false
+}
+
+// escalationDeliverWebhook apre un ticket via webhook configurato
+func escalationDeliverWebhook(config *Config, record *EscalationRecord) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	client := NewUpstreamClient("escalation", 10*time.Second)
+	resp, err := client.Post(config.Escalation.WebhookURL, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// escalationDeliverEmail invia la segnalazione alla casella di supporto
+func escalationDeliverEmail(config *Config, record *EscalationRecord) error {
+	if config.Escalation.SMTPHost == "" || config.Escalation.EmailTo == "" {
+		return fmt.Errorf("smtp non configurato (escalation.smtp_host / escalation.email_to)")
+	}
+	port := config.Escalation.SMTPPort
+	if port == 0 {
+		port = 25
+	}
+	from := config.Escalation.EmailFrom
+	if from == "" {
+		from = "gchat@localhost"
+	}
+
+	// Trascrizione HTML email-safe dell'ultimo scambio, se il tracker della
+	// conversazione è ancora attivo (vedi email_transcript.go)
+	var turns []TranscriptTurn
+	if tracker := TrackerSnapshot(record.Sender); tracker != nil && tracker.LatestMessage != "" {
+		turns = append(turns,
+			TranscriptTurn{Role: "user", Text: tracker.LatestMessage},
+			TranscriptTurn{Role: "bot", Text: tracker.LatestBotText})
+	}
+	turns = append(turns, TranscriptTurn{Role: "user", Text: record.Message, Timestamp: record.CreatedAt})
+
+	details := fmt.Sprintf("Riferimento: %s<br>Conversazione: %s<br>ASL: %s<br>Utente: %s<br>Recapito: %s",
+		html.EscapeString(record.Reference), html.EscapeString(record.Sender), html.EscapeString(record.ASL),
+		html.EscapeString(record.UserID), html.EscapeString(record.Contact))
+	body := fmt.Sprintf(`<p style="font-family:Arial,sans-serif;font-size:13px;color:#1f2937;">%s</p>%s`,
+		details, RenderEmailTranscript("Escalation "+record.Reference, turns, nil))
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [GISA-AI] Escalation %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n%s",
+		from, config.Escalation.EmailTo, record.Reference, body)
+
+	addr := fmt.Sprintf("%s:%d", config.Escalation.SMTPHost, port)
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USER"); user != "" {
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), config.Escalation.SMTPHost)
+	}
+	return smtp.SendMail(addr, auth, from, []string{config.Escalation.EmailTo}, []byte(msg))
+}
+
+// HandleEscalation registra e recapita una escalation al supporto
+// POST /api/escalations
+func HandleEscalation(c *gin.Context) {
+	config := LoadConfig()
+	if !config.Escalation.Enabled {
+		AbortWithError(c, ErrForbidden, "escalation disabilitata (escalation.enabled)")
+		return
+	}
+
+	var req struct {
+		Sender        string `json:"sender"`
+		Message       string `json:"message"`
+		ASL           string `json:"asl,omitempty"`
+		UserID        string `json:"user_id,omitempty"`
+		CodiceFiscale string `json:"codice_fiscale,omitempty"`
+		Contact       string `json:"contact,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		AbortWithError(c, ErrInvalidParameter, "campo message mancante")
+		return
+	}
+
+	// Riferimento pratica: handle casuale; se la generazione fallisce
+	// (crypto/rand) ripiega sul timestamp, l'escalation non deve perdersi
+	reference := newContinuationHandle()
+	if len(reference) >= 8 {
+		reference = "ESC-" + strings.ToUpper(reference[:8])
+	} else {
+		reference = "ESC-" + time.Now().UTC().Format("20060102150405")
+	}
+
+	record := EscalationRecord{
+		Reference:     reference,
+		Sender:        req.Sender,
+		Message:       req.Message,
+		ASL:           req.ASL,
+		UserID:        req.UserID,
+		CodiceFiscale: req.CodiceFiscale,
+		Contact:       req.Contact,
+		CreatedAt:     time.Now(),
+	}
+
+	// Recapito: webhook se configurato, altrimenti email
+	var err error
+	if config.Escalation.WebhookURL != "" {
+		record.Channel = "webhook"
+		err = escalationDeliverWebhook(config, &record)
+	} else {
+		record.Channel = "email"
+		err = escalationDeliverEmail(config, &record)
+	}
+	if err != nil {
+		log.Printf("ESCALATION_ERROR: recapito fallito - reference=%s, channel=%s, error=%v",
+			record.Reference, record.Channel, err)
+		AbortWithError(c, ErrBackendError, "recapito dell'escalation fallito, riprovare più tardi")
+		return
+	}
+
+	escalations.mu.Lock()
+	escalations.ensureLoaded()
+	escalations.records = append(escalations.records, record)
+	escalations.persist()
+	escalations.mu.Unlock()
+
+	// Escalation inviata: azzera il contatore dei fallback
+	fallbackCountsMu.Lock()
+	delete(fallbackCounts, req.Sender)
+	fallbackCountsMu.Unlock()
+
+	log.Printf("ESCALATION_SENT: reference=%s, channel=%s, sender=%s, asl=%s",
+		record.Reference, record.Channel, record.Sender, record.ASL)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"reference": record.Reference,
+	})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+)
+
+// Tassonomia degli eventi SSE: le release del backend ogni tanto
+// introducono tipi di evento che il frontend non conosce, e l'inoltro
+// grezzo rompe il rendering della chat (handler JS mancante, evento
+// ignorato a metà). Qui è censita la tassonomia versionata dei tipi noti:
+// gli eventi sconosciuti non vengono inoltrati così come sono ma messi in
+// quarantena dentro una busta generica "unknown" (con il tipo originale
+// nel metadata), contando le occorrenze per tipo così da accorgersi di
+// una release backend divergente prima degli utenti.
+
+// sseTaxonomyVersion identifica la versione della tassonomia: va
+// incrementata ogni volta che si aggiunge un tipo a knownSSEEventTypes
+const sseTaxonomyVersion = "1"
+
+// knownSSEEventTypes sono i tipi di evento che il frontend sa renderizzare
+// (emessi dal backend o generati dal gateway stesso)
+var knownSSEEventTypes = map[string]bool{
+	"token":            true, // chunk incrementale di testo
+	"final":            true, // risposta completa con result
+	"error":            true, // errore del turno
+	"busy":             true, // backend saturo con retry_after
+	"ping":             true, // keep-alive
+	"log":              true, // riga di log (admin logs/stream)
+	"notification":     true, // notifica accodata offline
+	"redelivery":       true, // risposta riproposta non confermata
+	"session_expiring": true, // avviso scadenza sessione
+	"slow_mode":        true, // avviso slow-mode progressivo
+	"consent_banner":   true, // richiesta consenso
+}
+
+type unknownEventStats struct {
+	counts map[string]int
+	mu     sync.Mutex
+}
+
+var unknownEvents = &unknownEventStats{counts: map[string]int{}}
+
+// QuarantineSSEEvent verifica il tipo contro la tassonomia. Per i tipi
+// noti ritorna l'evento invariato; per quelli sconosciuti ritorna la
+// busta "unknown" con tipo originale e versione tassonomia nel metadata,
+// incrementando la metrica di warning.
+func QuarantineSSEEvent(event SSEEvent) SSEEvent {
+	if event.Type == "" || knownSSEEventTypes[event.Type] {
+		return event
+	}
+
+	unknownEvents.mu.Lock()
+	unknownEvents.counts[event.Type]++
+	count := unknownEvents.counts[event.Type]
+	unknownEvents.mu.Unlock()
+
+	// Log solo alle prime occorrenze per non inondare i log su uno
+	// stream che emette il tipo ignoto a ogni token
+	if count <= 3 {
+		log.Printf("SSE_TAXONOMY_UNKNOWN: tipo evento sconosciuto in quarantena - type=%s, occorrenze=%d", event.Type, count)
+	}
+
+	quarantined := event
+	quarantined.Type = "unknown"
+	meta := map[string]interface{}{
+		"original_type":    event.Type,
+		"taxonomy_version": sseTaxonomyVersion,
+	}
+	for k, v := range event.Metadata {
+		meta[k] = v
+	}
+	quarantined.Metadata = meta
+	return quarantined
+}
+
+// UnknownEventCounts ritorna le occorrenze per tipo sconosciuto (per la
+// pagina alert e il debug), in ordine alfabetico di tipo
+func UnknownEventCounts() []map[string]interface{} {
+	unknownEvents.mu.Lock()
+	defer unknownEvents.mu.Unlock()
+
+	var types []string
+	for t := range unknownEvents.counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	var result []map[string]interface{}
+	for _, t := range types {
+		result = append(result, map[string]interface{}{"type": t, "count": unknownEvents.counts[t]})
+	}
+	return result
+}
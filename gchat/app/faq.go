@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FAQ curate nel gateway: le 20 domande più ripetitive non hanno bisogno
+// dell'LLM. Un archivio amministrabile (pattern di domanda -> risposta
+// canonica in markdown) viene consultato prima di chiamare il backend;
+// i match esatti o ad alta confidenza rispondono localmente e la risposta
+// viene marcata come curata.
+
+const (
+	faqFile = "data/faqs.json"
+	// Soglia Jaccard sui token per considerare un match "ad alta confidenza"
+	faqMatchThreshold = 0.9
+)
+
+// FAQEntry è una domanda curata con i suoi pattern di attivazione
+type FAQEntry struct {
+	ID        string    `json:"id"`
+	Patterns  []string  `json:"patterns"` // varianti della domanda
+	Answer    string    `json:"answer"`   // risposta in markdown
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type faqStore struct {
+	entries map[string]*FAQEntry
+	mu      sync.Mutex
+	loaded  bool
+}
+
+var faqs = &faqStore{
+	entries: make(map[string]*FAQEntry),
+}
+
+// ensureLoaded carica le FAQ persistite al primo accesso (lazy loading)
+func (s *faqStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(faqFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("FAQ_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+
+	var entries []*FAQEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("FAQ_LOAD_ERROR: parse failed: %v", err)
+		return
+	}
+	for _, e := range entries {
+		s.entries[e.ID] = e
+	}
+	log.Printf("FAQ_LOADED: %d FAQ caricate da %s", len(entries), faqFile)
+}
+
+// persist salva lo stato corrente su file (chiamare con lock acquisito)
+func (s *faqStore) persist() {
+	entries := make([]*FAQEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		log.Printf("FAQ_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.MkdirAll("data", 0755); err != nil {
+		log.Printf("FAQ_PERSIST_ERROR: cannot create data directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(faqFile, data, 0644); err != nil {
+		log.Printf("FAQ_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// normalizeFAQText normalizza una domanda per il confronto (minuscole,
+// niente punteggiatura, spazi compattati)
+func normalizeFAQText(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == ' ':
+			b.WriteRune(r)
+		case r == 'à', r == 'è', r == 'é', r == 'ì', r == 'ò', r == 'ù':
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// faqSimilarity calcola la Jaccard sui token di due domande normalizzate
+func faqSimilarity(a, b string) float64 {
+	tokensA := strings.Fields(a)
+	tokensB := strings.Fields(b)
+	if len(tokensA) == 0 || len(tokensB) == 0 {
+		return 0
+	}
+
+	setA := make(map[string]bool, len(tokensA))
+	for _, t := range tokensA {
+		setA[t] = true
+	}
+	intersection := 0
+	setB := make(map[string]bool, len(tokensB))
+	for _, t := range tokensB {
+		if !setB[t] {
+			setB[t] = true
+			if setA[t] {
+				intersection++
+			}
+		}
+	}
+	union := len(setA) + len(setB) - intersection
+	return float64(intersection) / float64(union)
+}
+
+// MatchFAQ cerca una FAQ curata per il messaggio: match esatto sul testo
+// normalizzato, oppure similarità sopra soglia. Ritorna la FAQ e la
+// confidenza del match.
+func MatchFAQ(message string) (*FAQEntry, float64, bool) {
+	normalized := normalizeFAQText(message)
+	if normalized == "" {
+		return nil, 0, false
+	}
+
+	faqs.mu.Lock()
+	defer faqs.mu.Unlock()
+	faqs.ensureLoaded()
+
+	var best *FAQEntry
+	bestScore := 0.0
+	for _, entry := range faqs.entries {
+		if !entry.Enabled {
+			continue
+		}
+		for _, pattern := range entry.Patterns {
+			normPattern := normalizeFAQText(pattern)
+			if normPattern == normalized {
+				return entry, 1.0, true
+			}
+			if score := faqSimilarity(normPattern, normalized); score > bestScore {
+				bestScore = score
+				best = entry
+			}
+		}
+	}
+
+	if best != nil && bestScore >= faqMatchThreshold {
+		return best, bestScore, true
+	}
+	return nil, 0, false
+}
+
+// HandleListFAQs restituisce tutte le FAQ curate
+// GET /admin/api/faqs
+func HandleListFAQs(c *gin.Context) {
+	faqs.mu.Lock()
+	faqs.ensureLoaded()
+	entries := make([]*FAQEntry, 0, len(faqs.entries))
+	for _, e := range faqs.entries {
+		entries = append(entries, e)
+	}
+	faqs.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	c.JSON(http.StatusOK, gin.H{"status": "success", "faqs": entries})
+}
+
+// HandleUpsertFAQ crea o aggiorna una FAQ
+// PUT /admin/api/faqs/:id  body: {"patterns": [...], "answer": "...", "enabled": true}
+func HandleUpsertFAQ(c *gin.Context) {
+	id := c.Param("id")
+
+	var req struct {
+		Patterns []string `json:"patterns"`
+		Answer   string   `json:"answer"`
+		Enabled  *bool    `json:"enabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	if len(req.Patterns) == 0 || strings.TrimSpace(req.Answer) == "" {
+		AbortWithError(c, ErrInvalidParameter, "patterns e answer sono obbligatori")
+		return
+	}
+
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	faqs.mu.Lock()
+	faqs.ensureLoaded()
+	faqs.entries[id] = &FAQEntry{
+		ID:        id,
+		Patterns:  req.Patterns,
+		Answer:    req.Answer,
+		Enabled:   enabled,
+		UpdatedAt: time.Now(),
+	}
+	faqs.persist()
+	faqs.mu.Unlock()
+
+	log.Printf("FAQ_UPSERT: id=%s, patterns=%d, enabled=%v, client_ip=%s", id, len(req.Patterns), enabled, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"status": "success", "id": id})
+}
+
+// HandleDeleteFAQ rimuove una FAQ
+// DELETE /admin/api/faqs/:id
+func HandleDeleteFAQ(c *gin.Context) {
+	id := c.Param("id")
+
+	faqs.mu.Lock()
+	faqs.ensureLoaded()
+	_, existed := faqs.entries[id]
+	delete(faqs.entries, id)
+	if existed {
+		faqs.persist()
+	}
+	faqs.mu.Unlock()
+
+	if !existed {
+		AbortWithError(c, ErrNotFound, "FAQ inesistente")
+		return
+	}
+	log.Printf("FAQ_DELETE: id=%s, client_ip=%s", id, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
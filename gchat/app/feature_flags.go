@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sottosistema feature flag per deployment: le funzionalità rischiose
+// (streaming, handoff, allegati, nuovi renderer data_type) sono gated da
+// flag definiti in config (sezione features) con override runtime via API
+// admin, persistiti su file e con granularità per ASL per il rollout
+// graduale. Risoluzione: override ASL > override globale > config > default.
+
+const flagsFile = "data/feature_flags.json"
+
+// featureDefaults: flag conosciuti e loro valore di default (lo streaming
+// resta governato anche da ui.enable_streaming per retrocompatibilità)
+var featureDefaults = map[string]bool{
+	"streaming":          true,
+	"handoff":            false,
+	"attachments":        false,
+	"new_data_renderers": false,
+	// Anteprima intent live nel pannello debug (POST /api/parse-preview)
+	"debug_parse_preview": false,
+}
+
+// FlagOverride è un override runtime persistito (ASL vuota = globale)
+type FlagOverride struct {
+	Flag      string `json:"flag"`
+	ASL       string `json:"asl,omitempty"`
+	Enabled   bool   `json:"enabled"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type flagStore struct {
+	overrides []*FlagOverride
+	loaded    bool
+	mu        sync.Mutex
+}
+
+var featureFlags = &flagStore{}
+
+// ensureLoaded carica gli override da disco (lazy, come gli altri store)
+func (fs *flagStore) ensureLoaded() {
+	if fs.loaded {
+		return
+	}
+	fs.loaded = true
+
+	data, err := os.ReadFile(flagsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("FLAGS_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &fs.overrides); err != nil {
+		log.Printf("FLAGS_PARSE_ERROR: %v", err)
+	}
+}
+
+// persist salva gli override su disco (chiamare con lock acquisito)
+func (fs *flagStore) persist() {
+	data, err := json.MarshalIndent(fs.overrides, "", "  ")
+	if err != nil {
+		log.Printf("FLAGS_PERSIST_ERROR: %v", err)
+		return
+	}
+	if err := os.WriteFile(flagsFile, data, 0644); err != nil {
+		log.Printf("FLAGS_PERSIST_ERROR: %v", err)
+	}
+}
+
+// findOverride cerca un override per flag+ASL (chiamare con lock acquisito)
+func (fs *flagStore) findOverride(flag, asl string) *FlagOverride {
+	for _, override := range fs.overrides {
+		if override.Flag == flag && override.ASL == asl {
+			return override
+		}
+	}
+	return nil
+}
+
+// FeatureEnabled valuta un flag per l'ASL indicata (vuota = solo globale)
+func FeatureEnabled(flag, asl string) bool {
+	featureFlags.mu.Lock()
+	defer featureFlags.mu.Unlock()
+	featureFlags.ensureLoaded()
+
+	// 1. Override runtime specifico per ASL
+	if asl != "" {
+		if override := featureFlags.findOverride(flag, asl); override != nil {
+			return override.Enabled
+		}
+	}
+	// 2. Override runtime globale
+	if override := featureFlags.findOverride(flag, ""); override != nil {
+		return override.Enabled
+	}
+	// 3. Config statica (sezione features)
+	config := LoadConfig()
+	if enabled, ok := config.Features[flag]; ok {
+		return enabled
+	}
+	// 4. Default del flag conosciuto (sconosciuto = disabilitato)
+	return featureDefaults[flag]
+}
+
+// EvaluatedFlags ritorna tutti i flag conosciuti valutati per l'ASL
+func EvaluatedFlags(asl string) map[string]bool {
+	config := LoadConfig()
+	flags := make(map[string]bool)
+	for flag := range featureDefaults {
+		flags[flag] = FeatureEnabled(flag, asl)
+	}
+	for flag := range config.Features {
+		flags[flag] = FeatureEnabled(flag, asl)
+	}
+	return flags
+}
+
+// HandleListFlags lista i flag valutati e gli override attivi
+// GET /admin/api/flags?asl=NOME
+func HandleListFlags(c *gin.Context) {
+	asl := c.Query("asl")
+
+	featureFlags.mu.Lock()
+	featureFlags.ensureLoaded()
+	overrides := make([]*FlagOverride, len(featureFlags.overrides))
+	copy(overrides, featureFlags.overrides)
+	featureFlags.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"flags":     EvaluatedFlags(asl),
+		"overrides": overrides,
+	})
+}
+
+// HandleSetFlag crea o aggiorna un override runtime
+// PUT /admin/api/flags/:name  body: {"enabled": bool, "asl": "NOME"}
+func HandleSetFlag(c *gin.Context) {
+	flag := c.Param("name")
+
+	var req struct {
+		Enabled bool   `json:"enabled"`
+		ASL     string `json:"asl"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	if req.ASL != "" {
+		canonical, ok := NormalizeASLName(req.ASL)
+		if !ok {
+			AbortWithError(c, ErrInvalidParameter, "ASL sconosciuta: "+req.ASL)
+			return
+		}
+		req.ASL = canonical
+	}
+
+	featureFlags.mu.Lock()
+	featureFlags.ensureLoaded()
+	if override := featureFlags.findOverride(flag, req.ASL); override != nil {
+		override.Enabled = req.Enabled
+		override.UpdatedAt = time.Now().Unix()
+	} else {
+		featureFlags.overrides = append(featureFlags.overrides, &FlagOverride{
+			Flag:      flag,
+			ASL:       req.ASL,
+			Enabled:   req.Enabled,
+			UpdatedAt: time.Now().Unix(),
+		})
+	}
+	featureFlags.persist()
+	featureFlags.mu.Unlock()
+
+	log.Printf("FLAGS_SET: flag=%s, asl='%s', enabled=%v, client_ip=%s", flag, req.ASL, req.Enabled, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"status": "success", "flags": EvaluatedFlags(req.ASL)})
+}
+
+// HandleDeleteFlag rimuove un override runtime (torna al valore di config)
+// DELETE /admin/api/flags/:name?asl=NOME
+func HandleDeleteFlag(c *gin.Context) {
+	flag := c.Param("name")
+	asl := c.Query("asl")
+	if asl != "" {
+		if canonical, ok := NormalizeASLName(asl); ok {
+			asl = canonical
+		}
+	}
+
+	featureFlags.mu.Lock()
+	featureFlags.ensureLoaded()
+	removed := false
+	for i, override := range featureFlags.overrides {
+		if override.Flag == flag && override.ASL == asl {
+			featureFlags.overrides = append(featureFlags.overrides[:i], featureFlags.overrides[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	if removed {
+		featureFlags.persist()
+	}
+	featureFlags.mu.Unlock()
+
+	if !removed {
+		AbortWithError(c, ErrNotFound, "nessun override per questo flag")
+		return
+	}
+
+	log.Printf("FLAGS_DELETE: flag=%s, asl='%s', client_ip=%s", flag, asl, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// HandleCapabilities espone i flag valutati per l'utente corrente insieme
+// alle capacità rilevate del backend (per il gating lato UI)
+// GET /api/capabilities
+func HandleCapabilities(c *gin.Context) {
+	_, _, aslName, _, _ := GetUserSession(c)
+	if canonical, ok := NormalizeASLName(aslName); ok {
+		aslName = canonical
+	}
+
+	executionTracking, probed := GetBackendCapabilities()
+	whisperWarm, _, _ := WhisperStatus()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"features": EvaluatedFlags(aslName),
+		"backend": gin.H{
+			"execution_tracking": executionTracking,
+			"probed":             probed,
+		},
+		"whisper": gin.H{
+			"enabled": LoadConfig().Transcription.Enabled,
+			"warm":    whisperWarm,
+		},
+	})
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Form dinamici guidati dal backend: alcuni tool hanno bisogno di input
+// strutturato (intervallo date, selettore piano). Il backend invia un
+// payload custom "form" negli slot; il gateway ne valida lo schema, lo
+// renderizza (partial HTML più JSON per la SPA), raccoglie la submit su
+// POST /api/forms/:id/submit, valida i tipi e inoltra le risposte al
+// backend come slot.
+
+const (
+	formTTL       = 30 * time.Minute
+	formMaxFields = 20
+)
+
+// Tipi di campo ammessi nello schema form
+var formFieldTypes = map[string]bool{
+	"text":   true,
+	"number": true,
+	"date":   true,
+	"select": true,
+}
+
+// FormField è un campo dello schema form inviato dal backend
+type FormField struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"` // text | number | date | select
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty"` // valori ammessi per select
+}
+
+// FormSpec è lo schema form validato e registrato dal gateway
+type FormSpec struct {
+	ID     string      `json:"id"`
+	Title  string      `json:"title"`
+	Intent string      `json:"intent"` // intent di destinazione della submit
+	Fields []FormField `json:"fields"`
+
+	sender    string
+	expiresAt time.Time
+}
+
+var (
+	formRegistry = map[string]*FormSpec{}
+	formMu       sync.Mutex
+)
+
+// validateFormSpec verifica lo schema ricevuto dal backend
+func validateFormSpec(spec *FormSpec) error {
+	if spec.Title == "" {
+		return fmt.Errorf("titolo mancante")
+	}
+	if len(spec.Fields) == 0 || len(spec.Fields) > formMaxFields {
+		return fmt.Errorf("numero campi non valido: %d", len(spec.Fields))
+	}
+	for _, field := range spec.Fields {
+		if field.Name == "" {
+			return fmt.Errorf("campo senza nome")
+		}
+		if !formFieldTypes[field.Type] {
+			return fmt.Errorf("tipo campo non ammesso: %s", field.Type)
+		}
+		if field.Type == "select" && len(field.Options) == 0 {
+			return fmt.Errorf("campo select %s senza opzioni", field.Name)
+		}
+	}
+	return nil
+}
+
+// ExtractForm estrae e registra un eventuale payload "form" dagli slot.
+// Ritorna lo spec registrato, o nil se assente o non valido.
+func ExtractForm(sender string, slots map[string]interface{}) *FormSpec {
+	raw, ok := slots["form"]
+	if !ok {
+		return nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var spec FormSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		log.Printf("FORM_ERROR: payload form non deserializzabile: %v", err)
+		return nil
+	}
+	if err := validateFormSpec(&spec); err != nil {
+		log.Printf("FORM_ERROR: schema form non valido: %v", err)
+		return nil
+	}
+
+	spec.ID = newContinuationHandle()
+	spec.sender = sender
+	spec.expiresAt = time.Now().Add(formTTL)
+
+	formMu.Lock()
+	for id, existing := range formRegistry {
+		if time.Now().After(existing.expiresAt) {
+			delete(formRegistry, id)
+		}
+	}
+	formRegistry[spec.ID] = &spec
+	formMu.Unlock()
+
+	log.Printf("FORM_REGISTERED: id=%s, title=%s, fields=%d, sender=%s", spec.ID, spec.Title, len(spec.Fields), sender)
+	return &spec
+}
+
+// RenderFormHTML genera il partial HTML del form (tutti i valori escapati)
+func RenderFormHTML(spec *FormSpec) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`<form class="dynamic-form" data-form-id="%s" action="`+BasePath(LoadConfig())+`/api/forms/%s/submit" method="post">`,
+		html.EscapeString(spec.ID), html.EscapeString(spec.ID)))
+	sb.WriteString(fmt.Sprintf(`<p class="form-title">%s</p>`, html.EscapeString(spec.Title)))
+	for _, field := range spec.Fields {
+		name := html.EscapeString(field.Name)
+		label := html.EscapeString(field.Label)
+		if label == "" {
+			label = name
+		}
+		required := ""
+		if field.Required {
+			required = " required"
+		}
+		sb.WriteString(fmt.Sprintf(`<label for="form-%s">%s</label>`, name, label))
+		switch field.Type {
+		case "select":
+			sb.WriteString(fmt.Sprintf(`<select id="form-%s" name="%s"%s>`, name, name, required))
+			for _, option := range field.Options {
+				escaped := html.EscapeString(option)
+				sb.WriteString(fmt.Sprintf(`<option value="%s">%s</option>`, escaped, escaped))
+			}
+			sb.WriteString(`</select>`)
+		case "date":
+			sb.WriteString(fmt.Sprintf(`<input type="date" id="form-%s" name="%s"%s>`, name, name, required))
+		case "number":
+			sb.WriteString(fmt.Sprintf(`<input type="number" id="form-%s" name="%s"%s>`, name, name, required))
+		default:
+			sb.WriteString(fmt.Sprintf(`<input type="text" id="form-%s" name="%s"%s>`, name, name, required))
+		}
+	}
+	sb.WriteString(`<button type="submit">Invia</button></form>`)
+	return sb.String()
+}
+
+// validateFormAnswer valida il valore di un singolo campo
+func validateFormAnswer(field FormField, value interface{}) (interface{}, error) {
+	switch field.Type {
+	case "number":
+		switch typed := value.(type) {
+		case float64:
+			return typed, nil
+		case string:
+			if parsed, err := strconv.ParseFloat(typed, 64); err == nil {
+				return parsed, nil
+			}
+		}
+		return nil, fmt.Errorf("il campo %s deve essere numerico", field.Name)
+	case "date":
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("il campo %s deve essere una data (YYYY-MM-DD)", field.Name)
+		}
+		if _, err := time.Parse("2006-01-02", text); err != nil {
+			return nil, fmt.Errorf("il campo %s deve essere una data valida (YYYY-MM-DD)", field.Name)
+		}
+		return text, nil
+	case "select":
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("il campo %s deve essere una delle opzioni previste", field.Name)
+		}
+		for _, option := range field.Options {
+			if text == option {
+				return text, nil
+			}
+		}
+		return nil, fmt.Errorf("valore non ammesso per il campo %s", field.Name)
+	default:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("il campo %s deve essere testuale", field.Name)
+		}
+		return text, nil
+	}
+}
+
+// forwardFormSlots inoltra le risposte del form al backend come slot
+// sul canale chat V1
+func forwardFormSlots(config *Config, spec *FormSpec, answers map[string]interface{}) (*NativeChatResponse, error) {
+	payload := map[string]interface{}{
+		"sender":  spec.sender,
+		"message": "/form_submit{\"intent\": \"" + spec.Intent + "\"}",
+		"slots":   answers,
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewUpstreamClient("llm", time.Duration(config.LLMServer.Timeout)*time.Second)
+	req, err := http.NewRequest("POST", config.LLMServer.URL+"/api/v1/chat", strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	ApplyUpstreamHeaders(req, nil)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("backend status %d: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var chatResp NativeChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, err
+	}
+	return &chatResp, nil
+}
+
+// HandleFormSubmit raccoglie e valida la submit di un form dinamico
+// POST /api/forms/:id/submit
+func HandleFormSubmit(c *gin.Context) {
+	config := LoadConfig()
+	formID := c.Param("id")
+
+	formMu.Lock()
+	spec, ok := formRegistry[formID]
+	if ok && time.Now().After(spec.expiresAt) {
+		delete(formRegistry, formID)
+		ok = false
+	}
+	formMu.Unlock()
+	if !ok {
+		AbortWithError(c, ErrNotFound, "form scaduto o inesistente")
+		return
+	}
+
+	var submission struct {
+		Answers map[string]interface{} `json:"answers"`
+	}
+	if err := c.ShouldBindJSON(&submission); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	validated := make(map[string]interface{}, len(spec.Fields))
+	for _, field := range spec.Fields {
+		value, present := submission.Answers[field.Name]
+		if !present || value == "" {
+			if field.Required {
+				AbortWithError(c, ErrInvalidParameter, fmt.Sprintf("campo obbligatorio mancante: %s", field.Name))
+				return
+			}
+			continue
+		}
+		typed, err := validateFormAnswer(field, value)
+		if err != nil {
+			AbortWithError(c, ErrInvalidParameter, err.Error())
+			return
+		}
+		validated[field.Name] = typed
+	}
+
+	v1Resp, err := forwardFormSlots(config, spec, validated)
+	if err != nil {
+		log.Printf("FORM_ERROR: inoltro submit fallito - id=%s, error=%v", formID, err)
+		AbortWithError(c, ErrBackendError, err.Error())
+		return
+	}
+
+	// Form monouso: una submit valida lo consuma
+	formMu.Lock()
+	delete(formRegistry, formID)
+	formMu.Unlock()
+
+	log.Printf("FORM_SUBMITTED: id=%s, fields=%d, sender=%s", formID, len(validated), spec.sender)
+
+	c.JSON(http.StatusOK, ChatResponse{
+		Message: v1Resp.Result.Text,
+		Status:  "success",
+	})
+}
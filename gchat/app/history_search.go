@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Ricerca full-text nella cronologia dell'utente: "quella risposta sul
+// campionamento latte del mese scorso" oggi si ritrova solo scorrendo le
+// conversazioni una a una. GET /api/history/search?q= interroga le API
+// chat-log del backend, filtra i messaggi dell'utente lato gateway
+// (normalizzazione accenti/maiuscole, match su tutti i termini) e ritorna
+// snippet evidenziati con deep link al turno nella pagina cronologia.
+
+const (
+	historySearchMaxConvs   = 50               // conversazioni esplorate per ricerca
+	historySearchMaxResults = 50               // risultati restituiti
+	historySearchSnippetLen = 80               // caratteri di contesto attorno al match
+	historySearchCacheTTL   = 60 * time.Second // cache trascrizioni per utente
+)
+
+type historyCacheEntry struct {
+	conversations map[string][]gin.H // session_id -> turni {role, text}
+	fetchedAt     time.Time
+}
+
+var (
+	historyCache   = map[string]*historyCacheEntry{}
+	historyCacheMu sync.Mutex
+)
+
+// historyConversationIDs lista gli ID conversazione dell'utente
+func historyConversationIDs(config *Config, codiceFiscale string) ([]string, error) {
+	client := NewUpstreamClient("llm", time.Duration(config.LLMServer.Timeout)*time.Second)
+	resp, err := upstreamGet(client, config.LLMServer.URL+"/api/chat-log/user-conversations?codice_fiscale="+url.QueryEscape(codiceFiscale))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("backend status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, key := range []string{"conversations", "results", "data"} {
+		list, ok := payload[key].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, item := range list {
+			conv, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, idKey := range []string{"session_id", "sid", "id", "sender"} {
+				if id, ok := conv[idKey].(string); ok && id != "" {
+					ids = append(ids, id)
+					break
+				}
+			}
+		}
+		break
+	}
+	if len(ids) > historySearchMaxConvs {
+		ids = ids[:historySearchMaxConvs]
+	}
+	return ids, nil
+}
+
+// historyTranscripts recupera (con cache breve) le trascrizioni dell'utente
+func historyTranscripts(config *Config, codiceFiscale string) (map[string][]gin.H, error) {
+	historyCacheMu.Lock()
+	if entry, ok := historyCache[codiceFiscale]; ok && time.Since(entry.fetchedAt) < historySearchCacheTTL {
+		historyCacheMu.Unlock()
+		return entry.conversations, nil
+	}
+	historyCacheMu.Unlock()
+
+	ids, err := historyConversationIDs(config, codiceFiscale)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations := make(map[string][]gin.H, len(ids))
+	for _, id := range ids {
+		turns, err := conversationTurns(config, id, false)
+		if err != nil {
+			log.Printf("HISTORY_SEARCH_WARNING: trascrizione %s non disponibile: %v", id, err)
+			continue
+		}
+		conversations[id] = turns
+	}
+
+	historyCacheMu.Lock()
+	historyCache[codiceFiscale] = &historyCacheEntry{conversations: conversations, fetchedAt: time.Now()}
+	historyCacheMu.Unlock()
+	return conversations, nil
+}
+
+// historySnippet costruisce lo snippet evidenziato (HTML-safe) attorno al
+// primo termine trovato
+func historySnippet(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	pos := -1
+	for _, term := range terms {
+		if idx := strings.Index(lower, term); idx != -1 && (pos == -1 || idx < pos) {
+			pos = idx
+		}
+	}
+	if pos == -1 {
+		pos = 0
+	}
+
+	start := pos - historySearchSnippetLen
+	if start < 0 {
+		start = 0
+	}
+	end := pos + historySearchSnippetLen
+	if end > len(text) {
+		end = len(text)
+	}
+	snippet := html.EscapeString(text[start:end])
+	for _, term := range terms {
+		re, err := regexp.Compile("(?i)" + regexp.QuoteMeta(html.EscapeString(term)))
+		if err != nil {
+			continue
+		}
+		snippet = re.ReplaceAllString(snippet, "<mark>$0</mark>")
+	}
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// HandleHistorySearch cerca nei messaggi delle conversazioni dell'utente
+// GET /api/history/search?q=campionamento+latte&codice_fiscale=...
+func HandleHistorySearch(c *gin.Context) {
+	if !RequireDatabase(c) {
+		return
+	}
+	config := LoadConfig()
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		AbortWithError(c, ErrInvalidParameter, "parametro q mancante")
+		return
+	}
+	codiceFiscale := c.Query("codice_fiscale")
+	if codiceFiscale == "" {
+		AbortWithError(c, ErrInvalidParameter, "parametro codice_fiscale mancante")
+		return
+	}
+
+	terms := strings.Fields(normalizeFAQText(query))
+	if len(terms) == 0 {
+		AbortWithError(c, ErrInvalidParameter, "query di ricerca vuota dopo la normalizzazione")
+		return
+	}
+
+	conversations, err := historyTranscripts(config, codiceFiscale)
+	if err != nil {
+		log.Printf("HISTORY_SEARCH_ERROR: recupero cronologia fallito - error=%v", err)
+		AbortWithError(c, ErrBackendError, "cronologia non disponibile")
+		return
+	}
+
+	var results []gin.H
+	for sessionID, turns := range conversations {
+		for turnIdx, turn := range turns {
+			text, _ := turn["text"].(string)
+			normalized := normalizeFAQText(text)
+			matched := true
+			for _, term := range terms {
+				if !strings.Contains(normalized, term) {
+					matched = false
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+			results = append(results, gin.H{
+				"session_id": sessionID,
+				"turn":       turnIdx,
+				"role":       turn["role"],
+				"snippet":    historySnippet(text, terms),
+				"deep_link":  fmt.Sprintf("%s/history?sid=%s&turn=%d", BasePath(config), url.QueryEscape(sessionID), turnIdx),
+			})
+			if len(results) >= historySearchMaxResults {
+				break
+			}
+		}
+		if len(results) >= historySearchMaxResults {
+			break
+		}
+	}
+
+	log.Printf("HISTORY_SEARCH: q=\"%s\", conversations=%d, results=%d", query, len(conversations), len(results))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"query":   query,
+		"results": results,
+	})
+}
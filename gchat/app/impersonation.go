@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Impersonificazione per il supporto: per riprodurre il problema di un
+// utente senza conoscerne le credenziali del portale, un endpoint admin
+// crea una sessione con il contesto identità dell'utente target. Ogni
+// impersonificazione è tracciata su audit log persistente, ha una
+// scadenza limitata e viene esposta nel template con un banner esplicito.
+
+const (
+	impersonationAuditFile  = "data/impersonation_audit.json"
+	impersonationDefaultMin = 15 // durata default in minuti
+	impersonationMaxMin     = 60 // durata massima consentita
+)
+
+// ImpersonationAuditEntry è una riga dell'audit log
+type ImpersonationAuditEntry struct {
+	AdminIP   string    `json:"admin_ip"`
+	TargetID  int       `json:"target_user_id"`
+	TargetCF  string    `json:"target_codice_fiscale"`
+	Reason    string    `json:"reason"`
+	StartedAt time.Time `json:"started_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Ended     bool      `json:"ended"` // chiusura esplicita prima della scadenza
+}
+
+type impersonationAuditStore struct {
+	entries []ImpersonationAuditEntry
+	mu      sync.Mutex
+	loaded  bool
+}
+
+var impersonationAudit = &impersonationAuditStore{}
+
+func (s *impersonationAuditStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(impersonationAuditFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("IMPERSONATE_AUDIT_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		log.Printf("IMPERSONATE_AUDIT_PARSE_ERROR: %v", err)
+	}
+}
+
+// persist scrive su file (da chiamare con il lock già acquisito)
+func (s *impersonationAuditStore) persist() {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		log.Printf("IMPERSONATE_AUDIT_PERSIST_ERROR: marshal fallito: %v", err)
+		return
+	}
+	if err := os.WriteFile(impersonationAuditFile, data, 0644); err != nil {
+		log.Printf("IMPERSONATE_AUDIT_PERSIST_ERROR: scrittura fallita: %v", err)
+	}
+}
+
+// ImpersonationActive ritorna i dati per il banner se la sessione corrente
+// è una impersonificazione non scaduta
+func ImpersonationActive(c *gin.Context) (gin.H, bool) {
+	session := sessions.Default(c)
+	expires, ok := session.Get("impersonation_expires").(int64)
+	if !ok {
+		return nil, false
+	}
+	if time.Now().Unix() > expires {
+		return nil, false
+	}
+	reason, _ := session.Get("impersonation_reason").(string)
+	return gin.H{
+		"active":     true,
+		"reason":     reason,
+		"expires_at": time.Unix(expires, 0).Format("15:04"),
+	}, true
+}
+
+// HandleImpersonate avvia una sessione con l'identità dell'utente target
+// POST /admin/api/impersonate {"user_id": 6448, "reason": "...", "duration_minutes": 15}
+func HandleImpersonate(c *gin.Context) {
+	clientIP := c.ClientIP()
+
+	var req struct {
+		UserID          int    `json:"user_id"`
+		Reason          string `json:"reason"`
+		DurationMinutes int    `json:"duration_minutes"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	if req.Reason == "" {
+		AbortWithError(c, ErrInvalidParameter, "campo reason obbligatorio (motivazione per l'audit)")
+		return
+	}
+
+	personale, err := GetPersonaleByUserID(req.UserID)
+	if err != nil {
+		AbortWithError(c, ErrNotFound, fmt.Sprintf("utente %d non trovato in personale", req.UserID))
+		return
+	}
+
+	duration := req.DurationMinutes
+	if duration <= 0 {
+		duration = impersonationDefaultMin
+	}
+	if duration > impersonationMaxMin {
+		duration = impersonationMaxMin
+	}
+	expiresAt := time.Now().Add(time.Duration(duration) * time.Minute)
+
+	// Sessione con l'identità del target più i marcatori di impersonificazione
+	if err := SaveUserSession(c, fmt.Sprintf("%d", req.UserID), "", personale.ASL, personale.CodiceFiscale, ""); err != nil {
+		AbortWithError(c, ErrInternal, err.Error())
+		return
+	}
+	session := sessions.Default(c)
+	session.Set("impersonation_expires", expiresAt.Unix())
+	session.Set("impersonation_reason", req.Reason)
+	if err := session.Save(); err != nil {
+		AbortWithError(c, ErrInternal, err.Error())
+		return
+	}
+
+	entry := ImpersonationAuditEntry{
+		AdminIP:   clientIP,
+		TargetID:  req.UserID,
+		TargetCF:  personale.CodiceFiscale,
+		Reason:    req.Reason,
+		StartedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	impersonationAudit.mu.Lock()
+	impersonationAudit.ensureLoaded()
+	impersonationAudit.entries = append(impersonationAudit.entries, entry)
+	impersonationAudit.persist()
+	impersonationAudit.mu.Unlock()
+
+	log.Printf("IMPERSONATE_STARTED: admin_ip=%s, target_user_id=%d, reason=\"%s\", expires=%s",
+		clientIP, req.UserID, req.Reason, expiresAt.Format("15:04:05"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"user_id":    req.UserID,
+		"asl":        personale.ASL,
+		"expires_at": expiresAt,
+	})
+}
+
+// HandleImpersonateEnd chiude esplicitamente l'impersonificazione corrente
+// DELETE /admin/api/impersonate
+func HandleImpersonateEnd(c *gin.Context) {
+	session := sessions.Default(c)
+	if _, ok := session.Get("impersonation_expires").(int64); !ok {
+		AbortWithError(c, ErrNotFound, "nessuna impersonificazione attiva")
+		return
+	}
+	session.Clear()
+	if err := session.Save(); err != nil {
+		AbortWithError(c, ErrInternal, err.Error())
+		return
+	}
+
+	impersonationAudit.mu.Lock()
+	impersonationAudit.ensureLoaded()
+	if n := len(impersonationAudit.entries); n > 0 {
+		impersonationAudit.entries[n-1].Ended = true
+		impersonationAudit.persist()
+	}
+	impersonationAudit.mu.Unlock()
+
+	log.Printf("IMPERSONATE_ENDED: admin_ip=%s", c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Questionario di ingresso configurabile: alcune ASL vogliono una breve
+// raccolta strutturata (ruolo, piano di interesse) prima della chat
+// libera. I campi sono definiti in config (sezione intake_form), la pagina
+// index li rende, le risposte finiscono in sessione e vengono iniettate
+// nei metadata verso l'LLM. Gli utenti che hanno già risposto non lo
+// rivedono (skip logic via sessione).
+
+const intakeSessionPrefix = "intake_"
+const intakeDoneKey = "intake_done"
+
+// IntakeField è un singolo campo del questionario
+type IntakeField struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"` // "text" | "select"
+	Options  []string `json:"options,omitempty"`
+	Required bool     `json:"required"`
+}
+
+// IntakeFormConfig definisce il questionario in config
+type IntakeFormConfig struct {
+	Enabled bool          `json:"enabled"`
+	Title   string        `json:"title"`
+	Fields  []IntakeField `json:"fields"`
+}
+
+// IntakePending indica se il questionario va mostrato all'utente corrente
+func IntakePending(c *gin.Context, config *Config) bool {
+	if !config.IntakeForm.Enabled || len(config.IntakeForm.Fields) == 0 {
+		return false
+	}
+	session := sessions.Default(c)
+	if done, ok := session.Get(intakeDoneKey).(bool); ok && done {
+		return false
+	}
+	return true
+}
+
+// IntakeMetadata recupera le risposte del questionario dalla sessione,
+// nel formato da iniettare nei metadata backend (vuoto se non compilato)
+func IntakeMetadata(c *gin.Context) map[string]interface{} {
+	config := LoadConfig()
+	if !config.IntakeForm.Enabled {
+		return nil
+	}
+
+	session := sessions.Default(c)
+	if done, ok := session.Get(intakeDoneKey).(bool); !ok || !done {
+		return nil
+	}
+
+	answers := make(map[string]interface{})
+	for _, field := range config.IntakeForm.Fields {
+		if value, ok := session.Get(intakeSessionPrefix + field.Name).(string); ok && value != "" {
+			answers[field.Name] = value
+		}
+	}
+	if len(answers) == 0 {
+		return nil
+	}
+	return answers
+}
+
+// HandleIntakeSubmit valida e salva le risposte del questionario
+// POST /api/intake  body: {"responses": {"campo": "valore"}}
+func HandleIntakeSubmit(c *gin.Context) {
+	config := LoadConfig()
+	if !config.IntakeForm.Enabled {
+		AbortWithError(c, ErrNotFound, "questionario di ingresso non attivo")
+		return
+	}
+
+	var req struct {
+		Responses map[string]string `json:"responses"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	session := sessions.Default(c)
+	for _, field := range config.IntakeForm.Fields {
+		value := strings.TrimSpace(req.Responses[field.Name])
+		if value == "" {
+			if field.Required {
+				AbortWithError(c, ErrInvalidParameter, fmt.Sprintf("campo obbligatorio mancante: %s", field.Name))
+				return
+			}
+			continue
+		}
+		// Per i campi select il valore deve essere una delle opzioni
+		if field.Type == "select" && len(field.Options) > 0 {
+			valid := false
+			for _, option := range field.Options {
+				if option == value {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				AbortWithError(c, ErrInvalidParameter, fmt.Sprintf("valore non ammesso per %s", field.Name))
+				return
+			}
+		}
+		session.Set(intakeSessionPrefix+field.Name, value)
+	}
+
+	session.Set(intakeDoneKey, true)
+	if err := session.Save(); err != nil {
+		log.Printf("INTAKE_SESSION_ERROR: %v", err)
+		AbortWithError(c, ErrInternal, "impossibile salvare le risposte")
+		return
+	}
+
+	log.Printf("INTAKE_SUBMITTED: campi=%d, client_ip=%s", len(req.Responses), c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
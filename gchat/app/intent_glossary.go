@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Glossario intent per le pagine debug e analytics: i nomi raw degli
+// intent (info_piano_attivita) non dicono nulla agli utenti business. Il
+// glossario intent → descrizione/categoria viene recuperato dal backend
+// (tabella intents esposta su /api/intents) con fallback sul file locale
+// data/intent_glossary.json, e servito cachato su
+// GET /api/debug/intent-glossary.
+
+const (
+	intentGlossaryFile = "data/intent_glossary.json"
+	intentGlossaryTTL  = 10 * time.Minute
+)
+
+// IntentGlossaryEntry descrive un intent in termini comprensibili
+type IntentGlossaryEntry struct {
+	Intent      string `json:"intent"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Example     string `json:"example_question,omitempty"`
+}
+
+type intentGlossaryCache struct {
+	entries   []IntentGlossaryEntry
+	source    string // "backend" | "file" | ""
+	fetchedAt time.Time
+	mu        sync.Mutex
+}
+
+var intentGlossary = &intentGlossaryCache{}
+
+// loadGlossaryFile carica il fallback locale (lista di voci in JSON)
+func loadGlossaryFile() []IntentGlossaryEntry {
+	data, err := os.ReadFile(intentGlossaryFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("INTENT_GLOSSARY_ERROR: lettura %s fallita: %v", intentGlossaryFile, err)
+		}
+		return nil
+	}
+	var entries []IntentGlossaryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("INTENT_GLOSSARY_ERROR: parse %s fallito: %v", intentGlossaryFile, err)
+		return nil
+	}
+	return entries
+}
+
+// fetchGlossaryBackend recupera il glossario dal backend (tabella intents)
+func fetchGlossaryBackend(llmServerURL string) []IntentGlossaryEntry {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var payload struct {
+		Intents []IntentGlossaryEntry `json:"intents"`
+	}
+	if err := fetchBackendJSON(ctx, llmServerURL+"/api/intents", &payload); err != nil {
+		log.Printf("INTENT_GLOSSARY_BACKEND_ERROR: %v", err)
+		return nil
+	}
+	return payload.Intents
+}
+
+// GetIntentGlossary ritorna il glossario cachato, preferendo il backend e
+// ripiegando sul file locale
+func GetIntentGlossary(llmServerURL string) ([]IntentGlossaryEntry, string) {
+	intentGlossary.mu.Lock()
+	defer intentGlossary.mu.Unlock()
+
+	if time.Since(intentGlossary.fetchedAt) < intentGlossaryTTL && intentGlossary.entries != nil {
+		return intentGlossary.entries, intentGlossary.source
+	}
+
+	if entries := fetchGlossaryBackend(llmServerURL); len(entries) > 0 {
+		intentGlossary.entries = entries
+		intentGlossary.source = "backend"
+	} else if entries := loadGlossaryFile(); len(entries) > 0 {
+		intentGlossary.entries = entries
+		intentGlossary.source = "file"
+	}
+	intentGlossary.fetchedAt = time.Now()
+
+	if intentGlossary.entries != nil {
+		sorted := make([]IntentGlossaryEntry, len(intentGlossary.entries))
+		copy(sorted, intentGlossary.entries)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Intent < sorted[j].Intent })
+		intentGlossary.entries = sorted
+		log.Printf("INTENT_GLOSSARY_LOADED: %d intent da %s", len(sorted), intentGlossary.source)
+	}
+	return intentGlossary.entries, intentGlossary.source
+}
+
+// HandleIntentGlossary serve il glossario intent alle pagine debug e analytics
+// GET /api/debug/intent-glossary
+func HandleIntentGlossary(c *gin.Context) {
+	config := LoadConfig()
+	entries, source := GetIntentGlossary(config.LLMServer.URL)
+
+	if entries == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"source":  "none",
+			"intents": []IntentGlossaryEntry{},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"source":  source,
+		"intents": entries,
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// Timeout per intent: un saluto deve fallire in pochi secondi, un report
+// completo ha legittimamente bisogno di 90. Con un solo timeout globale
+// (llm_server.timeout) il tool più lento costringe tutti ad aspettare. La
+// sezione intent_timeouts di config.json mappa pattern di intent (match
+// esatto o prefisso con "*") a un override in secondi; l'intent viene
+// risolto con una ParseMessage rapida prima della chiamata chat.
+
+// intentTimeoutParseSeconds: budget della parse NLU usata solo per
+// risolvere l'intent (non deve pesare sul turno)
+const intentTimeoutParseSeconds = 5
+
+// IntentTimeoutsConfig mappa gli intent ai timeout dedicati
+type IntentTimeoutsConfig struct {
+	Enabled bool `json:"enabled"`
+	// OverridesSeconds: intent o prefisso con "*" -> timeout in secondi
+	// (es. "report_*": 90, "greet": 5)
+	OverridesSeconds map[string]int `json:"overrides_seconds"`
+}
+
+// matchIntentPattern verifica il pattern (esatto o prefisso con "*")
+func matchIntentPattern(pattern, intent string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(intent, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == intent
+}
+
+// intentTimeoutFor risolve l'override per un intent già noto (0 = nessuno).
+// A parità di match vince il pattern più specifico (più lungo).
+func intentTimeoutFor(config *Config, intent string) int {
+	if intent == "" {
+		return 0
+	}
+	best, bestLen := 0, -1
+	for pattern, seconds := range config.IntentTimeouts.OverridesSeconds {
+		if seconds > 0 && matchIntentPattern(pattern, intent) && len(pattern) > bestLen {
+			best, bestLen = seconds, len(pattern)
+		}
+	}
+	return best
+}
+
+// ResolveIntentTimeout ritorna il timeout da usare per il messaggio:
+// quello dell'intent se la parse lo risolve e ha un override, altrimenti
+// il default passato. La parse usa un budget ridotto e in caso di errore
+// si resta sul default (mai bloccare il turno per l'ottimizzazione).
+func ResolveIntentTimeout(config *Config, message string, context map[string]interface{}, defaultTimeout int) int {
+	if !config.IntentTimeouts.Enabled || len(config.IntentTimeouts.OverridesSeconds) == 0 {
+		return defaultTimeout
+	}
+
+	parseResp, err := ParseMessage(message, config.LLMServer.URL, intentTimeoutParseSeconds, context)
+	if err != nil || parseResp == nil {
+		log.Printf("INTENT_TIMEOUT_PARSE_ERROR: fallback al timeout di default: %v", err)
+		return defaultTimeout
+	}
+
+	if override := intentTimeoutFor(config, parseResp.Intent); override > 0 {
+		log.Printf("INTENT_TIMEOUT_OVERRIDE: intent=%s, timeout=%ds (default %ds)",
+			parseResp.Intent, override, defaultTimeout)
+		return override
+	}
+	return defaultTimeout
+}
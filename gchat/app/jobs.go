@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Coda job persistente per operazioni backend lente (es. generazione report
+// completi) che superano qualsiasi timeout HTTP ragionevole. Il gateway
+// rileva i payload custom "job_started"/"job_completed" del backend, tiene
+// traccia dello stato su file (sopravvive ai riavvii) ed espone un'API di
+// polling; il completamento viene anche inoltrato sul canale SSE.
+
+const jobsFile = "data/jobs.json"
+
+type Job struct {
+	ID        string                 `json:"id"`
+	Sender    string                 `json:"sender"`
+	Status    string                 `json:"status"` // running | completed | failed
+	Intent    string                 `json:"intent,omitempty"`
+	Result    map[string]interface{} `json:"result,omitempty"`
+	CreatedAt int64                  `json:"created_at"`
+	UpdatedAt int64                  `json:"updated_at"`
+}
+
+type jobStore struct {
+	jobs   map[string]*Job
+	mu     sync.Mutex
+	loaded bool
+}
+
+var jobQueue = &jobStore{
+	jobs: make(map[string]*Job),
+}
+
+func (s *jobStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(jobsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("JOBS_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	var list []*Job
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("JOBS_LOAD_ERROR: parse failed: %v", err)
+		return
+	}
+	for _, j := range list {
+		s.jobs[j.ID] = j
+	}
+	log.Printf("JOBS_LOADED: %d job caricati da %s", len(list), jobsFile)
+}
+
+// persist salva su file (chiamare con lock acquisito)
+func (s *jobStore) persist() {
+	list := make([]*Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		list = append(list, j)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		log.Printf("JOBS_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.MkdirAll("data", 0755); err != nil {
+		log.Printf("JOBS_PERSIST_ERROR: cannot create data directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(jobsFile, data, 0644); err != nil {
+		log.Printf("JOBS_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// jobIDFromMetadata estrae il job_id da un payload custom del backend
+func jobIDFromMetadata(meta map[string]interface{}) string {
+	if meta == nil {
+		return ""
+	}
+	if id, ok := meta["job_id"].(string); ok {
+		return id
+	}
+	return ""
+}
+
+// JobObserveEvent rileva eventi job_started/job_completed/job_failed nello
+// stream SSE e aggiorna lo stato del job. Da chiamare nel loop di streaming.
+func JobObserveEvent(sender string, event SSEEvent) {
+	switch event.Type {
+	case "job_started", "job_completed", "job_failed":
+	default:
+		return
+	}
+
+	jobID := jobIDFromMetadata(event.Metadata)
+	if jobID == "" {
+		log.Printf("JOBS_EVENT_SKIP: evento %s senza job_id - sender=%s", event.Type, sender)
+		return
+	}
+
+	jobQueue.mu.Lock()
+	defer jobQueue.mu.Unlock()
+	jobQueue.ensureLoaded()
+
+	now := time.Now().Unix()
+	job, ok := jobQueue.jobs[jobID]
+	if !ok {
+		job = &Job{ID: jobID, Sender: sender, CreatedAt: now}
+		jobQueue.jobs[jobID] = job
+	}
+
+	switch event.Type {
+	case "job_started":
+		job.Status = "running"
+		if intent, ok := event.Metadata["intent"].(string); ok {
+			job.Intent = intent
+		}
+	case "job_completed":
+		job.Status = "completed"
+		job.Result = event.Metadata
+	case "job_failed":
+		job.Status = "failed"
+		job.Result = event.Metadata
+	}
+	job.UpdatedAt = now
+	jobQueue.persist()
+
+	log.Printf("JOBS_EVENT: type=%s, job_id=%s, sender=%s, status=%s", event.Type, jobID, sender, job.Status)
+
+	// Esito del job nell'inbox notifiche: l'utente lo ritrova anche se ha
+	// chiuso la chat prima del completamento (vedi notifications.go)
+	switch event.Type {
+	case "job_completed":
+		NotifyUser(sender, "job_completed", "Elaborazione completata", "Il risultato richiesto è pronto.", jobID)
+	case "job_failed":
+		NotifyUser(sender, "job_failed", "Elaborazione non riuscita", "L'elaborazione richiesta non è andata a buon fine.", jobID)
+	}
+}
+
+// JobObserveResult rileva un job avviato da una risposta non-streaming
+// (slot job_id nel risultato V1)
+func JobObserveResult(sender string, result *ChatResultV1) {
+	jobID := jobIDFromMetadata(result.Slots)
+	if jobID == "" {
+		return
+	}
+
+	jobQueue.mu.Lock()
+	defer jobQueue.mu.Unlock()
+	jobQueue.ensureLoaded()
+
+	now := time.Now().Unix()
+	if _, ok := jobQueue.jobs[jobID]; !ok {
+		jobQueue.jobs[jobID] = &Job{
+			ID:        jobID,
+			Sender:    sender,
+			Status:    "running",
+			Intent:    result.Intent,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		jobQueue.persist()
+		log.Printf("JOBS_STARTED: job_id=%s, sender=%s, intent=%s (da risposta non-streaming)", jobID, sender, result.Intent)
+	}
+}
+
+// HandleGetJob restituisce lo stato di un job per il polling del client
+// GET /api/jobs/:id
+func HandleGetJob(c *gin.Context) {
+	jobID := c.Param("id")
+
+	jobQueue.mu.Lock()
+	jobQueue.ensureLoaded()
+	job, ok := jobQueue.jobs[jobID]
+	jobQueue.mu.Unlock()
+
+	if !ok {
+		AbortWithError(c, ErrNotFound, "job non trovato")
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// HandleListJobs elenca i job di un utente, più recenti per primi
+// GET /api/jobs?sender=
+func HandleListJobs(c *gin.Context) {
+	sender := c.Query("sender")
+	if sender == "" {
+		AbortWithError(c, ErrInvalidParameter, "parametro sender obbligatorio")
+		return
+	}
+
+	jobQueue.mu.Lock()
+	jobQueue.ensureLoaded()
+	var list []*Job
+	for _, j := range jobQueue.jobs {
+		if j.Sender == sender {
+			list = append(list, j)
+		}
+	}
+	jobQueue.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt > list[j].CreatedAt
+	})
+
+	c.JSON(http.StatusOK, gin.H{"jobs": list, "status": "success"})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+)
+
+// Guardia sulla lingua delle risposte: dopo alcuni aggiornamenti di prompt
+// il backend ogni tanto risponde in inglese, e l'errore arriva dritto
+// all'utente. Qui la risposta finale passa da una rilevazione euristica
+// della lingua (conteggio di stopword, nessuna dipendenza esterna): al
+// mismatch con la lingua attesa viene loggato un evento di qualità e
+// incrementata la metrica, e con auto_translate attivo la risposta viene
+// instradata dal modulo di traduzione (vedi translation.go) prima della
+// consegna.
+
+// LanguageGuardConfig configura la guardia (sezione language_guard)
+type LanguageGuardConfig struct {
+	Enabled bool `json:"enabled"`
+	// AutoTranslate: se true la risposta in lingua sbagliata viene
+	// tradotta verso la lingua attesa invece che consegnata com'è
+	AutoTranslate bool `json:"auto_translate"`
+}
+
+// Stopword più frequenti per lingua: bastano a distinguere it/en su testi
+// di risposta (qualche frase); sotto le 3 occorrenze la lingua è "ignota"
+var (
+	italianStopwords = map[string]bool{
+		"il": true, "la": true, "le": true, "di": true, "che": true, "per": true,
+		"con": true, "del": true, "della": true, "sono": true, "non": true,
+		"una": true, "gli": true, "dei": true, "delle": true, "alla": true,
+		"nel": true, "questo": true, "essere": true, "come": true,
+	}
+	englishStopwords = map[string]bool{
+		"the": true, "of": true, "and": true, "to": true, "is": true,
+		"for": true, "that": true, "with": true, "are": true, "this": true,
+		"have": true, "from": true, "not": true, "been": true, "which": true,
+		"there": true, "can": true, "will": true,
+	}
+)
+
+type languageGuardStats struct {
+	mismatches int
+	translated int
+	mu         sync.Mutex
+}
+
+var langGuard = &languageGuardStats{}
+
+// detectResponseLanguage rileva la lingua del testo: "it", "en" o ""
+// (indeterminata, es. testi troppo corti o elenchi di codici piano)
+func detectResponseLanguage(text string) string {
+	itCount, enCount := 0, 0
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		word = strings.Trim(word, ".,;:!?()[]\"'")
+		if italianStopwords[word] {
+			itCount++
+		}
+		if englishStopwords[word] {
+			enCount++
+		}
+	}
+	if enCount >= 3 && enCount > itCount*2 {
+		return "en"
+	}
+	if itCount >= 3 && itCount > enCount*2 {
+		return "it"
+	}
+	return ""
+}
+
+// LanguageGuardApply verifica la lingua della risposta finale: al mismatch
+// registra l'evento di qualità e, con auto_translate, ritorna la
+// traduzione verso la lingua attesa. In ogni altro caso il testo passa
+// invariato.
+func LanguageGuardApply(config *Config, text string) string {
+	if !config.LanguageGuard.Enabled || text == "" {
+		return text
+	}
+
+	expected := config.Translation.SourceLang
+	if expected == "" {
+		expected = "it"
+	}
+
+	detected := detectResponseLanguage(text)
+	if detected == "" || detected == expected {
+		return text
+	}
+
+	langGuard.mu.Lock()
+	langGuard.mismatches++
+	count := langGuard.mismatches
+	langGuard.mu.Unlock()
+	log.Printf("LANG_MISMATCH: risposta in lingua '%s' (attesa '%s'), occorrenze=%d, text_len=%d",
+		detected, expected, count, len(text))
+
+	if !config.LanguageGuard.AutoTranslate {
+		return text
+	}
+
+	// Traduzione di ripiego verso la lingua attesa, con la lingua
+	// rilevata come sorgente (graceful: al fallimento passa l'originale)
+	translated, err := translateBetween(config, text, detected, expected)
+	if err != nil {
+		log.Printf("LANG_MISMATCH_TRANSLATE_ERROR: %v", err)
+		return text
+	}
+	langGuard.mu.Lock()
+	langGuard.translated++
+	langGuard.mu.Unlock()
+	log.Printf("LANG_MISMATCH_TRANSLATED: '%s' -> '%s', text_len=%d", detected, expected, len(text))
+	return translated
+}
+
+// LanguageMismatchStats ritorna i contatori della guardia (per gli alert)
+func LanguageMismatchStats() (mismatches, translated int) {
+	langGuard.mu.Lock()
+	defer langGuard.mu.Unlock()
+	return langGuard.mismatches, langGuard.translated
+}
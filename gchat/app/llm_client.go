@@ -1,9 +1,10 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -19,10 +20,10 @@ import (
 
 // Health check cache to avoid checking on every request
 type healthCheckCache struct {
-	isHealthy  bool
-	lastCheck  time.Time
-	mu         sync.RWMutex
-	ttl        time.Duration
+	isHealthy bool
+	lastCheck time.Time
+	mu        sync.RWMutex
+	ttl       time.Duration
 }
 
 var (
@@ -39,7 +40,11 @@ type ChatRequest struct {
 	UserID        string `json:"user_id,omitempty"`
 	CodiceFiscale string `json:"codice_fiscale,omitempty"`
 	Username      string `json:"username,omitempty"`
-	UOC           string `json:"uoc,omitempty"` // NUOVO: Unità Operativa Complessa
+	UOC           string `json:"uoc,omitempty"`      // NUOVO: Unità Operativa Complessa
+	Language      string `json:"language,omitempty"` // lingua preferita per la traduzione della risposta
+	// Contesto record GISA da deep-link (pagina stabilimento/piano)
+	ContextType string `json:"context_type,omitempty"`
+	ContextID   string `json:"context_id,omitempty"`
 }
 
 type ChatResponse struct {
@@ -49,6 +54,38 @@ type ChatResponse struct {
 	FullData    interface{}              `json:"full_data,omitempty"`
 	DataType    string                   `json:"data_type,omitempty"`
 	Suggestions []map[string]interface{} `json:"suggestions,omitempty"`
+	// Campi traduzione inline: se valorizzati, Message è tradotto e
+	// OriginalMessage contiene la risposta originale (toggle lato UI)
+	OriginalMessage string `json:"original_message,omitempty"`
+	TranslatedTo    string `json:"translated_to,omitempty"`
+	// Campi troncamento: se Truncated, il resto si recupera via
+	// GET /api/continuation/:handle
+	Truncated          bool   `json:"truncated,omitempty"`
+	ContinuationHandle string `json:"continuation_handle,omitempty"`
+	// FullDataHandle: in modalità banda ridotta full_data viene omesso e
+	// recuperato on demand via GET /api/full-data/:handle
+	FullDataHandle string `json:"full_data_handle,omitempty"`
+	// Curated: risposta servita dall'archivio FAQ del gateway, non dall'LLM
+	Curated bool `json:"curated,omitempty"`
+	// OfferEscalation: la UI propone l'invio della domanda al supporto
+	// (N fallback consecutivi nella conversazione)
+	OfferEscalation bool `json:"offer_escalation,omitempty"`
+	// Codice conversazione pronunciabile (GIAS-XXXX) e numero di turno,
+	// citabili nelle chiamate al supporto (vedi conversation_codes.go)
+	ConversationCode string `json:"conversation_code,omitempty"`
+	Turn             int    `json:"turn,omitempty"`
+	// RenderedHTML: partial server-side per il data_type (vedi partials.go)
+	RenderedHTML string `json:"rendered_html,omitempty"`
+	// Snapshot dati con cui è stata prodotta la risposta e avviso di
+	// staleness al cambio snapshot (vedi snapshot_version.go)
+	DataSnapshot    string `json:"data_snapshot,omitempty"`
+	SnapshotWarning string `json:"snapshot_warning,omitempty"`
+	// DeliveryID: ricevuta di consegna da confermare su POST /api/receipts
+	// (vedi delivery_receipts.go)
+	DeliveryID string `json:"delivery_id,omitempty"`
+	// SlowModeNotice: avviso visibile quando la risposta è stata
+	// rallentata dallo slow-mode progressivo (vedi slow_mode.go)
+	SlowModeNotice string `json:"slow_mode_notice,omitempty"`
 }
 
 // SSE Event structures for streaming
@@ -163,6 +200,10 @@ type NativeUserMetadata struct {
 	CodiceFiscale string `json:"codice_fiscale,omitempty"`
 	Username      string `json:"username,omitempty"`
 	UOC           string `json:"uoc,omitempty"`
+	// Purpose: finalità dichiarata dalla policy di minimizzazione metadati
+	Purpose string `json:"purpose,omitempty"`
+	// Workflow: metadati del percorso guidato attivo (vedi workflows.go)
+	Workflow map[string]interface{} `json:"workflow,omitempty"`
 }
 
 type NativeChatMessage struct {
@@ -229,6 +270,12 @@ func SendToLLMV1(message, sender, llmServerURL string, timeout int, context map[
 	if v, ok := context["uoc"].(string); ok {
 		meta.UOC = v
 	}
+	if v, ok := context["purpose"].(string); ok {
+		meta.Purpose = v
+	}
+	if v, ok := context["workflow"].(map[string]interface{}); ok {
+		meta.Workflow = v
+	}
 
 	chatMsg := NativeChatMessage{
 		Sender:   sender,
@@ -243,18 +290,43 @@ func SendToLLMV1(message, sender, llmServerURL string, timeout int, context map[
 
 	log.Printf("LLM_V1_SEND: JSON payload=%s", string(jsonData))
 
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	client := NewUpstreamClient("llm", time.Duration(timeout)*time.Second)
 
 	start := time.Now()
-	resp, err := client.Post(fullURL, "application/json", bytes.NewBuffer(jsonData))
-	elapsed := time.Since(start)
+	deadline := start.Add(time.Duration(timeout) * time.Second)
 
-	if err != nil {
-		log.Printf("LLM_V1_ERROR: HTTP failed - sender=%s, duration=%v, error=%v", sender, elapsed, err)
-		return nil, fmt.Errorf("error sending v1 request: %v", err)
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		httpReq, err := http.NewRequest("POST", fullURL, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("error creating v1 request: %v", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		ApplyUpstreamHeaders(httpReq, context)
+
+		resp, err = client.Do(httpReq)
+		if err != nil {
+			log.Printf("LLM_V1_ERROR: HTTP failed - sender=%s, duration=%v, error=%v", sender, time.Since(start), err)
+			return nil, fmt.Errorf("error sending v1 request: %v", err)
+		}
+
+		// Backend saturo con Retry-After: riaccoda entro il budget o propaga
+		// una risposta strutturata "occupato" (vedi retry_after.go)
+		wait, retry, busyErr := backendBusyRetry(resp, attempt, deadline)
+		if busyErr != nil {
+			resp.Body.Close()
+			log.Printf("LLM_V1_BUSY: sender=%s, status=%d, retry_after=%ds", sender, resp.StatusCode, busyErr.(*BackendBusyError).RetryAfter)
+			return nil, busyErr
+		}
+		if retry {
+			resp.Body.Close()
+			log.Printf("LLM_V1_RETRY_AFTER: sender=%s, status=%d, wait=%v", sender, resp.StatusCode, wait)
+			time.Sleep(wait)
+			continue
+		}
+		break
 	}
+	elapsed := time.Since(start)
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
@@ -274,6 +346,11 @@ func SendToLLMV1(message, sender, llmServerURL string, timeout int, context map[
 	}
 
 	log.Printf("LLM_V1_SUCCESS: sender=%s, intent=%s, duration=%v", sender, chatResp.Result.Intent, elapsed)
+	RecordIntentLatency(chatResp.Result.Intent, elapsed)
+
+	// Recorder di schema opt-in: campiona la struttura dei payload custom
+	SchemaObserve(chatResp.Result.Intent, chatResp.Result.Slots)
+
 	return &chatResp, nil
 }
 
@@ -301,6 +378,12 @@ func SendToLLMStreamV1(message, sender, llmServerURL string, timeout int, contex
 	if v, ok := context["uoc"].(string); ok {
 		meta.UOC = v
 	}
+	if v, ok := context["purpose"].(string); ok {
+		meta.Purpose = v
+	}
+	if v, ok := context["workflow"].(map[string]interface{}); ok {
+		meta.Workflow = v
+	}
 
 	chatMsg := NativeChatMessage{
 		Sender:   sender,
@@ -313,22 +396,42 @@ func SendToLLMStreamV1(message, sender, llmServerURL string, timeout int, contex
 		return fmt.Errorf("error marshaling v1 stream message: %v", err)
 	}
 
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
-
-	req, err := http.NewRequest("POST", fullURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("error creating v1 stream request: %v", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "text/event-stream")
-	req.Header.Set("Cache-Control", "no-cache")
+	client := NewUpstreamClient("llm", time.Duration(timeout)*time.Second)
 
 	start := time.Now()
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("error sending v1 stream request: %v", err)
+	deadline := start.Add(time.Duration(timeout) * time.Second)
+
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest("POST", fullURL, bytes.NewBuffer(jsonData))
+		if err != nil {
+			return fmt.Errorf("error creating v1 stream request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Cache-Control", "no-cache")
+		ApplyUpstreamHeaders(req, context)
+
+		resp, err = client.Do(req)
+		if err != nil {
+			return fmt.Errorf("error sending v1 stream request: %v", err)
+		}
+
+		// Backend saturo con Retry-After: nessun evento è ancora partito,
+		// quindi il retry entro budget è trasparente per il client
+		wait, retry, busyErr := backendBusyRetry(resp, attempt, deadline)
+		if busyErr != nil {
+			resp.Body.Close()
+			log.Printf("LLM_V1_STREAM_BUSY: sender=%s, status=%d, retry_after=%ds", sender, resp.StatusCode, busyErr.(*BackendBusyError).RetryAfter)
+			return busyErr
+		}
+		if retry {
+			resp.Body.Close()
+			log.Printf("LLM_V1_STREAM_RETRY_AFTER: sender=%s, status=%d, wait=%v", sender, resp.StatusCode, wait)
+			time.Sleep(wait)
+			continue
+		}
+		break
 	}
 	defer resp.Body.Close()
 
@@ -337,76 +440,243 @@ func SendToLLMStreamV1(message, sender, llmServerURL string, timeout int, contex
 		return fmt.Errorf("LLM server returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	// Parse SSE stream - same format as legacy but final event has "result" field
-	scanner := bufio.NewScanner(resp.Body)
-	var eventType string
-	var dataLines []string
-
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		if line == "" {
-			if len(dataLines) > 0 {
-				dataJSON := strings.Join(dataLines, "\n")
-
-				// Try to parse as V1 final event first
-				var finalEvent SSEFinalEventV1
-				if eventType == "final" {
-					if err := json.Unmarshal([]byte(dataJSON), &finalEvent); err == nil && finalEvent.Result.Text != "" {
-						// Convert V1 final to SSEEvent for downstream compatibility
-						// Put suggestions as metadata for JS to use
-						metaMap := make(map[string]interface{})
-						metaMap["intent"] = finalEvent.Result.Intent
-						if len(finalEvent.Result.Suggestions) > 0 {
-							suggsIface := make([]interface{}, len(finalEvent.Result.Suggestions))
-							for i, s := range finalEvent.Result.Suggestions {
-								suggsIface[i] = map[string]interface{}{"text": s.Text, "query": s.Query}
-							}
-							metaMap["suggestions"] = suggsIface
-						}
-						eventChan <- SSEEvent{
-							Type:      "final",
-							Timestamp: finalEvent.Timestamp,
-							Content:   finalEvent.Result.Text,
-							Metadata:  metaMap,
-						}
-						log.Printf("LLM_V1_STREAM_FINAL: intent=%s, text_len=%d", finalEvent.Result.Intent, len(finalEvent.Result.Text))
-						continue
+	// Parse SSE stream - same format as legacy but final event has "result"
+	// field. Parser spec-compliant: CRLF, "data:" senza spazio, eventi
+	// multi-riga oltre il buffer dello Scanner (vedi sse_parser.go)
+	parser := NewSSEParser(resp.Body, LoadConfig().SSEBatching.MaxEventBytes)
+
+	for {
+		parsed, perr := parser.Next()
+		if perr != nil {
+			if perr == io.EOF {
+				break
+			}
+			return fmt.Errorf("error reading v1 stream: %v", perr)
+		}
+
+		eventType := parsed.Type
+		dataJSON := parsed.Data
+
+		// Try to parse as V1 final event first
+		var finalEvent SSEFinalEventV1
+		if eventType == "final" {
+			if err := json.Unmarshal([]byte(dataJSON), &finalEvent); err == nil && finalEvent.Result.Text != "" {
+				// Convert V1 final to SSEEvent for downstream compatibility
+				// Put suggestions as metadata for JS to use
+				metaMap := make(map[string]interface{})
+				metaMap["intent"] = finalEvent.Result.Intent
+				if len(finalEvent.Result.Suggestions) > 0 {
+					// Dedup e ranking per click-through (vedi suggestions.go)
+					suggs := make([]map[string]interface{}, len(finalEvent.Result.Suggestions))
+					for i, s := range finalEvent.Result.Suggestions {
+						suggs[i] = map[string]interface{}{"text": s.Text, "query": s.Query}
+					}
+					suggs = RankSuggestions(LoadConfig(), suggs)
+					suggsIface := make([]interface{}, len(suggs))
+					for i, s := range suggs {
+						suggsIface[i] = s
 					}
+					metaMap["suggestions"] = suggsIface
 				}
-
-				// Fallback: parse as generic SSEEvent
-				var event SSEEvent
-				if err := json.Unmarshal([]byte(dataJSON), &event); err == nil {
-					if eventType != "" {
-						event.Type = eventType
+				// Contenuti rich anche sul percorso streaming, già sanitizzati
+				if raw, ok := finalEvent.Result.Slots["custom_html"].(string); ok && raw != "" {
+					metaMap["custom_html"] = SanitizeHTML(raw)
+				}
+				// Offerta di escalation dopo N fallback consecutivi
+				if EscalationObserveIntent(sender, finalEvent.Result.Intent) {
+					metaMap["offer_escalation"] = true
+				}
+				// Azione da confermare: token firmato nel metadata
+				if confirmation := ExtractPendingAction(LoadConfig(), sender, &finalEvent.Result); confirmation != nil {
+					metaMap["confirmation"] = confirmation
+				}
+				// Codice conversazione e turno anche in streaming
+				code, turn := ConversationRecordTurn(sender)
+				metaMap["conversation_code"] = code
+				metaMap["turn"] = turn
+				// Prompt dello step successivo del workflow guidato
+				if next, ok := context["workflow_next_prompt"].(string); ok && next != "" {
+					metaMap["workflow_next_prompt"] = next
+				}
+				// Snapshot dati e avviso di staleness al cambio snapshot
+				if snapshot, warning := SnapshotObserveTurn(sender); snapshot != "" {
+					metaMap["data_snapshot"] = snapshot
+					if warning != "" {
+						metaMap["snapshot_warning"] = warning
 					}
-					eventChan <- event
 				}
+				// Export del turno come trace OTel (vedi trace_export.go)
+				TraceRecordTurn(code, turn, sender, finalEvent.Result.Intent, start, time.Since(start), finalEvent.Result.Execution)
+				// Collasso dei paragrafi duplicati consecutivi
+				finalText, _ := CollapseDuplicateParts(finalEvent.Result.Text)
+				// Guardia lingua anche sul percorso streaming
+				finalText = LanguageGuardApply(LoadConfig(), finalText)
+				// Registra la risposta in attesa di ack (delivery receipt)
+				if deliveryID := DeliveryRegister(sender, finalText); deliveryID != "" {
+					metaMap["delivery_id"] = deliveryID
+				}
+				eventChan <- SSEEvent{
+					Type:      "final",
+					Timestamp: finalEvent.Timestamp,
+					Content:   finalText,
+					Metadata:  metaMap,
+				}
+				log.Printf("LLM_V1_STREAM_FINAL: intent=%s, text_len=%d", finalEvent.Result.Intent, len(finalEvent.Result.Text))
+				RecordIntentLatency(finalEvent.Result.Intent, time.Since(start))
+				TrackerRecordTurn(sender, message, finalEvent.Result.Intent, finalEvent.Result.Text)
+				// Contabilità costi anche sul canale streaming
+				ctxASL, _ := context["asl"].(string)
+				ctxUserID, _ := context["user_id"].(string)
+				CostRecordTurn(ctxASL, ctxUserID, extractBackendTokens(finalEvent.Result.Slots))
+				continue
 			}
-			eventType = ""
-			dataLines = nil
-			continue
 		}
 
-		if strings.HasPrefix(line, "event: ") {
-			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event: "))
-		} else if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimSpace(strings.TrimPrefix(line, "data: "))
-			dataLines = append(dataLines, data)
+		// Fallback: parse as generic SSEEvent
+		var event SSEEvent
+		if err := json.Unmarshal([]byte(dataJSON), &event); err == nil {
+			if eventType != "" {
+				event.Type = eventType
+			}
+			// Tipi fuori tassonomia in quarantena (vedi event_taxonomy.go)
+			eventChan <- QuarantineSSEEvent(event)
 		}
 	}
 
-	if err := scanner.Err(); err != nil && err != io.EOF {
-		return fmt.Errorf("error reading v1 stream: %v", err)
-	}
-
 	elapsed := time.Since(start)
 	log.Printf("LLM_V1_STREAM_COMPLETE: sender=%s, total_duration=%v", sender, elapsed)
 	close(eventChan)
 	return nil
 }
 
+// streamWithFallback esegue lo streaming V1 inoltrando gli eventi a eventChan.
+// Se lo stream fallisce prima di aver consegnato un evento "final" (connessione
+// interrotta a metà turno), ritenta in modo trasparente lo stesso messaggio via
+// SendToLLMV1 e consegna la risposta completa come singolo evento finale
+// marcato con metadata "fallback_mode": true.
+// Chiude sempre eventChan al termine.
+func streamWithFallback(message, sender, llmServerURL string, timeout int, context map[string]interface{}, eventChan chan SSEEvent) error {
+	config := LoadConfig()
+	backendChan := make(chan SSEEvent, sseChannelBuffer(config))
+	streamErr := make(chan error, 1)
+
+	go func() {
+		streamErr <- SendToLLMStreamV1(message, sender, llmServerURL, timeout, context, backendChan)
+	}()
+
+	finalSent := false
+	var err error
+
+forwardLoop:
+	for {
+		select {
+		case event, ok := <-backendChan:
+			if !ok {
+				// Canale chiuso dal backend: stream completato con successo
+				break forwardLoop
+			}
+			if event.Type == "final" {
+				finalSent = true
+			}
+			// Invio con timeout e policy per i client lenti (sse_channel.go)
+			if !sseEventSend(config, eventChan, event, sender) {
+				go drainSSEChannel(backendChan)
+				close(eventChan)
+				return fmt.Errorf("client SSE troppo lento, stream abbandonato")
+			}
+		case err = <-streamErr:
+			if err == nil {
+				// Successo: continua a drenare fino alla chiusura del canale
+				continue
+			}
+			// Errore: drena gli eventi già bufferizzati, poi esci
+		drainLoop:
+			for {
+				select {
+				case event, ok := <-backendChan:
+					if !ok {
+						break drainLoop
+					}
+					if event.Type == "final" {
+						finalSent = true
+					}
+					if !sseEventSend(config, eventChan, event, sender) {
+						go drainSSEChannel(backendChan)
+						close(eventChan)
+						return fmt.Errorf("client SSE troppo lento, stream abbandonato")
+					}
+				default:
+					break drainLoop
+				}
+			}
+			break forwardLoop
+		}
+	}
+
+	if err != nil && !finalSent {
+		// Backend saturo oltre il budget: evento "busy" strutturato al client,
+		// nessun fallback non-streaming (fallirebbe allo stesso modo)
+		var busy *BackendBusyError
+		if errors.As(err, &busy) {
+			log.Printf("CHAT_STREAM_BUSY: backend busy, notifying client - sender=%s, retry_after=%ds", sender, busy.RetryAfter)
+			sseEventSend(config, eventChan, SSEEvent{
+				Type:      "busy",
+				Timestamp: time.Now().UnixMilli(),
+				Message:   busy.BusyMessage(),
+				Metadata:  map[string]interface{}{"retry_after": busy.RetryAfter},
+			}, sender)
+			close(eventChan)
+			return busy
+		}
+
+		// Stream fallito a metà turno: retry trasparente via endpoint non-streaming
+		log.Printf("CHAT_STREAM_FALLBACK: stream failed before final event, retrying via non-streaming - sender=%s, error=%v", sender, err)
+
+		v1Resp, fbErr := SendToLLMV1(message, sender, llmServerURL, timeout, context)
+		if fbErr != nil {
+			log.Printf("CHAT_STREAM_FALLBACK_ERROR: non-streaming retry also failed - sender=%s, error=%v", sender, fbErr)
+			sseEventSend(config, eventChan, SSEEvent{
+				Type:      "error",
+				Timestamp: time.Now().UnixMilli(),
+				Error:     fmt.Sprintf("Error communicating with LLM server: %v", fbErr),
+			}, sender)
+			close(eventChan)
+			return fbErr
+		}
+
+		// Costruisci il singolo evento finale con la risposta completa
+		metaMap := make(map[string]interface{})
+		metaMap["intent"] = v1Resp.Result.Intent
+		metaMap["fallback_mode"] = true
+		if len(v1Resp.Result.Suggestions) > 0 {
+			suggsIface := make([]interface{}, len(v1Resp.Result.Suggestions))
+			for i, s := range v1Resp.Result.Suggestions {
+				suggsIface[i] = map[string]interface{}{"text": s.Text, "query": s.Query}
+			}
+			metaMap["suggestions"] = suggsIface
+		}
+		sseEventSend(config, eventChan, SSEEvent{
+			Type:      "final",
+			Timestamp: time.Now().UnixMilli(),
+			Content:   v1Resp.Result.Text,
+			Metadata:  metaMap,
+			IsFinal:   true,
+		}, sender)
+		log.Printf("CHAT_STREAM_FALLBACK_SUCCESS: delivered full answer via non-streaming - sender=%s, intent=%s, text_len=%d",
+			sender, v1Resp.Result.Intent, len(v1Resp.Result.Text))
+		close(eventChan)
+		return nil
+	}
+
+	if err != nil {
+		// Final già consegnato: segnala solo l'errore residuo nei log
+		log.Printf("CHAT_STREAM_FALLBACK_SKIP: stream error after final event delivered - sender=%s, error=%v", sender, err)
+	}
+
+	close(eventChan)
+	return err
+}
+
 func CheckLLMServerHealth(llmServerURL string, timeout int) error {
 	healthCache.mu.RLock()
 
@@ -429,11 +699,9 @@ func CheckLLMServerHealth(llmServerURL string, timeout int) error {
 	// Need to perform actual health check
 	log.Printf("LLM_HEALTH_CHECK: Performing actual check - url=%s", llmServerURL)
 
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
-	}
+	client := NewUpstreamClient("llm", time.Duration(timeout)*time.Second)
 
-	resp, err := client.Get(llmServerURL)
+	resp, err := upstreamGet(client, llmServerURL)
 	if err != nil {
 		log.Printf("LLM_HEALTH_ERROR: Cannot connect to LLM server - url=%s, error=%v", llmServerURL, err)
 
@@ -471,6 +739,9 @@ func CheckLLMServerHealth(llmServerURL string, timeout int) error {
 }
 
 func HandleChat(c *gin.Context) {
+	gaugeChatStarted()
+	defer gaugeChatFinished()
+
 	clientIP := c.ClientIP()
 	sessionID := c.GetHeader("X-Session-ID")
 	log.Printf("CHAT_REQUEST: client_ip=%s, session_id=%s", clientIP, sessionID)
@@ -478,10 +749,7 @@ func HandleChat(c *gin.Context) {
 	var req ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("CHAT_ERROR: Invalid JSON format - client_ip=%s, error=%v", clientIP, err)
-		c.JSON(http.StatusBadRequest, ChatResponse{
-			Status: "error",
-			Error:  "Invalid request format",
-		})
+		AbortWithError(c, ErrInvalidRequest, err.Error())
 		return
 	}
 
@@ -491,6 +759,56 @@ func HandleChat(c *gin.Context) {
 		req.Sender = "user"
 	}
 
+	// Informativa privacy: senza consenso alla versione corrente la chat
+	// è bloccata (vedi consent.go)
+	if !RequireConsent(c, config, req.UserID, req.CodiceFiscale, req.Sender) {
+		return
+	}
+
+	// Load shedding: sotto saturazione il traffico a bassa priorità viene
+	// rifiutato subito con un 503 esplicativo (vedi load_shedding.go)
+	if msg, shed := ShedChatRequest(config, &req); shed {
+		log.Printf("SHED_CHAT: client_ip=%s, sender=%s, authenticated=%t", clientIP, req.Sender, req.UserID != "" || req.CodiceFiscale != "")
+		c.Header("Retry-After", "30")
+		AbortWithError(c, ErrBackendUnavailable, msg)
+		return
+	}
+
+	// Anti-abuso: chiavi bannate e pattern di messaggi identici ripetuti
+	// vengono bloccati con un ban temporaneo (vedi abuse.go)
+	if remaining, banned := AbuseBanned(config, req.UserID, clientIP); banned {
+		c.Header("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+		AbortWithError(c, ErrRateLimited, "accesso sospeso temporaneamente per uso anomalo")
+		return
+	}
+	if AbuseObserveMessage(config, req.UserID, clientIP, req.Message) {
+		AbortWithError(c, ErrRateLimited, "accesso sospeso temporaneamente per uso anomalo")
+		return
+	}
+
+	// Modalità demo pubblica: rate limit per IP, identità sintetica e
+	// instradamento sul backend sandbox
+	if DemoModeActive(config) {
+		if !DemoAllow(clientIP) {
+			AbortWithError(c, ErrRateLimited, "limite richieste demo raggiunto, riprovare tra un minuto")
+			return
+		}
+		ApplyDemoMode(config, &req)
+	}
+
+	// Slow-mode progressivo: oltre la soglia soft la risposta viene
+	// rallentata con un avviso invece che rifiutata (vedi slow_mode.go)
+	slowDelay, slowNotice, slowBlock := SlowModeObserve(config, req.UserID, clientIP)
+	if slowBlock {
+		c.Header("Retry-After", "60")
+		AbortWithError(c, ErrRateLimited, "troppe richieste ravvicinate, riprovare tra un minuto")
+		return
+	}
+	if slowDelay > 0 {
+		log.Printf("CHAT_SLOW_MODE: client_ip=%s, sender=%s, delay=%v", clientIP, req.Sender, slowDelay)
+		time.Sleep(slowDelay)
+	}
+
 	log.Printf("CHAT_PROCESSING: client_ip=%s, sender=%s, message_length=%d, asl=%s, asl_id=%s, user_id=%s",
 		clientIP, req.Sender, len(req.Message), req.ASL, req.ASLID, req.UserID)
 
@@ -518,8 +836,9 @@ func HandleChat(c *gin.Context) {
 
 	// Prepare context for LLM server - prioritize asl_name (ASL) over asl_id
 	context := make(map[string]interface{})
-	if req.ASL != "" {
-		context["asl"] = req.ASL
+	if canonicalASL, ok := ResolveContextASL(req.ASL, req.UserID); ok {
+		context["asl"] = canonicalASL
+		req.ASL = canonicalASL
 	} else if req.ASLID != "" {
 		context["asl_id"] = req.ASLID
 	}
@@ -536,49 +855,238 @@ func HandleChat(c *gin.Context) {
 	if uoc != "" {
 		context["uoc"] = uoc
 	}
+	if ValidateRecordContext(req.ContextType, req.ContextID) {
+		// Contesto record GISA da deep-link: scopa le risposte sul record
+		context["context_type"] = req.ContextType
+		context["context_id"] = req.ContextID
+	}
+	// Risposte del questionario di ingresso (se compilato)
+	if intake := IntakeMetadata(c); intake != nil {
+		context["intake"] = intake
+	}
+
+	// Policy di minimizzazione dei metadati upstream (vedi metadata_policy.go)
+	ApplyMetadataPolicy(config, context)
+
+	// Normalizzazione pre-flight del testo (NFC, caratteri di controllo,
+	// emoji); l'originale resta nel contesto per display e cronologia
+	if normalized, changed := NormalizeMessageText(config, req.Message); changed {
+		context["original_message"] = req.Message
+		req.Message = normalized
+	}
+
+	// Preprocessing della domanda (espansione sigle, refusi); l'originale
+	// resta nel contesto per la vista debug
+	if processed, changed := PreprocessMessage(config, req.Message); changed {
+		if _, ok := context["original_message"]; !ok {
+			context["original_message"] = req.Message
+		}
+		req.Message = processed
+	}
+
+	// Workflow guidato attivo: la risposta riempie lo step corrente e la
+	// domanda composta dal template va al backend con i metadati del
+	// workflow (vedi workflows.go)
+	workflowQuery, workflowMeta, workflowNext, workflowActive := WorkflowObserveAnswer(req.Sender, req.Message)
+	if workflowActive {
+		log.Printf("CHAT_WORKFLOW: sender=%s, workflow=%v, query=%s", req.Sender, workflowMeta["workflow_id"], workflowQuery)
+		context["workflow"] = workflowMeta
+		req.Message = workflowQuery
+	}
+
+	// FAQ curate: i match esatti/ad alta confidenza rispondono localmente
+	// senza scomodare l'LLM
+	if entry, score, ok := MatchFAQ(req.Message); ok {
+		log.Printf("CHAT_FAQ_HIT: client_ip=%s, sender=%s, faq=%s, score=%.2f", clientIP, req.Sender, entry.ID, score)
+		MeterChatTurn(req.ASL, 0)
+		SessionRecordTurn(c, req.Sender)
+		c.JSON(http.StatusOK, ChatResponse{
+			Message: entry.Answer,
+			Status:  "success",
+			Curated: true,
+		})
+		return
+	}
 
 	// Check LLM server health before sending message
 	if err := CheckLLMServerHealth(config.LLMServer.URL, config.LLMServer.Timeout); err != nil {
 		log.Printf("CHAT_ERROR: LLM server health check failed - client_ip=%s, sender=%s, error=%v", clientIP, req.Sender, err)
-		c.JSON(http.StatusServiceUnavailable, ChatResponse{
-			Status: "error",
-			Error:  fmt.Sprintf("LLM server service unavailable: %v", err),
-		})
+		AbortWithError(c, ErrBackendUnavailable, err.Error())
 		return
 	}
 
 	start := time.Now()
 
-	v1Resp, err := SendToLLMV1(req.Message, req.Sender, config.LLMServer.URL, config.LLMServer.Timeout, context)
+	// Canary routing: una quota di conversazioni (sticky per sender) va
+	// sulla nuova versione del backend (vedi canary.go)
+	backendURL, variant := CanaryRoute(config, req.Sender)
+
+	// Timeout per intent: override risolto con una parse rapida, il
+	// default resta llm_server.timeout (vedi intent_timeouts.go)
+	chatTimeout := ResolveIntentTimeout(config, req.Message, context, config.LLMServer.Timeout)
+
+	// Coalescing: richieste identiche in volo condividono una sola chiamata backend
+	coalesceKey := CoalesceKey(req.Sender, req.Message, c.GetHeader("X-Idempotency-Key"))
+	v1Resp, err := CoalescedSendToLLMV1(coalesceKey, req.Message, req.Sender, backendURL, chatTimeout, context)
 	totalDuration := time.Since(start)
 
 	if err != nil {
+		// Backend saturo oltre il budget: risposta strutturata "occupato"
+		// con l'indicazione di quando riprovare (vedi retry_after.go)
+		var busy *BackendBusyError
+		if errors.As(err, &busy) {
+			log.Printf("CHAT_BUSY: client_ip=%s, sender=%s, retry_after=%ds", clientIP, req.Sender, busy.RetryAfter)
+			c.Header("Retry-After", strconv.Itoa(busy.RetryAfter))
+			AbortWithError(c, ErrRateLimited, busy.BusyMessage())
+			return
+		}
 		log.Printf("CHAT_ERROR: LLM failed - client_ip=%s, sender=%s, duration=%v, error=%v", clientIP, req.Sender, totalDuration, err)
-		c.JSON(http.StatusInternalServerError, ChatResponse{
-			Status: "error",
-			Error:  fmt.Sprintf("Error communicating with LLM server: %v", err),
-		})
+		AbortWithError(c, ErrBackendError, err.Error())
 		return
 	}
 
-	// Converti suggestions V1 → []map[string]interface{}
+	// Converti suggestions V1 → []map[string]interface{}, poi dedup e
+	// ranking per click-through (vedi suggestions.go)
 	var suggestions []map[string]interface{}
 	for _, s := range v1Resp.Result.Suggestions {
 		suggestions = append(suggestions, map[string]interface{}{"text": s.Text, "query": s.Query})
 	}
+	suggestions = RankSuggestions(config, suggestions)
 
 	log.Printf("CHAT_SUCCESS: client_ip=%s, sender=%s, intent=%s, response_length=%d, duration=%v",
 		clientIP, req.Sender, v1Resp.Result.Intent, len(v1Resp.Result.Text), totalDuration)
 
-	c.JSON(http.StatusOK, ChatResponse{
-		Message:     v1Resp.Result.Text,
+	// Metering per-ASL: registra il turno con eventuali token riportati dal backend
+	MeterChatTurn(req.ASL, extractBackendTokens(v1Resp.Result.Slots))
+
+	// Contabilità costi per utente/ASL/giorno (vedi costs.go)
+	CostRecordTurn(req.ASL, req.UserID, extractBackendTokens(v1Resp.Result.Slots))
+
+	// Tracker conversazione per l'aggancio read-only dal debug
+	TrackerRecordTurn(req.Sender, req.Message, v1Resp.Result.Intent, v1Resp.Result.Text)
+
+	// Analytics di sessione: collega il turno alla sessione del cookie
+	// e tagga la variante di backend servita (stable/canary)
+	SessionRecordTurn(c, req.Sender)
+	SessionTagVariant(c, variant)
+
+	// Titolo automatico della conversazione alla prima domanda
+	AutoTitleConversation(req.Sender, req.Message)
+
+	// Rileva eventuali job asincroni avviati dal backend (slot job_id)
+	JobObserveResult(req.Sender, &v1Resp.Result)
+
+	// Collasso dei paragrafi duplicati consecutivi (difetto noto backend)
+	responseText, _ := CollapseDuplicateParts(v1Resp.Result.Text)
+
+	// Guardia lingua: mismatch loggato ed eventuale traduzione di ripiego
+	responseText = LanguageGuardApply(config, responseText)
+
+	chatResp := ChatResponse{
+		Message:     responseText,
 		Status:      "success",
 		Suggestions: suggestions,
-	})
+	}
+
+	// Prompt dello step successivo del workflow in coda alla risposta
+	if workflowActive && workflowNext != "" {
+		chatResp.Message += "\n\n" + workflowNext
+	}
+
+	// Snapshot dati di riferimento e avviso di staleness se la
+	// conversazione riprende dopo un cambio snapshot
+	chatResp.DataSnapshot, chatResp.SnapshotWarning = SnapshotObserveTurn(req.Sender)
+
+	// Ricevuta di consegna: il client conferma su POST /api/receipts
+	chatResp.DeliveryID = DeliveryRegister(req.Sender, chatResp.Message)
+
+	// Avviso slow-mode se la risposta è stata rallentata
+	chatResp.SlowModeNotice = slowNotice
+
+	// Codice pronunciabile e numero di turno per i riferimenti al supporto
+	chatResp.ConversationCode, chatResp.Turn = ConversationRecordTurn(req.Sender)
+
+	// Export del turno come trace OTel consultabile da /api/debug/trace
+	TraceRecordTurn(chatResp.ConversationCode, chatResp.Turn, req.Sender, v1Resp.Result.Intent, start, totalDuration, v1Resp.Result.Execution)
+
+	// Dopo N fallback consecutivi la UI propone l'escalation al supporto
+	if EscalationObserveIntent(req.Sender, v1Resp.Result.Intent) {
+		chatResp.OfferEscalation = true
+	}
+
+	// Contenuti rich del backend (custom_html, cards): sanitizzazione a
+	// whitelist prima di esporli al browser
+	ApplyRichContent(&chatResp, v1Resp.Result.Slots)
+
+	// Form dinamico richiesto dal backend (payload custom "form"):
+	// schema validato e registrato, partial HTML più JSON per la SPA
+	if spec := ExtractForm(req.Sender, v1Resp.Result.Slots); spec != nil {
+		chatResp.FullData = gin.H{"form": spec, "html": RenderFormHTML(spec)}
+		chatResp.DataType = "form"
+	}
+
+	// Azione che modifica dati: il gateway la trattiene e chiede conferma
+	// esplicita con un token firmato (vedi confirmation.go)
+	if confirmation := ExtractPendingAction(config, req.Sender, &v1Resp.Result); confirmation != nil {
+		chatResp.FullData = confirmation
+		chatResp.DataType = "confirmation"
+	}
+
+	// Dati strutturati dichiarati dal backend negli slot (data_type/full_data)
+	if chatResp.DataType == "" {
+		if dataType, ok := v1Resp.Result.Slots["data_type"].(string); ok && dataType != "" {
+			chatResp.DataType = dataType
+			chatResp.FullData = v1Resp.Result.Slots["full_data"]
+		}
+	}
+
+	// Partial server-side per il data_type, con fallback tabellare generico
+	if html, ok := RenderPartial(chatResp.DataType, chatResp.FullData); ok {
+		chatResp.RenderedHTML = html
+	}
+
+	// Traduzione inline opzionale per utenti non italofoni: lavora sul
+	// messaggio già elaborato (deduplica, language guard, prompt workflow),
+	// non sul testo grezzo del backend
+	if translationNeeded(config, req.Language) {
+		if translated, terr := TranslateText(config, chatResp.Message, req.Language); terr == nil {
+			chatResp.OriginalMessage = chatResp.Message
+			chatResp.Message = translated
+			chatResp.TranslatedTo = req.Language
+		} else {
+			log.Printf("CHAT_TRANSLATE_SKIP: traduzione fallita, risposta originale - error=%v", terr)
+		}
+	}
+
+	// Troncamento lato server delle risposte oltre soglia (PC datati)
+	if visible, handle, truncated := TruncateResponse(chatResp.Message, responseMaxChars(config)); truncated {
+		chatResp.Message = visible
+		chatResp.Truncated = true
+		chatResp.ContinuationHandle = handle
+	}
+
+	// Watermark demo sulla risposta
+	if DemoModeActive(config) {
+		chatResp.Message = DemoWatermark(config, chatResp.Message)
+	}
+
+	// Banda ridotta: full_data viene messo da parte e recuperato on demand
+	if chatResp.FullData != nil && LowBandwidthRequested(c) {
+		if handle := RegisterFullData(chatResp.FullData); handle != "" {
+			chatResp.FullData = nil
+			chatResp.FullDataHandle = handle
+			log.Printf("LOWBW_FULLDATA_DEFERRED: handle=%s, sender=%s", handle, req.Sender)
+		}
+	}
+
+	c.JSON(http.StatusOK, chatResp)
 }
 
 // HandleChatStream handles streaming chat requests with SSE
 func HandleChatStream(c *gin.Context) {
+	gaugeChatStarted()
+	defer gaugeChatFinished()
+
 	clientIP := c.ClientIP()
 	sessionID := c.GetHeader("X-Session-ID")
 	log.Printf("CHAT_STREAM_REQUEST: client_ip=%s, session_id=%s", clientIP, sessionID)
@@ -586,10 +1094,7 @@ func HandleChatStream(c *gin.Context) {
 	var req ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("CHAT_STREAM_ERROR: Invalid JSON format - client_ip=%s, error=%v", clientIP, err)
-		c.JSON(http.StatusBadRequest, ChatResponse{
-			Status: "error",
-			Error:  "Invalid request format",
-		})
+		AbortWithError(c, ErrInvalidRequest, err.Error())
 		return
 	}
 
@@ -599,6 +1104,50 @@ func HandleChatStream(c *gin.Context) {
 		req.Sender = "user"
 	}
 
+	// Informativa privacy: stessa politica del canale non-streaming
+	if !RequireConsent(c, config, req.UserID, req.CodiceFiscale, req.Sender) {
+		return
+	}
+
+	// Load shedding: stessa politica del canale non-streaming
+	if msg, shed := ShedChatRequest(config, &req); shed {
+		log.Printf("SHED_CHAT_STREAM: client_ip=%s, sender=%s, authenticated=%t", clientIP, req.Sender, req.UserID != "" || req.CodiceFiscale != "")
+		c.Header("Retry-After", "30")
+		AbortWithError(c, ErrBackendUnavailable, msg)
+		return
+	}
+
+	// Anti-abuso: stessa politica del canale non-streaming (vedi abuse.go)
+	if remaining, banned := AbuseBanned(config, req.UserID, clientIP); banned {
+		c.Header("Retry-After", strconv.Itoa(int(remaining.Seconds())+1))
+		AbortWithError(c, ErrRateLimited, "accesso sospeso temporaneamente per uso anomalo")
+		return
+	}
+	if AbuseObserveMessage(config, req.UserID, clientIP, req.Message) {
+		AbortWithError(c, ErrRateLimited, "accesso sospeso temporaneamente per uso anomalo")
+		return
+	}
+
+	// Modalità demo pubblica: rate limit per IP, identità sintetica e
+	// instradamento sul backend sandbox
+	if DemoModeActive(config) {
+		if !DemoAllow(clientIP) {
+			AbortWithError(c, ErrRateLimited, "limite richieste demo raggiunto, riprovare tra un minuto")
+			return
+		}
+		ApplyDemoMode(config, &req)
+	}
+
+	// Slow-mode progressivo: stessa politica del canale non-streaming; il
+	// ritardo viene applicato prima della chiamata backend e l'avviso
+	// arriva subito come evento "slow_mode"
+	slowDelay, slowNotice, slowBlock := SlowModeObserve(config, req.UserID, clientIP)
+	if slowBlock {
+		c.Header("Retry-After", "60")
+		AbortWithError(c, ErrRateLimited, "troppe richieste ravvicinate, riprovare tra un minuto")
+		return
+	}
+
 	log.Printf("CHAT_STREAM_PROCESSING: client_ip=%s, sender=%s, message_length=%d, asl=%s, asl_id=%s, user_id=%s",
 		clientIP, req.Sender, len(req.Message), req.ASL, req.ASLID, req.UserID)
 
@@ -626,8 +1175,9 @@ func HandleChatStream(c *gin.Context) {
 
 	// Prepare context for LLM server
 	context := make(map[string]interface{})
-	if req.ASL != "" {
-		context["asl"] = req.ASL
+	if canonicalASL, ok := ResolveContextASL(req.ASL, req.UserID); ok {
+		context["asl"] = canonicalASL
+		req.ASL = canonicalASL
 	} else if req.ASLID != "" {
 		context["asl_id"] = req.ASLID
 	}
@@ -644,14 +1194,71 @@ func HandleChatStream(c *gin.Context) {
 	if uoc != "" {
 		context["uoc"] = uoc
 	}
+	if ValidateRecordContext(req.ContextType, req.ContextID) {
+		// Contesto record GISA da deep-link: scopa le risposte sul record
+		context["context_type"] = req.ContextType
+		context["context_id"] = req.ContextID
+	}
+	// Risposte del questionario di ingresso (se compilato)
+	if intake := IntakeMetadata(c); intake != nil {
+		context["intake"] = intake
+	}
+
+	// Policy di minimizzazione dei metadati upstream (vedi metadata_policy.go)
+	ApplyMetadataPolicy(config, context)
+
+	// Normalizzazione pre-flight del testo (stessa pipeline del non-streaming)
+	if normalized, changed := NormalizeMessageText(config, req.Message); changed {
+		context["original_message"] = req.Message
+		req.Message = normalized
+	}
+
+	// Preprocessing della domanda (espansione sigle, refusi)
+	if processed, changed := PreprocessMessage(config, req.Message); changed {
+		if _, ok := context["original_message"]; !ok {
+			context["original_message"] = req.Message
+		}
+		req.Message = processed
+	}
+
+	// Workflow guidato attivo: stessa pipeline del non-streaming; il
+	// prompt dello step successivo arriva nel metadata dell'evento final
+	if workflowQuery, workflowMeta, workflowNext, workflowActive := WorkflowObserveAnswer(req.Sender, req.Message); workflowActive {
+		log.Printf("CHAT_STREAM_WORKFLOW: sender=%s, workflow=%v", req.Sender, workflowMeta["workflow_id"])
+		context["workflow"] = workflowMeta
+		if workflowNext != "" {
+			context["workflow_next_prompt"] = workflowNext
+		}
+		req.Message = workflowQuery
+	}
+
+	// FAQ curate: risposta locale immediata come unico evento final
+	if entry, score, ok := MatchFAQ(req.Message); ok {
+		log.Printf("CHAT_STREAM_FAQ_HIT: client_ip=%s, sender=%s, faq=%s, score=%.2f", clientIP, req.Sender, entry.ID, score)
+		MeterChatTurn(req.ASL, 0)
+		SessionRecordTurn(c, req.Sender)
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		finalEvent := SSEEvent{
+			Type:      "final",
+			Timestamp: time.Now().UnixMilli(),
+			Content:   entry.Answer,
+			Metadata:  map[string]interface{}{"curated": true},
+		}
+		if eventJSON, jerr := json.Marshal(finalEvent); jerr == nil {
+			fmt.Fprintf(c.Writer, "event: final\ndata: %s\n\n", string(eventJSON))
+			if flusher, fok := c.Writer.(http.Flusher); fok {
+				flusher.Flush()
+			}
+		}
+		return
+	}
 
 	// Check LLM server health
 	if err := CheckLLMServerHealth(config.LLMServer.URL, config.LLMServer.Timeout); err != nil {
 		log.Printf("CHAT_STREAM_ERROR: LLM server health check failed - client_ip=%s, sender=%s, error=%v", clientIP, req.Sender, err)
-		c.JSON(http.StatusServiceUnavailable, ChatResponse{
-			Status: "error",
-			Error:  fmt.Sprintf("LLM server service unavailable: %v", err),
-		})
+		AbortWithError(c, ErrBackendUnavailable, err.Error())
 		return
 	}
 
@@ -661,47 +1268,186 @@ func HandleChatStream(c *gin.Context) {
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
 
-	// Create event channel
-	eventChan := make(chan SSEEvent, 10)
+	// Create event channel (buffer e policy configurabili, vedi sse_channel.go)
+	eventChan := make(chan SSEEvent, sseChannelBuffer(config))
+
+	// Canary routing: stessa logica sticky del percorso non-streaming
+	backendURL, variant := CanaryRoute(config, req.Sender)
 
-	// Start streaming in goroutine
+	// Timeout per intent anche in streaming (vedi intent_timeouts.go)
+	chatTimeout := ResolveIntentTimeout(config, req.Message, context, config.LLMServer.Timeout)
+
+	// Start streaming in goroutine (con fallback automatico su non-streaming)
 	go func() {
+		// Ritardo artificiale dello slow-mode prima della chiamata backend
+		if slowDelay > 0 {
+			log.Printf("CHAT_STREAM_SLOW_MODE: client_ip=%s, sender=%s, delay=%v", clientIP, req.Sender, slowDelay)
+			time.Sleep(slowDelay)
+		}
 		start := time.Now()
-		err := SendToLLMStreamV1(req.Message, req.Sender, config.LLMServer.URL, config.LLMServer.Timeout, context, eventChan)
+		err := streamWithFallback(req.Message, req.Sender, backendURL, chatTimeout, context, eventChan)
 		totalDuration := time.Since(start)
 
 		if err != nil {
 			log.Printf("CHAT_STREAM_ERROR: LLM server communication failed - client_ip=%s, sender=%s, duration=%v, error=%v", clientIP, req.Sender, totalDuration, err)
-			// Send error event
-			eventChan <- SSEEvent{
-				Type:      "error",
-				Timestamp: time.Now().UnixMilli(),
-				Error:     fmt.Sprintf("Error communicating with LLM server: %v", err),
-			}
 		} else {
 			log.Printf("CHAT_STREAM_SUCCESS: client_ip=%s, sender=%s, total_duration=%v", clientIP, req.Sender, totalDuration)
 		}
+
+		// Metering per-ASL: registra turno e secondi di streaming consumati
+		MeterChatTurn(req.ASL, 0)
+		MeterStreamedSeconds(req.ASL, totalDuration.Seconds())
 	}()
 
 	// Stream events to client
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
 		log.Printf("CHAT_STREAM_ERROR: Streaming not supported - client_ip=%s", clientIP)
-		c.JSON(http.StatusInternalServerError, ChatResponse{
-			Status: "error",
-			Error:  "Streaming not supported",
-		})
+		AbortWithError(c, ErrStreamingUnsupported, "")
 		return
 	}
 
+	// Misura la qualità dello stream (TTFE, gap tra eventi, esito)
+	recorder := newStreamRecorder()
+	serverError := false
+
+	// In banda ridotta i metadata degli eventi intermedi vengono omessi
+	lowBandwidth := LowBandwidthRequested(c)
+
+	// Registra lo stream nel registro supervisore (live-view)
+	// Analytics di sessione: collega il turno alla sessione del cookie
+	// e tagga la variante di backend servita (stable/canary)
+	SessionRecordTurn(c, req.Sender)
+	SessionTagVariant(c, variant)
+
+	// Titolo automatico della conversazione alla prima domanda
+	AutoTitleConversation(req.Sender, req.Message)
+
+	SupervisorStreamStarted(req.Sender)
+	defer SupervisorStreamEnded(req.Sender)
+
+	// Avvisa la UI se la sessione sta per scadere (evento session_expiring)
+	if remaining := SessionRemainingSeconds(c); remaining >= 0 && remaining < SessionExpiryWarning {
+		warnEvent := SSEEvent{
+			Type:      "session_expiring",
+			Timestamp: time.Now().UnixMilli(),
+			Metadata:  map[string]interface{}{"expires_in_seconds": remaining},
+		}
+		if warnJSON, werr := json.Marshal(warnEvent); werr == nil {
+			fmt.Fprintf(c.Writer, "event: session_expiring\ndata: %s\n\n", string(warnJSON))
+			flusher.Flush()
+			log.Printf("CHAT_STREAM_SESSION_WARNING: sessione in scadenza tra %ds - sender=%s", remaining, req.Sender)
+		}
+	}
+
+	// Avviso slow-mode immediato, prima del ritardo artificiale
+	if slowNotice != "" {
+		slowEvent := SSEEvent{
+			Type:      "slow_mode",
+			Timestamp: time.Now().UnixMilli(),
+			Content:   slowNotice,
+			Metadata:  map[string]interface{}{"delay_ms": slowDelay.Milliseconds()},
+		}
+		if slowJSON, serr := json.Marshal(slowEvent); serr == nil {
+			fmt.Fprintf(c.Writer, "event: slow_mode\ndata: %s\n\n", string(slowJSON))
+			flusher.Flush()
+		}
+	}
+
+	// Ripropone le risposte mai confermate dal client (tab chiuso a metà
+	// streaming): evento "redelivery" con lo stesso delivery_id
+	for _, record := range UndeliveredFor(req.Sender) {
+		redeliveryEvent := SSEEvent{
+			Type:      "redelivery",
+			Timestamp: time.Now().UnixMilli(),
+			Content:   record.Text,
+			Metadata:  map[string]interface{}{"delivery_id": record.ID, "created_at": record.CreatedAt.Unix()},
+		}
+		if redeliveryJSON, rerr := json.Marshal(redeliveryEvent); rerr == nil {
+			fmt.Fprintf(c.Writer, "event: redelivery\ndata: %s\n\n", string(redeliveryJSON))
+			flusher.Flush()
+			log.Printf("CHAT_STREAM_REDELIVERY: riproposta risposta %s - sender=%s", record.ID, req.Sender)
+		}
+	}
+
+	// Consegna sul canale live le notifiche accodate mentre l'utente era
+	// offline (evento "notification", vedi notifications.go)
+	for _, n := range PendingNotifications(req.Sender) {
+		notifEvent := SSEEvent{
+			Type:      "notification",
+			Timestamp: time.Now().UnixMilli(),
+			Message:   n.Title,
+			Metadata:  map[string]interface{}{"id": n.ID, "kind": n.Kind, "body": n.Body, "job_id": n.JobID},
+		}
+		if notifJSON, nerr := json.Marshal(notifEvent); nerr == nil {
+			fmt.Fprintf(c.Writer, "event: notification\ndata: %s\n\n", string(notifJSON))
+			flusher.Flush()
+			log.Printf("CHAT_STREAM_NOTIFICATION: consegnata notifica %s - sender=%s, kind=%s", n.ID, req.Sender, n.Kind)
+		}
+	}
+
+	// Micro-batching SSE opzionale (flush a soglia di tempo o di byte)
+	batcher := newSSEBatcher(c.Writer, flusher, config)
+
 	c.Stream(func(w io.Writer) bool {
-		event, ok := <-eventChan
+		var event SSEEvent
+		var ok bool
+		if batcher != nil {
+			// Flush a tempo: se non arrivano eventi entro la finestra,
+			// scarica quanto accumulato finora
+			select {
+			case event, ok = <-eventChan:
+			case <-time.After(batcher.MaxDelay()):
+				batcher.Flush()
+				return true
+			}
+		} else {
+			event, ok = <-eventChan
+		}
 		if !ok {
 			// Channel closed, end stream
+			if batcher != nil {
+				batcher.Close()
+			}
 			log.Printf("CHAT_STREAM_CHANNEL_CLOSED: client_ip=%s, sender=%s", clientIP, req.Sender)
 			return false
 		}
 
+		if event.Type == "error" {
+			serverError = true
+		}
+
+		// Rileva e registra eventi di job asincroni (job_started/job_completed)
+		JobObserveEvent(req.Sender, event)
+
+		// Mirror verso eventuali supervisori agganciati; se supervisionato
+		// l'evento viene marcato per il banner di consenso lato utente
+		SupervisorObserveEvent(req.Sender, &event)
+
+		// Traduzione inline dell'evento finale per utenti non italofoni
+		if event.Type == "final" && event.Content != "" && translationNeeded(config, req.Language) {
+			if translated, terr := TranslateText(config, event.Content, req.Language); terr == nil {
+				if event.Metadata == nil {
+					event.Metadata = make(map[string]interface{})
+				}
+				event.Metadata["original_message"] = event.Content
+				event.Metadata["translated_to"] = req.Language
+				event.Content = translated
+			} else {
+				log.Printf("CHAT_STREAM_TRANSLATE_SKIP: traduzione fallita - error=%v", terr)
+			}
+		}
+
+		// Watermark demo sull'evento finale
+		if event.Type == "final" && DemoModeActive(config) {
+			event.Content = DemoWatermark(config, event.Content)
+		}
+
+		// Banda ridotta: solo l'evento finale conserva i metadata
+		if lowBandwidth && event.Type != "final" && event.Type != "error" {
+			event.Metadata = nil
+		}
+
 		// Format SSE event
 		eventJSON, err := json.Marshal(event)
 		if err != nil {
@@ -710,24 +1456,59 @@ func HandleChatStream(c *gin.Context) {
 		}
 
 		// Write SSE formatted event
-		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, string(eventJSON))
+		if batcher != nil {
+			// Accumula; final/error e soglia byte forzano il flush
+			batcher.Add(event.Type, eventJSON)
+		} else {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, string(eventJSON))
 
-		// CRITICAL: Flush buffer immediately to send event to client
-		flusher.Flush()
+			// CRITICAL: Flush buffer immediately to send event to client
+			flusher.Flush()
 
-		// Log event transmission
-		log.Printf("CHAT_STREAM_EVENT_SENT: client_ip=%s, sender=%s, event_type=%s, data_length=%d",
-			clientIP, req.Sender, event.Type, len(eventJSON))
+			// Log event transmission
+			log.Printf("CHAT_STREAM_EVENT_SENT: client_ip=%s, sender=%s, event_type=%s, data_length=%d",
+				clientIP, req.Sender, event.Type, len(eventJSON))
+		}
+		recorder.RecordEvent()
 
 		// Continue streaming until channel is closed
 		// Don't close on "final" - let the backend close the channel naturally
 		return true
 	})
+
+	// Classifica l'esito dello stream per le metriche di qualità
+	clientGone := c.Request.Context().Err() != nil
+	recorder.Finish(clientGone, serverError)
 }
 
 func HandlePredefinedQuestions(c *gin.Context) {
 	config := LoadConfig()
 
+	// ETag calcolato dal contenuto delle domande: il frontend le richiede a
+	// ogni caricamento pagina, con If-None-Match rispondiamo 304 senza body
+	payload, err := json.Marshal(config.PredefinedQuestions)
+	if err != nil {
+		AbortWithError(c, ErrInternal, err.Error())
+		return
+	}
+	etag := fmt.Sprintf("\"%x\"", sha256.Sum256(payload))
+
+	maxAge := config.HTTPCache.PredefinedQuestionsMaxAge
+	if maxAge <= 0 {
+		maxAge = 300
+	}
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	c.Header("ETag", etag)
+	if info, serr := os.Stat("config/config.json"); serr == nil {
+		c.Header("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+
+	if c.GetHeader("If-None-Match") == etag {
+		log.Printf("PREDEFINED_QUESTIONS_NOT_MODIFIED: client_ip=%s", c.ClientIP())
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	log.Printf("PREDEFINED_QUESTIONS_REQUEST: client_ip=%s", c.ClientIP())
 
 	c.JSON(http.StatusOK, gin.H{
@@ -744,37 +1525,80 @@ func ProxyChatLogAPI(c *gin.Context, llmServerURL string, timeout int) {
 	// Find "/api/chat-log/" in the path and use everything from there
 	apiIdx := strings.Index(originalPath, "/api/chat-log/")
 	if apiIdx == -1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API path"})
+		AbortWithError(c, ErrInvalidParameter, "percorso API chat-log non valido")
 		return
 	}
 	apiPath := originalPath[apiIdx:]
 	backendURL := llmServerURL + apiPath
-	if c.Request.URL.RawQuery != "" {
-		backendURL += "?" + c.Request.URL.RawQuery
+
+	// Policy dichiarativa sui parametri: page size e intervalli di date
+	// limitati, filtri vietati rimossi (vedi chatlog_query_policy.go)
+	query, adjustments := SanitizeChatLogQuery(LoadConfig(), c.Request.URL.Query())
+	if len(adjustments) > 0 {
+		log.Printf("CHATLOG_PROXY_SANITIZED: path=%s, %s", apiPath, strings.Join(adjustments, "; "))
+	}
+	if encoded := query.Encode(); encoded != "" {
+		backendURL += "?" + encoded
 	}
 
 	log.Printf("CHATLOG_PROXY: %s -> %s", originalPath, backendURL)
 
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
+	client := NewUpstreamClient("llm", time.Duration(timeout)*time.Second)
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), "GET", backendURL, nil)
+	if err != nil {
+		AbortWithError(c, ErrInternal, err.Error())
+		return
 	}
+	ApplyUpstreamHeaders(req, nil)
 
-	resp, err := client.Get(backendURL)
+	resp, err := client.Do(req)
 	if err != nil {
 		log.Printf("CHATLOG_PROXY_ERROR: url=%s, error=%v", backendURL, err)
-		c.JSON(http.StatusBadGateway, gin.H{"error": "Backend not available"})
+		AbortWithError(c, ErrBackendUnavailable, err.Error())
 		return
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	// La lista conversazioni viene arricchita con i titoli noti al gateway
+	if strings.Contains(apiPath, "user-conversations") {
+		proxyConversationListWithTitles(c, resp, backendURL)
+		return
+	}
+
+	// Propaga status e header rilevanti (inclusi Content-Disposition per gli
+	// export e Transfer-Encoding chunked/ndjson per risposte streaming)
+	for _, header := range []string{"Content-Type", "Content-Length", "Content-Disposition", "Cache-Control", "Last-Modified", "ETag"} {
+		if v := resp.Header.Get(header); v != "" {
+			c.Header(header, v)
+		}
+	}
+	c.Status(resp.StatusCode)
+
+	// Stream diretto senza bufferizzare l'intero corpo in memoria: gli export
+	// di log voluminosi non devono più mandare in OOM il gateway
+	written, err := io.Copy(flushWriter{c.Writer}, resp.Body)
 	if err != nil {
-		log.Printf("CHATLOG_PROXY_ERROR: read error=%v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read backend response"})
+		// Header già inviati: possiamo solo loggare l'interruzione
+		log.Printf("CHATLOG_PROXY_ERROR: stream interrotto dopo %d byte - url=%s, error=%v", written, backendURL, err)
 		return
 	}
 
-	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	log.Printf("CHATLOG_PROXY_OK: status=%d, bytes=%d, url=%s", resp.StatusCode, written, backendURL)
+}
+
+// flushWriter forza il flush dopo ogni chunk per supportare risposte
+// chunked/ndjson del backend attraverso il proxy
+type flushWriter struct {
+	w gin.ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if n > 0 {
+		f.w.Flush()
+	}
+	return n, err
 }
 
 // Debug mode structures
@@ -795,24 +1619,44 @@ type DebugChatRequest struct {
 	CodiceFiscale string `json:"codice_fiscale,omitempty"`
 	Username      string `json:"username,omitempty"`
 	UOC           string `json:"uoc,omitempty"` // NUOVO: Unità Operativa Complessa
+	// LLMParams: override opzionali di temperature/top_p/model validati
+	// contro i range ammessi in config (debug.llm_param_ranges)
+	LLMParams *LLMParams `json:"llm_params,omitempty"`
+	// Compare: invia il messaggio anche al backend secondario configurato
+	// in debug.compare_backend e riporta gli esiti affiancati
+	Compare bool `json:"compare,omitempty"`
+	// AttachSender: ispezione read-only del tracker del sender reale
+	// (nessun messaggio viene inviato al backend)
+	AttachSender string `json:"attach_sender,omitempty"`
 }
 
 type DebugChatResponse struct {
-	Message    string                   `json:"message"`
-	Status     string                   `json:"status"`
-	Error      string                   `json:"error,omitempty"`
-	Intent     map[string]interface{}   `json:"intent,omitempty"`
-	Entities   []map[string]interface{} `json:"entities,omitempty"`
-	Slots      map[string]interface{}   `json:"slots,omitempty"`
+	Message         string                   `json:"message"`
+	Status          string                   `json:"status"`
+	Error           string                   `json:"error,omitempty"`
+	Intent          map[string]interface{}   `json:"intent,omitempty"`
+	Entities        []map[string]interface{} `json:"entities,omitempty"`
+	Slots           map[string]interface{}   `json:"slots,omitempty"`
 	Metadata        map[string]interface{}   `json:"metadata,omitempty"`
 	Confidence      float64                  `json:"confidence,omitempty"`
 	ExecutedActions []string                 `json:"executed_actions,omitempty"`
 	// Enhanced debug fields for LangGraph visualization
-	ExecutionPath     []string                 `json:"execution_path,omitempty"`
-	NodeTimings       map[string]interface{}   `json:"node_timings,omitempty"`
-	WorkflowState     string                   `json:"workflow_state,omitempty"`
-	TotalExecutionMs  float64                  `json:"total_execution_ms,omitempty"`
-	OriginalMessage   string                   `json:"original_message,omitempty"`
+	ExecutionPath    []string               `json:"execution_path,omitempty"`
+	NodeTimings      map[string]interface{} `json:"node_timings,omitempty"`
+	WorkflowState    string                 `json:"workflow_state,omitempty"`
+	TotalExecutionMs float64                `json:"total_execution_ms,omitempty"`
+	OriginalMessage  string                 `json:"original_message,omitempty"`
+	// Simulated indica che execution_path/node_timings non provengono dal
+	// backend ma sono stati simulati dal gateway (vedi config debug)
+	Simulated bool `json:"simulated,omitempty"`
+	// EffectiveLLMParams: parametri LLM effettivamente inoltrati al backend
+	EffectiveLLMParams map[string]interface{} `json:"effective_llm_params,omitempty"`
+	// Compare: esiti affiancati dei due backend in modalità compare A/B
+	Compare *CompareReport `json:"compare,omitempty"`
+	// Tracker: stato conversazione del sender reale (modalità attach)
+	Tracker *conversationTracker `json:"tracker,omitempty"`
+	// Warnings: conflitti rilevati tra parse e stato del tracker
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // ParseMessage calls LLM server /api/v1/parse endpoint to get NLU predictions
@@ -839,6 +1683,9 @@ func ParseMessage(message, llmServerURL string, timeout int, context map[string]
 	if v, ok := context["uoc"].(string); ok {
 		meta.UOC = v
 	}
+	if v, ok := context["purpose"].(string); ok {
+		meta.Purpose = v
+	}
 
 	payload := map[string]interface{}{
 		"text":     message,
@@ -870,11 +1717,16 @@ func ParseMessage(message, llmServerURL string, timeout int, context map[string]
 		logCurlCommand("PARSE", curlCmd, requestData, config.Log.DebugFile)
 	}
 
-	client := &http.Client{
-		Timeout: time.Duration(timeout) * time.Second,
+	client := NewUpstreamClient("llm", time.Duration(timeout)*time.Second)
+
+	httpReq, err := http.NewRequest("POST", fullURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("error creating parse request: %v", err)
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	ApplyUpstreamHeaders(httpReq, context)
 
-	resp, err := client.Post(fullURL, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := client.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("error calling parse endpoint: %v", err)
 	}
@@ -901,10 +1753,7 @@ func HandleDebugChat(c *gin.Context) {
 	var req DebugChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("DEBUG_CHAT_ERROR: Invalid request - client_ip=%s, error=%v", clientIP, err)
-		c.JSON(http.StatusBadRequest, DebugChatResponse{
-			Status: "error",
-			Error:  "Invalid request format",
-		})
+		AbortWithError(c, ErrInvalidRequest, err.Error())
 		return
 	}
 
@@ -949,28 +1798,88 @@ func HandleDebugChat(c *gin.Context) {
 		context["uoc"] = uoc
 	}
 
+	// Preprocessing della domanda: in debug l'originale viene riecheggiato
+	// in original_message per confrontare testo grezzo ed elaborato
+	originalMessage := req.Message
+	if processed, changed := PreprocessMessage(config, req.Message); changed {
+		context["original_message"] = originalMessage
+		req.Message = processed
+	}
+
+	// Override parametri LLM per conversazione (validati contro config)
+	var effectiveLLMParams map[string]interface{}
+	if req.LLMParams != nil {
+		if err := ValidateLLMParams(config, req.LLMParams); err != nil {
+			log.Printf("DEBUG_CHAT_ERROR: llm_params non validi - client_ip=%s, error=%v", clientIP, err)
+			AbortWithError(c, ErrInvalidParameter, err.Error())
+			return
+		}
+		effectiveLLMParams = llmParamsMetadata(req.LLMParams)
+		if len(effectiveLLMParams) > 0 {
+			context["llm_params"] = effectiveLLMParams
+		}
+	}
+
 	// Step 1: Parse message to get NLU predictions
 	parseResp, err := ParseMessage(req.Message, config.LLMServer.URL, config.LLMServer.Timeout, context)
 	if err != nil {
 		log.Printf("DEBUG_CHAT_ERROR: Parse failed - error=%v", err)
-		c.JSON(http.StatusInternalServerError, DebugChatResponse{
-			Status: "error",
-			Error:  fmt.Sprintf("Failed to parse message: %v", err),
-		})
+		AbortWithError(c, ErrBackendError, err.Error())
 		return
 	}
 
-	// Step 2: Send message to LLM server via V1 API
-	v1Resp, err := SendToLLMV1(req.Message, req.Sender, config.LLMServer.URL, config.LLMServer.Timeout, context)
-	if err != nil {
-		log.Printf("DEBUG_CHAT_ERROR: LLM server request failed - error=%v", err)
-		c.JSON(http.StatusInternalServerError, DebugChatResponse{
-			Status: "error",
-			Error:  fmt.Sprintf("Failed to send message: %v", err),
-		})
+	// Aggancio read-only al tracker del sender reale: si confrontano parse
+	// e stato conversazione senza inviare alcun messaggio al backend
+	if req.AttachSender != "" {
+		tracker := TrackerSnapshot(req.AttachSender)
+		attachResp := DebugChatResponse{
+			Status: "success",
+			Intent: map[string]interface{}{
+				"name":       parseResp.Intent,
+				"confidence": parseResp.Confidence,
+			},
+			Confidence:      parseResp.Confidence,
+			Metadata:        context,
+			Slots:           parseResp.Slots,
+			OriginalMessage: originalMessage,
+			Tracker:         tracker,
+			Warnings:        TrackerConflicts(tracker, req.Message, parseResp.Intent),
+		}
+		if tracker != nil {
+			attachResp.Message = tracker.LatestBotText
+		}
+		log.Printf("DEBUG_CHAT_ATTACH: attach_sender=%s, parse_intent=%s, warnings=%d",
+			req.AttachSender, parseResp.Intent, len(attachResp.Warnings))
+		c.JSON(http.StatusOK, attachResp)
 		return
 	}
 
+	// Step 2: Send message to LLM server via V1 API (in modalità compare
+	// lo stesso messaggio viene inviato in parallelo anche al backend
+	// secondario, senza duplicare la chiamata al primario)
+	var v1Resp *NativeChatResponse
+	var compareReport *CompareReport
+	if req.Compare {
+		if config.Debug.CompareBackend.URL == "" {
+			log.Printf("DEBUG_CHAT_ERROR: compare richiesto ma debug.compare_backend non configurato - client_ip=%s", clientIP)
+			AbortWithError(c, ErrInvalidParameter, "backend di confronto non configurato (debug.compare_backend)")
+			return
+		}
+		v1Resp, compareReport = RunCompare(req.Message, req.Sender, config, context)
+		if v1Resp == nil {
+			log.Printf("DEBUG_CHAT_ERROR: LLM server request failed - error=%s", compareReport.Primary.Error)
+			AbortWithError(c, ErrBackendError, compareReport.Primary.Error)
+			return
+		}
+	} else {
+		v1Resp, err = SendToLLMV1(req.Message, req.Sender, config.LLMServer.URL, config.LLMServer.Timeout, context)
+		if err != nil {
+			log.Printf("DEBUG_CHAT_ERROR: LLM server request failed - error=%v", err)
+			AbortWithError(c, ErrBackendError, err.Error())
+			return
+		}
+	}
+
 	responseText := v1Resp.Result.Text
 	confidence := parseResp.Confidence
 
@@ -1006,26 +1915,22 @@ func HandleDebugChat(c *gin.Context) {
 		entities = append(entities, map[string]interface{}{"entity": k, "value": v})
 	}
 
-	// Fallback to simulated execution path if backend didn't provide one
+	// Se il backend non riporta il tracking di esecuzione, non fabbricare dati:
+	// simula solo se esplicitamente abilitato in config, marcando "simulated"
+	simulated := false
 	if len(executionPath) == 0 {
-		executionPath = determineExecutionPath(intentMap)
-		log.Printf("DEBUG_CHAT: Using fallback simulated execution_path: %v", executionPath)
-	}
-
-	if nodeTimings == nil {
-		nodeTimings = map[string]interface{}{
-			"classify": map[string]interface{}{
-				"duration": 150,
-				"status":   "completed",
-			},
-			"dialogue_manager": map[string]interface{}{
-				"duration": 100,
-				"status":   "completed",
-			},
-			"response_generator": map[string]interface{}{
-				"duration": 300,
-				"status":   "completed",
-			},
+		executionTracking, probed := GetBackendCapabilities()
+		if config.Debug.SimulateExecutionPath && (!executionTracking || !probed) {
+			executionPath = determineExecutionPath(intentMap)
+			nodeTimings = map[string]interface{}{
+				"classify":           map[string]interface{}{"duration": 150, "status": "simulated"},
+				"dialogue_manager":   map[string]interface{}{"duration": 100, "status": "simulated"},
+				"response_generator": map[string]interface{}{"duration": 300, "status": "simulated"},
+			}
+			simulated = true
+			log.Printf("DEBUG_CHAT: execution tracking assente dal backend, uso percorso SIMULATO: %v", executionPath)
+		} else {
+			log.Printf("DEBUG_CHAT: execution tracking assente dal backend, campi omessi (simulazione disabilitata)")
 		}
 	}
 
@@ -1042,7 +1947,11 @@ func HandleDebugChat(c *gin.Context) {
 		NodeTimings:      nodeTimings,
 		WorkflowState:    "completed",
 		TotalExecutionMs: totalExecutionMs,
-		OriginalMessage:  req.Message,
+		OriginalMessage:  originalMessage,
+		Simulated:        simulated,
+		// Echo dei parametri LLM effettivi per il confronto lato UI
+		EffectiveLLMParams: effectiveLLMParams,
+		Compare:            compareReport,
 	}
 
 	log.Printf("DEBUG_CHAT_SUCCESS: sender=%s, intent=%s, confidence=%.2f, slots=%d",
@@ -1081,4 +1990,4 @@ func determineExecutionPath(intent map[string]interface{}) []string {
 	path = append(path, "response_generator")
 
 	return path
-}
\ No newline at end of file
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log"
+)
+
+// Override dei parametri LLM per conversazione (solo debug mode): i power
+// user possono variare temperature/top_p/model per confrontare i
+// comportamenti del modello. I valori sono validati contro i range ammessi
+// in config (debug.llm_param_ranges) e inoltrati al backend nei metadata;
+// i parametri effettivi vengono riecheggiati in DebugChatResponse.
+
+// LLMParams sono gli override opzionali richiesti dal client debug.
+// Puntatori per distinguere "assente" da "zero esplicito".
+type LLMParams struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Model       string   `json:"model,omitempty"`
+}
+
+// LLMParamRanges definisce i limiti ammessi per gli override (config)
+type LLMParamRanges struct {
+	TemperatureMax float64  `json:"temperature_max"`
+	TopPMax        float64  `json:"top_p_max"`
+	AllowedModels  []string `json:"allowed_models"`
+}
+
+// Limiti di default quando la config non specifica i range
+const (
+	defaultTemperatureMax = 2.0
+	defaultTopPMax        = 1.0
+)
+
+// ValidateLLMParams verifica gli override contro i range di config.
+// Ritorna errore descrittivo alla prima violazione.
+func ValidateLLMParams(config *Config, params *LLMParams) error {
+	ranges := config.Debug.LLMParamRanges
+
+	tempMax := ranges.TemperatureMax
+	if tempMax <= 0 {
+		tempMax = defaultTemperatureMax
+	}
+	topPMax := ranges.TopPMax
+	if topPMax <= 0 {
+		topPMax = defaultTopPMax
+	}
+
+	if params.Temperature != nil && (*params.Temperature < 0 || *params.Temperature > tempMax) {
+		return fmt.Errorf("temperature %.2f fuori dal range ammesso [0, %.2f]", *params.Temperature, tempMax)
+	}
+	if params.TopP != nil && (*params.TopP < 0 || *params.TopP > topPMax) {
+		return fmt.Errorf("top_p %.2f fuori dal range ammesso [0, %.2f]", *params.TopP, topPMax)
+	}
+	if params.Model != "" && len(ranges.AllowedModels) > 0 {
+		allowed := false
+		for _, model := range ranges.AllowedModels {
+			if model == params.Model {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("model '%s' non presente tra i modelli ammessi", params.Model)
+		}
+	}
+
+	return nil
+}
+
+// llmParamsMetadata serializza gli override nel formato atteso dal campo
+// metadata del backend, omettendo i valori non specificati
+func llmParamsMetadata(params *LLMParams) map[string]interface{} {
+	meta := make(map[string]interface{})
+	if params.Temperature != nil {
+		meta["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		meta["top_p"] = *params.TopP
+	}
+	if params.Model != "" {
+		meta["model"] = params.Model
+	}
+	log.Printf("LLM_PARAMS_OVERRIDE: %v", meta)
+	return meta
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Gauge di carico in tempo reale per gli operatori: stream SSE attivi,
+// richieste chat in corso, trascrizioni whisper in volo, stato del backend
+// e goroutine, così la saturazione si vede prima che arrivino le
+// segnalazioni degli utenti.
+
+// Contatori atomici incrementati/decrementati dagli handler
+var (
+	inflightChatRequests   int64
+	inflightTranscriptions int64
+)
+
+func gaugeChatStarted()        { atomic.AddInt64(&inflightChatRequests, 1) }
+func gaugeChatFinished()       { atomic.AddInt64(&inflightChatRequests, -1) }
+func gaugeTranscribeStarted()  { atomic.AddInt64(&inflightTranscriptions, 1) }
+func gaugeTranscribeFinished() { atomic.AddInt64(&inflightTranscriptions, -1) }
+
+// backendState descrive lo stato del backend visto dal gateway (dalla
+// cache dell'health check: "closed" = sano, "open" = non raggiungibile,
+// "unknown" = mai verificato)
+func backendState() (string, time.Time) {
+	healthCache.mu.RLock()
+	defer healthCache.mu.RUnlock()
+
+	if healthCache.lastCheck.IsZero() {
+		return "unknown", healthCache.lastCheck
+	}
+	if healthCache.isHealthy {
+		return "closed", healthCache.lastCheck
+	}
+	return "open", healthCache.lastCheck
+}
+
+// HandleLoadGauges espone le misure di carico correnti
+// GET /admin/api/load
+func HandleLoadGauges(c *gin.Context) {
+	supervisorRegistry.mu.Lock()
+	activeStreams := len(supervisorRegistry.streams)
+	supervisorRegistry.mu.Unlock()
+
+	chatCoalescer.mu.Lock()
+	inflightCoalesced := len(chatCoalescer.calls)
+	chatCoalescer.mu.Unlock()
+
+	state, lastCheck := backendState()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":                  "success",
+		"active_sse_streams":      activeStreams,
+		"inflight_chat_requests":  atomic.LoadInt64(&inflightChatRequests),
+		"inflight_coalesced":      inflightCoalesced,
+		"inflight_transcriptions": atomic.LoadInt64(&inflightTranscriptions),
+		"backend_circuit":         state,
+		"backend_last_check":      lastCheck.Unix(),
+		"goroutines":              runtime.NumGoroutine(),
+		"collapsed_duplicates":    atomic.LoadInt64(&collapsedDuplicates),
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Load shedding con rilevamento saturazione: sotto carico estremo è
+// meglio rifiutare subito il traffico a bassa priorità con un 503
+// esplicativo che far scadere tutto in timeout. La saturazione si misura
+// sugli stessi segnali esposti dai gauge di carico (richieste chat in
+// volo, goroutine, circuito backend); vengono scartati prima gli endpoint
+// di debug e poi la chat anonima, proteggendo la chat autenticata.
+
+const (
+	shedDefaultMaxInflight   = 64   // richieste chat in volo
+	shedDefaultMaxGoroutines = 2000 // soglia goroutine
+)
+
+// Livelli di saturazione
+const (
+	shedLevelNormal    = 0 // nessuno scarto
+	shedLevelSaturated = 1 // scarta debug e chat anonima
+	shedLevelCritical  = 2 // scarta anche la chat autenticata
+)
+
+// LoadSheddingConfig configura le soglie (sezione load_shedding)
+type LoadSheddingConfig struct {
+	Enabled         bool `json:"enabled"`
+	MaxInflightChat int  `json:"max_inflight_chat"` // default 64
+	MaxGoroutines   int  `json:"max_goroutines"`    // default 2000
+}
+
+// sheddingLevel calcola il livello di saturazione corrente dai gauge
+func sheddingLevel(config *Config) int {
+	if !config.LoadShedding.Enabled {
+		return shedLevelNormal
+	}
+	maxInflight := config.LoadShedding.MaxInflightChat
+	if maxInflight <= 0 {
+		maxInflight = shedDefaultMaxInflight
+	}
+	maxGoroutines := config.LoadShedding.MaxGoroutines
+	if maxGoroutines <= 0 {
+		maxGoroutines = shedDefaultMaxGoroutines
+	}
+
+	inflight := atomic.LoadInt64(&inflightChatRequests)
+	if inflight >= int64(2*maxInflight) {
+		return shedLevelCritical
+	}
+	if inflight >= int64(maxInflight) || runtime.NumGoroutine() >= maxGoroutines {
+		return shedLevelSaturated
+	}
+	// Backend irraggiungibile: le richieste in volo si accumulerebbero
+	if state, _ := backendState(); state == "open" && inflight >= int64(maxInflight/2) {
+		return shedLevelSaturated
+	}
+	return shedLevelNormal
+}
+
+// LoadSheddingMiddleware scarta gli endpoint di debug quando il gateway
+// è saturo (il traffico chat viene valutato negli handler, dove la
+// priorità dell'utente è nota)
+func LoadSheddingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !strings.Contains(path, "/debug") {
+			c.Next()
+			return
+		}
+		config := LoadConfig()
+		if level := sheddingLevel(config); level >= shedLevelSaturated {
+			log.Printf("SHED_DEBUG: path=%s, level=%d, client_ip=%s", path, level, c.ClientIP())
+			c.Header("Retry-After", "30")
+			AbortWithError(c, ErrBackendUnavailable,
+				"gateway saturo: gli endpoint di debug sono temporaneamente sospesi, riprova tra qualche istante")
+			return
+		}
+		c.Next()
+	}
+}
+
+// ShedChatRequest decide se scartare una richiesta chat in base alla
+// priorità: gli utenti anonimi (senza user_id né codice fiscale) vengono
+// scartati per primi, quelli autenticati solo a livello critico.
+// Ritorna il messaggio del 503 se la richiesta va scartata.
+func ShedChatRequest(config *Config, req *ChatRequest) (string, bool) {
+	level := sheddingLevel(config)
+	if level == shedLevelNormal {
+		return "", false
+	}
+	authenticated := req.UserID != "" || req.CodiceFiscale != ""
+	if authenticated && level < shedLevelCritical {
+		return "", false
+	}
+	if authenticated {
+		return "il servizio è momentaneamente sovraccarico, riprova tra qualche minuto", true
+	}
+	return "il servizio è sovraccarico: accedi dal portale GISA per avere priorità, oppure riprova più tardi", true
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Live tail del log applicativo via SSE per la pagina di debug.
+// Permette al supporto di osservare in tempo reale eventi come LLM_ERROR
+// senza accesso shell al server.
+
+// logTailEvent è il payload di ogni riga inoltrata al client
+type logTailEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Line      string `json:"line"`
+}
+
+// logTailIdentityRe oscura i valori dei campi identità nelle righe di log
+// (codice_fiscale=..., user_id=..., username=...), stessa policy
+// dell'access log (vedi accessLogSensitiveParams in access_log.go)
+var logTailIdentityRe = regexp.MustCompile(`(?i)(` + strings.Join(accessLogSensitiveParams, "|") + `)=([^,\s&"]+)`)
+
+// maskLogTailLine applica l'oscuramento identità prima dell'invio al client
+func maskLogTailLine(line string) string {
+	return logTailIdentityRe.ReplaceAllString(line, "$1=***")
+}
+
+// logLineMatches verifica i filtri level/prefix sulla riga di log.
+// level: "error" seleziona solo righe con _ERROR; "warn" anche WARN.
+// prefixes: lista di prefissi strutturati (es. "LLM_,CHAT_"), vuota = tutti.
+func logLineMatches(line string, level string, prefixes []string) bool {
+	switch level {
+	case "error":
+		if !strings.Contains(line, "_ERROR") && !strings.Contains(line, "ERROR_") {
+			return false
+		}
+	case "warn":
+		if !strings.Contains(line, "_ERROR") && !strings.Contains(line, "ERROR_") && !strings.Contains(line, "WARN") {
+			return false
+		}
+	}
+
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, p := range prefixes {
+		if p != "" && strings.Contains(line, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleLogStream fa il tail del log applicativo e lo inoltra via SSE.
+// GET /admin/api/logs/stream?level=error&prefix=LLM_,CHAT_
+func HandleLogStream(c *gin.Context) {
+	config := LoadConfig()
+	logFile := config.Log.File
+	if logFile == "" {
+		logFile = "log/app.log"
+	}
+
+	level := c.Query("level")
+	var prefixes []string
+	if p := c.Query("prefix"); p != "" {
+		for _, part := range strings.Split(p, ",") {
+			if trimmed := strings.TrimSpace(part); trimmed != "" {
+				prefixes = append(prefixes, trimmed)
+			}
+		}
+	}
+
+	log.Printf("LOGTAIL_REQUEST: file=%s, level=%s, prefixes=%v, client_ip=%s", logFile, level, prefixes, c.ClientIP())
+
+	file, err := os.Open(logFile)
+	if err != nil {
+		log.Printf("LOGTAIL_ERROR: cannot open log file %s: %v", logFile, err)
+		AbortWithError(c, ErrNotFound, fmt.Sprintf("file di log non disponibile: %v", err))
+		return
+	}
+	defer file.Close()
+
+	// Parti dalla fine del file (comportamento tail -f)
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		log.Printf("LOGTAIL_ERROR: seek failed: %v", err)
+		AbortWithError(c, ErrInternal, "impossibile posizionarsi in coda al log")
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		AbortWithError(c, ErrStreamingUnsupported, "")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	reader := bufio.NewReader(file)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	clientGone := c.Request.Context().Done()
+	linesSent := 0
+
+	for {
+		select {
+		case <-clientGone:
+			log.Printf("LOGTAIL_CLOSED: client disconnected, lines_sent=%d, client_ip=%s", linesSent, c.ClientIP())
+			return
+		case <-ticker.C:
+			for {
+				line, err := reader.ReadString('\n')
+				if line != "" {
+					trimmed := strings.TrimRight(line, "\r\n")
+					if logLineMatches(trimmed, level, prefixes) {
+						event := logTailEvent{
+							Timestamp: time.Now().UnixMilli(),
+							Line:      maskLogTailLine(trimmed),
+						}
+						data, merr := json.Marshal(event)
+						if merr == nil {
+							fmt.Fprintf(c.Writer, "event: log\ndata: %s\n\n", string(data))
+							flusher.Flush()
+							linesSent++
+						}
+					}
+				}
+				if err != nil {
+					// EOF: attendi il prossimo tick per nuove righe
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"compress/gzip"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Modalità banda ridotta per gli uffici ASL con connettività scarsa:
+// negoziata via header X-Low-Bandwidth o parametro low_bandwidth (la
+// preferenza resta in sessione). Effetti: streaming disabilitato, payload
+// full_data sostituiti da un handle per il fetch on demand, risposte
+// compresse gzip, metadata SSE ridotti al minimo.
+
+const fullDataTTL = 10 * time.Minute
+
+type fullDataEntry struct {
+	data      interface{}
+	expiresAt time.Time
+}
+
+type fullDataStore struct {
+	entries map[string]*fullDataEntry
+	mu      sync.Mutex
+}
+
+var fullDataCache = &fullDataStore{
+	entries: make(map[string]*fullDataEntry),
+}
+
+// LowBandwidthRequested determina se la richiesta è in modalità banda
+// ridotta. Priorità: header > query > preferenza salvata in sessione.
+func LowBandwidthRequested(c *gin.Context) bool {
+	pref := c.GetHeader("X-Low-Bandwidth")
+	if pref == "" {
+		pref = c.Query("low_bandwidth")
+	}
+
+	session := sessions.Default(c)
+	if pref != "" {
+		enabled := pref == "1" || pref == "true"
+		session.Set("low_bandwidth", enabled)
+		if err := session.Save(); err != nil {
+			log.Printf("LOWBW_SESSION_ERROR: %v", err)
+		}
+		return enabled
+	}
+
+	if saved := session.Get("low_bandwidth"); saved != nil {
+		if enabled, ok := saved.(bool); ok {
+			return enabled
+		}
+	}
+	return false
+}
+
+// RegisterFullData mette da parte un payload full_data e ritorna l'handle
+// con cui la UI può recuperarlo on demand
+func RegisterFullData(data interface{}) string {
+	handle := newContinuationHandle()
+	if handle == "" {
+		return ""
+	}
+
+	fullDataCache.mu.Lock()
+	fullDataCache.entries[handle] = &fullDataEntry{
+		data:      data,
+		expiresAt: time.Now().Add(fullDataTTL),
+	}
+	fullDataCache.mu.Unlock()
+
+	time.AfterFunc(fullDataTTL, func() {
+		fullDataCache.mu.Lock()
+		delete(fullDataCache.entries, handle)
+		fullDataCache.mu.Unlock()
+	})
+
+	return handle
+}
+
+// HandleFullDataFetch restituisce un payload full_data messo da parte
+// GET /api/full-data/:handle
+func HandleFullDataFetch(c *gin.Context) {
+	handle := c.Param("handle")
+
+	fullDataCache.mu.Lock()
+	entry, ok := fullDataCache.entries[handle]
+	fullDataCache.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		AbortWithError(c, ErrNotFound, "handle full_data scaduto o inesistente")
+		return
+	}
+
+	log.Printf("LOWBW_FULLDATA_FETCH: handle=%s, client_ip=%s", handle, c.ClientIP())
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"full_data": entry.data,
+	})
+}
+
+// gzipResponseWriter comprime il body al volo
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	gz *gzip.Writer
+}
+
+func (g *gzipResponseWriter) Write(data []byte) (int, error) {
+	return g.gz.Write(data)
+}
+
+func (g *gzipResponseWriter) WriteString(s string) (int, error) {
+	return g.gz.Write([]byte(s))
+}
+
+// LowBandwidthGzipMiddleware comprime le risposte quando l'utente è in
+// modalità banda ridotta e il client accetta gzip. Gli stream SSE e gli
+// asset statici sono esclusi (gli eventi devono uscire subito).
+func LowBandwidthGzipMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if strings.Contains(path, "/stream") || strings.Contains(path, "/static") ||
+			strings.Contains(path, "/watch") {
+			c.Next()
+			return
+		}
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") || !LowBandwidthRequested(c) {
+			c.Next()
+			return
+		}
+
+		gz, err := gzip.NewWriterLevel(c.Writer, gzip.BestCompression)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		c.Header("Content-Encoding", "gzip")
+		c.Header("Vary", "Accept-Encoding")
+		c.Writer = &gzipResponseWriter{ResponseWriter: c.Writer, gz: gz}
+
+		c.Next()
+
+		if err := gz.Close(); err != nil {
+			log.Printf("LOWBW_GZIP_ERROR: %v", err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 
@@ -85,6 +86,22 @@ func parseQueryParams(c *gin.Context) (userIDStr, aslID, aslName, codiceFiscale,
 }
 
 func main() {
+	// Sottocomandi CLI: "seed" (dati fittizi), "smoke" (test post-deploy),
+	// "profile" (stampa la configurazione risolta per un profilo)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "seed":
+			RunSeed(os.Args[2:])
+			return
+		case "smoke":
+			RunSmoke(os.Args[2:])
+			return
+		case "profile":
+			RunProfileCmd(os.Args[2:])
+			return
+		}
+	}
+
 	config := LoadConfig()
 
 	r := gin.Default()
@@ -93,8 +110,8 @@ func main() {
 	// IMPORTANTE: in produzione usare una chiave segreta da variabile d'ambiente
 	store := cookie.NewStore([]byte("gias-secret-key-32-bytes-long!!!"))
 	store.Options(sessions.Options{
-		Path:     "/gias/webchat",
-		MaxAge:   SessionTTL, // 5 minuti
+		Path:     sessionCookiePath(config),
+		MaxAge:   SessionTTLSeconds(), // default 5 minuti, configurabile
 		HttpOnly: true,
 		Secure:   false, // true in produzione con HTTPS
 		SameSite: http.SameSiteLaxMode,
@@ -103,6 +120,12 @@ func main() {
 	// Apply session middleware
 	r.Use(sessions.Sessions("gias_session", store))
 	r.Use(SessionMiddleware())
+	r.Use(RequestIDMiddleware())
+
+	// Catena di middleware trasversali (security header, gzip, load
+	// shedding, access log, ...) dichiarata per gruppo in config.json
+	// (vedi middleware_registry.go)
+	ApplyMiddlewareChain(r, config, "global")
 
 	// Add template functions
 	r.SetFuncMap(template.FuncMap{
@@ -117,17 +140,60 @@ func main() {
 		},
 	})
 
-	r.LoadHTMLGlob("template/*")
+	r.LoadHTMLGlob("template/*.html")
+
+	// Partial per data_type scoperti da template/partials (vedi partials.go)
+	LoadPartials()
+
+	// Probe delle capacità del backend in background (non blocca l'avvio)
+	go ProbeBackendCapabilities(config.LLMServer.URL, config.LLMServer.Timeout)
 
-	// Base path for reverse proxy
-	basePath := "/gias/webchat"
+	// Warm-up e keep-warm del server whisper (preload del modello)
+	go StartWhisperKeepWarm(config)
+
+	// Purger di retention schedulato (policy per classe di dato)
+	go StartRetentionScheduler(config)
+
+	// Reload della cache personale su SIGHUP (vedi personale_reload.go)
+	go StartPersonaleReloadOnSignal()
+
+	// Job di archiviazione cold-storage su bucket S3-compatibile
+	go StartArchiveScheduler(config)
+
+	// Base path for reverse proxy (configurabile, vedi basepath.go)
+	basePath := BasePath(config)
+
+	// Mount aggiuntivi per URL legacy (riscrittura sul prefisso primario)
+	RegisterExtraMounts(r, config, basePath)
 
 	// Group routes under base path
 	api := r.Group(basePath)
 	api.Static("/static", "./statics")
 
+	// Catena middleware dedicata al gruppo sotto base path (vuota per
+	// default, configurabile senza modifiche al codice)
+	ApplyMiddlewareChain(api, config, "api")
+
+	// Autenticazione SAML/SPID opzionale (auth.mode = "saml"); in modalità
+	// "portal" resta il flusso a parametri esistente
+	if config.Auth.Mode == "saml" {
+		samlSP, err := InitSAML(config)
+		if err != nil {
+			log.Fatalf("AUTH_SAML_ERROR: %v", err)
+		}
+		api.Any("/saml/*action", gin.WrapH(samlSP))
+		api.Use(SAMLAuthMiddleware(samlSP, config))
+	}
+
 	// Main page handler - supports both GET (querystring), POST (JSON body), and session
 	indexHandler := func(c *gin.Context) {
+		// Fallback no-JS (progressive enhancement): ?nojs=1 o form POST
+		// con campo "message" vengono serviti con rendering server-side
+		if NoJSRequested(c) {
+			HandleNoJS(c, config, basePath)
+			return
+		}
+
 		// Merge parameters: Session + Query + POST (priority: POST > Query > Session)
 		userIDStr, aslID, aslName, codiceFiscale, username := MergeSessionParams(c)
 
@@ -147,6 +213,24 @@ func main() {
 			welcomeMessage = strings.ReplaceAll(welcomeMessage, "Priorità 2025:", fmt.Sprintf("Priorità %d:", currentYear))
 		}
 
+		// Contesto record GISA da deep-link (context_type/context_id)
+		contextType, contextID := MergeRecordContext(c)
+
+		// Analytics di sessione: apre/aggiorna la sessione con lo snapshot identità
+		SessionTouch(c, aslName, userIDStr, codiceFiscale)
+
+		// ASL canonica per la valutazione dei feature flag per-ASL
+		flagASL := aslName
+		if canonical, ok := NormalizeASLName(aslName); ok {
+			flagASL = canonical
+		}
+
+		// Banda ridotta: niente streaming, risposte compresse
+		lowBandwidth := LowBandwidthRequested(c)
+		if lowBandwidth {
+			log.Printf("LOWBW_ACTIVE: modalità banda ridotta - client_ip=%s", c.ClientIP())
+		}
+
 		// Default template data
 		templateData := gin.H{
 			"title":                "Assistente Gisa",
@@ -154,16 +238,35 @@ func main() {
 			"welcomeMessage":       welcomeMessage,
 			"basePath":             basePath,
 			"transcriptionEnabled": config.Transcription.Enabled,
-			"streamingEnabled":     config.UI.EnableStreaming,
+			"streamingEnabled":     config.UI.EnableStreaming && !lowBandwidth && FeatureEnabled("streaming", flagASL),
+			"features":             EvaluatedFlags(flagASL),
 			"queryParams": gin.H{
 				"asl_id":         aslID,
 				"asl_name":       aslName,
 				"user_id":        userIDStr,
 				"codice_fiscale": codiceFiscale,
 				"username":       username,
+				"context_type":   contextType,
+				"context_id":     contextID,
 			},
 		}
 
+		if contextType != "" {
+			templateData["recordContext"] = gin.H{
+				"label": RecordContextLabel(contextType, contextID),
+			}
+		}
+
+		// Banner impersonificazione (sessione di supporto, time-boxed)
+		if banner, active := ImpersonationActive(c); active {
+			templateData["impersonation"] = banner
+		}
+
+		// Questionario di ingresso (se configurato e non ancora compilato)
+		if IntakePending(c, config) {
+			templateData["intakeForm"] = config.IntakeForm
+		}
+
 		// If user_id is provided, try to load user data
 		if userData := loadUserData(userIDStr, aslName, "INDEX"); userData != nil {
 			templateData["user"] = userData
@@ -181,6 +284,15 @@ func main() {
 	api.GET("/api/predefined-questions", HandlePredefinedQuestions)
 	api.POST("/api/transcribe", TranscribeHandler)
 
+	// Superficie API v1 versionata per client SDK (vedi api_v1.go)
+	RegisterAPIV1Routes(api)
+
+	// Upload resumabili stile tus per la trascrizione (connessioni mobili)
+	api.POST("/api/transcribe/uploads", HandleUploadCreate)
+	api.HEAD("/api/transcribe/uploads/:id", HandleUploadStatus)
+	api.PATCH("/api/transcribe/uploads/:id", HandleUploadChunk)
+	api.POST("/api/transcribe/uploads/:id/finalize", HandleUploadFinalize)
+
 	// Debug mode endpoints
 	api.GET("/debug", func(c *gin.Context) {
 		userIDStr, aslID, aslName, codiceFiscale, username := MergeSessionParams(c)
@@ -197,6 +309,7 @@ func main() {
 			"basePath":       basePath,
 			"llmModel":       backendStatus.LLM,
 			"framework":      backendStatus.Framework,
+			"whisperStatus":  GetWhisperHealth(),
 			"queryParams": gin.H{
 				"asl_id": aslID, "asl_name": aslName, "user_id": userIDStr,
 				"codice_fiscale": codiceFiscale, "username": username,
@@ -231,6 +344,12 @@ func main() {
 
 	api.POST("/debug/chat", HandleDebugChat)
 
+	// Glossario intent → descrizione business per le pagine debug/analytics
+	api.GET("/api/debug/intent-glossary", HandleIntentGlossary)
+
+	// Trace OTLP/JSON per turno (chiave <codice>-<turno>, es. GIAS-7F3K-2)
+	api.GET("/api/debug/trace/:turn", HandleDebugTrace)
+
 	// Chat Analytics Dashboard
 	api.GET("/analytics", func(c *gin.Context) {
 		userIDStr, aslID, aslName, codiceFiscale, username := MergeSessionParams(c)
@@ -269,13 +388,193 @@ func main() {
 		c.HTML(http.StatusOK, "monitor.html", templateData)
 	})
 
-	// Chat History API proxy (avoid CORS by proxying through Go server)
-	api.GET("/api/chat-log/user-conversations", func(c *gin.Context) {
-		ProxyChatLogAPI(c, config.LLMServer.URL, config.LLMServer.Timeout)
-	})
-	api.GET("/api/chat-log/conversation/:sessionId", func(c *gin.Context) {
+	// Superficie amministrativa: tutte le route /admin/api/* passano dal
+	// token di ambiente ADMIN_API_TOKEN (vedi admin_auth.go)
+	admin := api.Group("/admin/api", AdminAuthMiddleware())
+
+	// Usage metering per-ASL (export CSV per chargeback)
+	admin.GET("/usage", HandleUsageExport)
+
+	// Live tail del log applicativo via SSE (pagina debug)
+	admin.GET("/logs/stream", HandleLogStream)
+
+	// Tagging e bookmark delle conversazioni
+	api.POST("/api/conversations/:sender/tags", HandleAddConversationTag)
+	api.DELETE("/api/conversations/:sender/tags", HandleRemoveConversationTag)
+	api.POST("/api/conversations/:sender/bookmark", HandleBookmarkConversation)
+	api.GET("/api/conversations", HandleListConversationsByTag)
+
+	// Polling job asincroni (operazioni backend lente)
+	api.GET("/api/jobs/:id", HandleGetJob)
+	api.GET("/api/jobs", HandleListJobs)
+
+	// Inbox notifiche per i risultati asincroni
+	api.GET("/api/notifications", HandleListNotifications)
+	api.POST("/api/notifications/:id/read", HandleMarkNotificationRead)
+
+	// Tracking click sui suggerimenti (alimenta il ranking)
+	api.POST("/api/suggestions/click", HandleSuggestionClick)
+
+	// Metriche qualità streaming SSE
+	api.GET("/api/analytics/streaming", HandleStreamingAnalytics)
+
+	// Keep-alive della sessione (estende il TTL senza inviare messaggi)
+	api.POST("/api/session/ping", HandleSessionPing)
+
+	// Cambio dell'ASL attiva per i supervisori multi-ASL (vedi scope.go)
+	api.POST("/api/scope", HandleScopeSwitch)
+
+	// Informativa privacy: testo/versione corrente e accettazione
+	api.GET("/api/consent", HandleConsentNotice)
+	api.POST("/api/consent", HandleConsentAccept)
+
+	// Smoke test conversazionali contro il backend (script YAML nel body)
+	admin.POST("/smoke/run", HandleSmokeRun)
+
+	// Benchmark NLU in blocco (regressione dopo retraining del modello)
+	admin.POST("/benchmark", HandleBenchmark)
+
+	// Export analytics anonimizzato per la reportistica regionale
+	admin.GET("/analytics/export", HandleAnalyticsExport)
+
+	// Questionario di ingresso configurabile
+	api.POST("/api/intake", HandleIntakeSubmit)
+
+	// Gauge di carico per gli operatori (saturazione in tempo reale)
+	admin.GET("/load", HandleLoadGauges)
+
+	// Stato SLO di latenza per intent (p95 vs target, violazioni recenti)
+	admin.GET("/slo", HandleSLOReport)
+
+	// Analytics di sessione (sessioni/giorno per ASL, turni medi)
+	api.GET("/api/analytics/sessions", HandleSessionAnalytics)
+
+	// Prontezza del gateway (backend + whisper) per probe e load balancer
+	api.GET("/readyz", HandleReadyz)
+
+	// Anteprima intent live per la debug UI (flag debug_parse_preview)
+	api.POST("/api/parse-preview", HandleParsePreview)
+
+	// Retention dei dati del gateway (run on demand + report)
+	admin.POST("/retention/run", HandleRetentionRun)
+	admin.GET("/retention/report", HandleRetentionReport)
+
+	// Report degli schemi payload osservati (recorder opt-in)
+	admin.GET("/schema-report", HandleSchemaReport)
+
+	// Contabilità costi giornaliera dai token riportati dal backend
+	admin.GET("/costs", HandleCosts)
+
+	// Canary routing: stato e regolazione dello split a runtime
+	admin.GET("/canary", HandleCanaryStatus)
+	admin.POST("/canary", HandleCanaryAdjust)
+
+	// Ban temporanei anti-abuso: elenco e rimozione
+	admin.GET("/bans", HandleListBans)
+	admin.DELETE("/bans/:key", HandleLiftBan)
+
+	// Config effettiva (profilo applicato, segreti mascherati, drift dal file)
+	admin.GET("/config/effective", HandleEffectiveConfig)
+
+	// Build e configurazione runtime dell'istanza (per il fleet tooling)
+	admin.GET("/buildinfo", HandleBuildInfo)
+
+	// Replay di conversazioni storiche contro il backend corrente
+	admin.POST("/replay", HandleReplay)
+
+	// Reload esplicito della cache personale (anche via SIGHUP)
+	admin.POST("/personale/reload", HandlePersonaleReload)
+
+	// Archivio cold-storage: catalogo e restore on-demand dal bucket
+	admin.GET("/archive", HandleArchiveCatalog)
+	admin.GET("/archive/restore/:sessionId", HandleArchiveRestore)
+
+	// Workflow guidati: elenco definizioni e avvio percorso
+	api.GET("/api/workflows", HandleListWorkflows)
+	api.POST("/api/workflows/:id/start", HandleStartWorkflow)
+
+	// Rinomina conversazioni (titoli per la sidebar cronologia)
+	api.PUT("/api/conversations/:id/title", HandleSetConversationTitle)
+
+	// Impersonificazione di supporto (audit log, scadenza time-boxed)
+	admin.POST("/impersonate", HandleImpersonate)
+	admin.DELETE("/impersonate", HandleImpersonateEnd)
+
+	// Escalation delle domande senza risposta verso il supporto
+	api.POST("/api/escalations", HandleEscalation)
+
+	// Ricerca full-text nella cronologia dell'utente
+	api.GET("/api/history/search", HandleHistorySearch)
+
+	// Submit dei form dinamici guidati dal backend
+	api.POST("/api/forms/:id/submit", HandleFormSubmit)
+
+	// Sintesi vocale con timing parola-per-parola (se il motore li fornisce)
+	api.POST("/api/tts", HandleTTS)
+
+	// Variabili di contesto del backend per il frontend (anno, stato)
+	api.GET("/api/context", HandleContext)
+
+	// Risoluzione dei codici conversazione pronunciabili (supporto)
+	api.GET("/api/conversations/lookup", HandleConversationLookup)
+
+	// Conferma esplicita delle azioni che modificano dati
+	api.POST("/api/actions/confirm", HandleActionConfirm)
+
+	// Telemetria errori lato client (ingestione + report per il monitor)
+	api.POST("/api/telemetry/client-errors", HandleClientErrors)
+	api.GET("/api/monitor/client-errors", HandleClientErrorReport)
+
+	// Stato alert firing/resolved per il monitor (stessa vista del pager)
+	api.GET("/api/alerts", HandleAlerts)
+
+	// Ricevute di consegna delle risposte (ack dal client)
+	api.POST("/api/receipts", HandleDeliveryReceipt)
+
+	// Link di condivisione firmati a scadenza (trascrizione sola lettura)
+	api.POST("/api/conversations/:sender/share", HandleCreateShare)
+	api.GET("/share/:id", HandleShareView)
+
+	// FAQ curate nel gateway (priorità sull'LLM)
+	admin.GET("/faqs", HandleListFAQs)
+	admin.PUT("/faqs/:id", HandleUpsertFAQ)
+	admin.DELETE("/faqs/:id", HandleDeleteFAQ)
+
+	// Feature flag: valutazione per l'utente e override runtime admin
+	api.GET("/api/capabilities", HandleCapabilities)
+	admin.GET("/flags", HandleListFlags)
+	admin.PUT("/flags/:name", HandleSetFlag)
+	admin.DELETE("/flags/:name", HandleDeleteFlag)
+
+	// Continuazione delle risposte troncate lato server
+	api.GET("/api/continuation/:handle", HandleContinuation)
+
+	// Fetch on demand dei payload full_data (modalità banda ridotta)
+	api.GET("/api/full-data/:handle", HandleFullDataFetch)
+
+	// Modalità supervisore: live-view delle conversazioni streaming in corso
+	admin.GET("/conversations/active", HandleActiveConversations)
+	admin.GET("/conversations/:sender/watch", HandleWatchConversation)
+
+	// Chat History API proxy (avoid CORS by proxying through Go server).
+	// Anti-scraping: gli accessi contano nella finestra anti-abuso
+	chatLogProxy := func(c *gin.Context) {
+		if !RequireDatabase(c) {
+			return
+		}
+		cfg := LoadConfig()
+		if _, banned := AbuseBanned(cfg, c.Query("user_id"), c.ClientIP()); banned {
+			AbortWithError(c, ErrRateLimited, "accesso sospeso temporaneamente per uso anomalo")
+			return
+		}
+		if AbuseObserveDataRequest(cfg, c.Query("user_id"), c.ClientIP()) {
+			AbortWithError(c, ErrRateLimited, "accesso sospeso temporaneamente per uso anomalo")
+			return
+		}
 		ProxyChatLogAPI(c, config.LLMServer.URL, config.LLMServer.Timeout)
-	})
+	}
+	api.GET("/api/chat-log/user-conversations", chatLogProxy)
+	api.GET("/api/chat-log/conversation/:sessionId", chatLogProxy)
 
 	// Chat History Page
 	api.GET("/history", func(c *gin.Context) {
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+)
+
+// Minimizzazione dei metadati inviati al backend LLM: la privacy review
+// chiede perché il codice fiscale viaggia verso l'LLM a ogni messaggio.
+// Una policy configurabile stabilisce quali campi identità sono ammessi
+// nel contesto upstream e quali vanno pseudonimizzati (hash salato, stesso
+// meccanismo degli export analytics); la policy si applica in tutti gli
+// handler chat nel punto unico in cui il contesto viene costruito.
+
+// MetadataPolicyConfig configura la policy (sezione metadata_policy)
+type MetadataPolicyConfig struct {
+	Enabled bool `json:"enabled"`
+	// AllowedFields: campi identità inoltrati al backend; i campi fuori
+	// lista vengono rimossi (vuoto = tutti ammessi)
+	AllowedFields []string `json:"allowed_fields"`
+	// HashFields: campi pseudonimizzati con hash salato prima dell'invio
+	HashFields []string `json:"hash_fields"`
+	// Purpose: finalità dichiarata, inoltrata al backend per l'audit GDPR
+	Purpose string `json:"purpose"`
+}
+
+// Campi identità soggetti alla policy (i campi tecnici come
+// context_type/intake/original_message non sono dati personali diretti)
+var metadataPolicyFields = []string{"asl", "asl_id", "user_id", "codice_fiscale", "username", "uoc"}
+
+// ApplyMetadataPolicy applica la policy di minimizzazione al contesto
+// prima dell'invio upstream: rimuove i campi non ammessi e pseudonimizza
+// quelli marcati, aggiungendo la finalità dichiarata
+func ApplyMetadataPolicy(config *Config, context map[string]interface{}) {
+	policy := config.MetadataPolicy
+	if !policy.Enabled || context == nil {
+		return
+	}
+
+	allowed := map[string]bool{}
+	for _, field := range policy.AllowedFields {
+		allowed[field] = true
+	}
+	hashed := map[string]bool{}
+	for _, field := range policy.HashFields {
+		hashed[field] = true
+	}
+
+	salt := anonymizationSalt(config)
+	removed, pseudonymized := 0, 0
+	for _, field := range metadataPolicyFields {
+		value, present := context[field].(string)
+		if !present || value == "" {
+			continue
+		}
+		if len(policy.AllowedFields) > 0 && !allowed[field] {
+			delete(context, field)
+			removed++
+			continue
+		}
+		if hashed[field] {
+			context[field] = AnonymizeIdentifier(salt, value)
+			pseudonymized++
+		}
+	}
+
+	if policy.Purpose != "" {
+		context["purpose"] = policy.Purpose
+	}
+	if removed > 0 || pseudonymized > 0 {
+		log.Printf("METADATA_POLICY_APPLIED: removed=%d, pseudonymized=%d", removed, pseudonymized)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metering per-ASL: registra turni di chat, secondi di streaming, minuti di
+// trascrizione e token del backend (se riportati) aggregati per mese e ASL.
+// I dati vengono persistiti su file JSON per sopravvivere ai riavvii.
+
+const (
+	meteringFile = "data/usage_metering.json"
+	meterNoASL   = "SCONOSCIUTA" // ASL non identificabile dalla richiesta
+	monthFormat  = "2006-01"
+)
+
+type UsageRecord struct {
+	Month                string  `json:"month"` // formato YYYY-MM
+	ASL                  string  `json:"asl"`
+	Turns                int64   `json:"turns"`
+	StreamedSeconds      float64 `json:"streamed_seconds"`
+	TranscriptionMinutes float64 `json:"transcription_minutes"`
+	BackendTokens        int64   `json:"backend_tokens"`
+}
+
+type usageStore struct {
+	records map[string]*UsageRecord // chiave: month|asl
+	mu      sync.Mutex
+	loaded  bool
+}
+
+var meterStore = &usageStore{
+	records: make(map[string]*UsageRecord),
+}
+
+func usageKey(month, asl string) string {
+	return month + "|" + asl
+}
+
+// ensureLoaded carica i dati persistiti al primo accesso (lazy loading)
+func (s *usageStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(meteringFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("METERING_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+
+	var records []*UsageRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		log.Printf("METERING_LOAD_ERROR: parse failed: %v", err)
+		return
+	}
+	for _, r := range records {
+		s.records[usageKey(r.Month, r.ASL)] = r
+	}
+	log.Printf("METERING_LOADED: %d record di utilizzo caricati da %s", len(records), meteringFile)
+}
+
+// persist salva lo stato corrente su file (chiamare con lock acquisito)
+func (s *usageStore) persist() {
+	records := make([]*UsageRecord, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		log.Printf("METERING_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.MkdirAll("data", 0755); err != nil {
+		log.Printf("METERING_PERSIST_ERROR: cannot create data directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(meteringFile, data, 0644); err != nil {
+		log.Printf("METERING_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// record applica una mutazione al record del mese corrente per l'ASL indicata
+func (s *usageStore) record(asl string, update func(*UsageRecord)) {
+	if asl == "" {
+		asl = meterNoASL
+	}
+	month := time.Now().Format(monthFormat)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ensureLoaded()
+
+	key := usageKey(month, asl)
+	rec, ok := s.records[key]
+	if !ok {
+		rec = &UsageRecord{Month: month, ASL: asl}
+		s.records[key] = rec
+	}
+	update(rec)
+	s.persist()
+}
+
+// MeterChatTurn registra un turno di chat completato, con eventuali token riportati dal backend
+func MeterChatTurn(asl string, backendTokens int64) {
+	meterStore.record(asl, func(r *UsageRecord) {
+		r.Turns++
+		r.BackendTokens += backendTokens
+	})
+}
+
+// MeterStreamedSeconds registra i secondi di streaming SSE consumati
+func MeterStreamedSeconds(asl string, seconds float64) {
+	meterStore.record(asl, func(r *UsageRecord) {
+		r.StreamedSeconds += seconds
+	})
+}
+
+// MeterTranscriptionMinutes registra i minuti di audio trascritti
+func MeterTranscriptionMinutes(asl string, minutes float64) {
+	meterStore.record(asl, func(r *UsageRecord) {
+		r.TranscriptionMinutes += minutes
+	})
+}
+
+// extractBackendTokens estrae il conteggio token dagli slot del risultato, se il backend lo riporta
+func extractBackendTokens(slots map[string]interface{}) int64 {
+	if slots == nil {
+		return 0
+	}
+	for _, key := range []string{"token_usage", "tokens_used", "total_tokens"} {
+		if v, ok := slots[key]; ok {
+			switch n := v.(type) {
+			case float64:
+				return int64(n)
+			case int:
+				return int64(n)
+			}
+		}
+	}
+	return 0
+}
+
+// HandleUsageExport restituisce il report mensile di utilizzo per ASL in formato CSV
+// GET /admin/api/usage?month=YYYY-MM (default: mese corrente)
+func HandleUsageExport(c *gin.Context) {
+	month := c.Query("month")
+	if month == "" {
+		month = time.Now().Format(monthFormat)
+	}
+	if _, err := time.Parse(monthFormat, month); err != nil {
+		AbortWithError(c, ErrInvalidParameter, "parametro month non valido, formato atteso YYYY-MM")
+		return
+	}
+
+	log.Printf("METERING_EXPORT_REQUEST: month=%s, client_ip=%s", month, c.ClientIP())
+
+	meterStore.mu.Lock()
+	meterStore.ensureLoaded()
+	var records []*UsageRecord
+	for _, r := range meterStore.records {
+		if r.Month == month {
+			records = append(records, r)
+		}
+	}
+	meterStore.mu.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ASL < records[j].ASL
+	})
+
+	var csv strings.Builder
+	csv.WriteString("month,asl,turns,streamed_seconds,transcription_minutes,backend_tokens\n")
+	for _, r := range records {
+		csv.WriteString(fmt.Sprintf("%s,%q,%d,%.1f,%.2f,%d\n",
+			r.Month, r.ASL, r.Turns, r.StreamedSeconds, r.TranscriptionMinutes, r.BackendTokens))
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"gias-usage-%s.csv\"", month))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", []byte(csv.String()))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveMiddlewareChainDefaults(t *testing.T) {
+	config := &Config{}
+	names, handlers := ResolveMiddlewareChain(config, "global")
+	expected := []string{"security_headers", "gzip_lowband", "load_shedding", "access_log"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("catena di default errata: %v", names)
+	}
+	if len(handlers) != len(names) {
+		t.Errorf("handlers e nomi disallineati: %d vs %d", len(handlers), len(names))
+	}
+}
+
+func TestResolveMiddlewareChainPreservesOrder(t *testing.T) {
+	config := &Config{
+		Middleware: MiddlewareConfig{
+			Chains: map[string][]string{
+				"api": {"cors", "body_size_limit", "access_log"},
+			},
+		},
+	}
+	names, _ := ResolveMiddlewareChain(config, "api")
+	expected := []string{"cors", "body_size_limit", "access_log"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("ordine della catena non rispettato: %v", names)
+	}
+}
+
+func TestResolveMiddlewareChainSkipsUnknown(t *testing.T) {
+	config := &Config{
+		Middleware: MiddlewareConfig{
+			Chains: map[string][]string{
+				"api": {"inesistente", "cors"},
+			},
+		},
+	}
+	names, _ := ResolveMiddlewareChain(config, "api")
+	if !reflect.DeepEqual(names, []string{"cors"}) {
+		t.Errorf("nome sconosciuto non saltato: %v", names)
+	}
+}
+
+func TestResolveMiddlewareChainEmptyDeclared(t *testing.T) {
+	// Una catena dichiarata vuota disattiva anche i default del gruppo
+	config := &Config{
+		Middleware: MiddlewareConfig{
+			Chains: map[string][]string{"global": {}},
+		},
+	}
+	names, _ := ResolveMiddlewareChain(config, "global")
+	if len(names) != 0 {
+		t.Errorf("catena vuota dichiarata deve restare vuota: %v", names)
+	}
+}
+
+func TestBodySizeLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(bodySizeLimitMiddleware(64))
+	r.POST("/echo", func(c *gin.Context) {
+		var payload map[string]interface{}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, payload)
+	})
+
+	small := httptest.NewRecorder()
+	r.ServeHTTP(small, httptest.NewRequest("POST", "/echo", strings.NewReader(`{"a":1}`)))
+	if small.Code != http.StatusOK {
+		t.Errorf("richiesta entro il limite rifiutata: %d", small.Code)
+	}
+
+	big := httptest.NewRecorder()
+	r.ServeHTTP(big, httptest.NewRequest("POST", "/echo", strings.NewReader(`{"a":"`+strings.Repeat("x", 256)+`"}`)))
+	if big.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("richiesta oltre il limite non rifiutata: %d", big.Code)
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(corsMiddleware([]string{"https://gisa.example"}))
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	allowed := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/ping", nil)
+	req.Header.Set("Origin", "https://gisa.example")
+	r.ServeHTTP(allowed, req)
+	if allowed.Header().Get("Access-Control-Allow-Origin") != "https://gisa.example" {
+		t.Errorf("origin ammesso senza header CORS")
+	}
+
+	denied := httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/ping", nil)
+	req.Header.Set("Origin", "https://altro.example")
+	r.ServeHTTP(denied, req)
+	if denied.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("origin non ammesso con header CORS")
+	}
+
+	preflight := httptest.NewRecorder()
+	req = httptest.NewRequest("OPTIONS", "/ping", nil)
+	req.Header.Set("Origin", "https://gisa.example")
+	r.ServeHTTP(preflight, req)
+	if preflight.Code != http.StatusNoContent {
+		t.Errorf("preflight non gestito: %d", preflight.Code)
+	}
+}
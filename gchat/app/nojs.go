@@ -0,0 +1,216 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Fallback accessibile senza JavaScript: gli utenti con screen reader su
+// terminali bloccati segnalano che il widget JS è inutilizzabile. In
+// modalità no-JS una form HTML fa POST su "/" e la conversazione viene
+// renderizzata lato server dalla cronologia, passando comunque per la
+// stessa pipeline chat (FAQ, health check, metering, analytics).
+
+const (
+	nojsConvTTL    = 30 * time.Minute // scadenza conversazioni inattive
+	nojsMaxTurns   = 40               // turni conservati per conversazione
+	nojsSessionKey = "nojs_conv"
+)
+
+type nojsTurn struct {
+	Role string // "user" | "bot"
+	Text string
+}
+
+type nojsConversation struct {
+	Turns     []nojsTurn
+	UpdatedAt time.Time
+}
+
+var (
+	nojsConvs   = map[string]*nojsConversation{}
+	nojsConvsMu sync.Mutex
+)
+
+// NoJSRequested: la modalità no-JS è attiva per GET con ?nojs=1 o per la
+// form POST che porta il campo "message"
+func NoJSRequested(c *gin.Context) bool {
+	if c.Query("nojs") == "1" {
+		return true
+	}
+	return c.Request.Method == http.MethodPost && c.PostForm("message") != ""
+}
+
+// nojsConversationID recupera (o crea) l'ID conversazione dalla sessione
+func nojsConversationID(c *gin.Context) string {
+	session := sessions.Default(c)
+	if id, ok := session.Get(nojsSessionKey).(string); ok && id != "" {
+		return id
+	}
+	id := newContinuationHandle()
+	session.Set(nojsSessionKey, id)
+	if err := session.Save(); err != nil {
+		log.Printf("NOJS_SESSION_ERROR: %v", err)
+	}
+	return id
+}
+
+// nojsAppend aggiunge turni alla conversazione e pota quelle scadute
+func nojsAppend(convID string, turns ...nojsTurn) {
+	nojsConvsMu.Lock()
+	defer nojsConvsMu.Unlock()
+
+	now := time.Now()
+	for id, conv := range nojsConvs {
+		if now.Sub(conv.UpdatedAt) > nojsConvTTL {
+			delete(nojsConvs, id)
+		}
+	}
+
+	conv, ok := nojsConvs[convID]
+	if !ok {
+		conv = &nojsConversation{}
+		nojsConvs[convID] = conv
+	}
+	conv.Turns = append(conv.Turns, turns...)
+	if len(conv.Turns) > nojsMaxTurns {
+		conv.Turns = conv.Turns[len(conv.Turns)-nojsMaxTurns:]
+	}
+	conv.UpdatedAt = now
+}
+
+// nojsTurns restituisce una copia dei turni correnti della conversazione
+func nojsTurns(convID string) []nojsTurn {
+	nojsConvsMu.Lock()
+	defer nojsConvsMu.Unlock()
+
+	conv, ok := nojsConvs[convID]
+	if !ok || time.Since(conv.UpdatedAt) > nojsConvTTL {
+		return nil
+	}
+	turns := make([]nojsTurn, len(conv.Turns))
+	copy(turns, conv.Turns)
+	return turns
+}
+
+// nojsFormatText: escape HTML + conversione \n in <br> per il rendering
+func nojsFormatText(text string) template.HTML {
+	escaped := template.HTMLEscapeString(text)
+	return template.HTML(strings.ReplaceAll(escaped, "\n", "<br>"))
+}
+
+// nojsSend invia il messaggio attraverso la stessa pipeline della chat JS
+// e accoda domanda e risposta alla cronologia server-side
+func nojsSend(c *gin.Context, config *Config, convID, message, userIDStr, aslID, aslName, codiceFiscale, username string) {
+	sender := "nojs_" + convID
+
+	// UOC da personale.csv se user_id disponibile (come HandleChat)
+	uoc := ""
+	if userIDStr != "" {
+		if userID, err := strconv.Atoi(userIDStr); err == nil {
+			if personale, err := GetPersonaleByUserID(userID); err == nil {
+				uoc = personale.DescrizioneAreaStrutturaComplessa
+			}
+		}
+	}
+
+	context := make(map[string]interface{})
+	if canonicalASL, ok := ResolveContextASL(aslName, userIDStr); ok {
+		context["asl"] = canonicalASL
+		aslName = canonicalASL
+	} else if aslID != "" {
+		context["asl_id"] = aslID
+	}
+	if userIDStr != "" {
+		context["user_id"] = userIDStr
+	}
+	if codiceFiscale != "" {
+		context["codice_fiscale"] = codiceFiscale
+	}
+	if username != "" {
+		context["username"] = username
+	}
+	if uoc != "" {
+		context["uoc"] = uoc
+	}
+
+	// Policy di minimizzazione dei metadati upstream (vedi metadata_policy.go)
+	ApplyMetadataPolicy(config, context)
+
+	// FAQ curate: match locale senza scomodare l'LLM
+	if entry, score, ok := MatchFAQ(message); ok {
+		log.Printf("NOJS_FAQ_HIT: conv=%s, faq=%s, score=%.2f", convID, entry.ID, score)
+		MeterChatTurn(aslName, 0)
+		SessionRecordTurn(c, sender)
+		nojsAppend(convID, nojsTurn{Role: "user", Text: message}, nojsTurn{Role: "bot", Text: entry.Answer})
+		return
+	}
+
+	if err := CheckLLMServerHealth(config.LLMServer.URL, config.LLMServer.Timeout); err != nil {
+		log.Printf("NOJS_ERROR: health check fallito - conv=%s, error=%v", convID, err)
+		nojsAppend(convID, nojsTurn{Role: "user", Text: message},
+			nojsTurn{Role: "bot", Text: "Il servizio non è al momento disponibile. Riprovare più tardi."})
+		return
+	}
+
+	start := time.Now()
+	v1Resp, err := SendToLLMV1(message, sender, config.LLMServer.URL, config.LLMServer.Timeout, context)
+	if err != nil {
+		log.Printf("NOJS_ERROR: LLM failed - conv=%s, duration=%v, error=%v", convID, time.Since(start), err)
+		nojsAppend(convID, nojsTurn{Role: "user", Text: message},
+			nojsTurn{Role: "bot", Text: "Si è verificato un errore nell'elaborazione della richiesta. Riprovare più tardi."})
+		return
+	}
+
+	log.Printf("NOJS_SUCCESS: conv=%s, intent=%s, response_length=%d, duration=%v",
+		convID, v1Resp.Result.Intent, len(v1Resp.Result.Text), time.Since(start))
+
+	MeterChatTurn(aslName, extractBackendTokens(v1Resp.Result.Slots))
+	SessionRecordTurn(c, sender)
+	AutoTitleConversation(sender, message)
+
+	nojsAppend(convID, nojsTurn{Role: "user", Text: message}, nojsTurn{Role: "bot", Text: v1Resp.Result.Text})
+}
+
+// HandleNoJS serve la chat senza JavaScript: POST invia il messaggio e fa
+// redirect sulla GET (pattern post/redirect/get per evitare il reinvio al
+// refresh), GET renderizza la conversazione dal template nojs.html
+func HandleNoJS(c *gin.Context, config *Config, basePath string) {
+	userIDStr, aslID, aslName, codiceFiscale, username := MergeSessionParams(c)
+	convID := nojsConversationID(c)
+
+	if c.Request.Method == http.MethodPost {
+		message := strings.TrimSpace(c.PostForm("message"))
+		if message != "" {
+			nojsSend(c, config, convID, message, userIDStr, aslID, aslName, codiceFiscale, username)
+		}
+		c.Redirect(http.StatusSeeOther, basePath+"/?nojs=1")
+		return
+	}
+
+	turns := nojsTurns(convID)
+	rendered := make([]gin.H, 0, len(turns))
+	for _, turn := range turns {
+		rendered = append(rendered, gin.H{
+			"role": turn.Role,
+			"html": nojsFormatText(turn.Text),
+		})
+	}
+
+	log.Printf("NOJS_REQUEST: conv=%s, turns=%d, client_ip=%s", convID, len(turns), c.ClientIP())
+
+	c.HTML(http.StatusOK, "nojs.html", gin.H{
+		"title":          "Assistente Gisa (versione accessibile)",
+		"basePath":       basePath,
+		"welcomeMessage": config.UI.WelcomeMessage,
+		"turns":          rendered,
+	})
+}
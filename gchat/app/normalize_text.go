@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizzazione pre-flight dei messaggi verso il backend: virgolette
+// tipografiche, emoji e caratteri zero-width (incollati da Word, PDF o
+// WhatsApp) ogni tanto mandano in confusione l'NLU. Prima di SendToLLM il
+// testo viene portato in Unicode NFC, ripulito dai caratteri di controllo,
+// con gli spazi collassati e le emoji gestite secondo configurazione.
+// L'originale resta intatto per display e cronologia (original_message).
+
+// NormalizeTextConfig configura la normalizzazione (sezione normalize_text)
+type NormalizeTextConfig struct {
+	Enabled bool `json:"enabled"`
+	// EmojiHandling: "keep" (default), "strip" (rimosse), "space" (sostituite da spazio)
+	EmojiHandling string `json:"emoji_handling"`
+}
+
+// Virgolette e trattini tipografici ricondotti agli equivalenti ASCII
+var typographicReplacer = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "«", `"`, "»", `"`,
+	"–", "-", "—", "-", "…", "...",
+	" ", " ", // non-breaking space
+)
+
+// isZeroWidth riconosce i caratteri invisibili che rompono la tokenizzazione
+// (zero-width space/joiner/non-joiner, word joiner, BOM)
+func isZeroWidth(r rune) bool {
+	switch r {
+	case '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff':
+		return true
+	}
+	return false
+}
+
+// isEmoji riconosce i range Unicode delle emoji e dei simboli pittografici
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F300 && r <= 0x1FAFF: // pittogrammi, emoticon, simboli estesi
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // simboli vari e dingbat
+		return true
+	case r == 0xFE0F || r == 0xFE0E: // variation selector
+		return true
+	}
+	return false
+}
+
+// NormalizeMessageText applica la normalizzazione e ritorna il testo
+// elaborato più true se è cambiato rispetto all'originale
+func NormalizeMessageText(config *Config, message string) (string, bool) {
+	if !config.NormalizeText.Enabled {
+		return message, false
+	}
+
+	// Unicode NFC: ricompone le sequenze decomposte (è vs e + accento)
+	normalized := norm.NFC.String(message)
+	normalized = typographicReplacer.Replace(normalized)
+
+	var b strings.Builder
+	b.Grow(len(normalized))
+	for _, r := range normalized {
+		if isZeroWidth(r) {
+			continue
+		}
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			continue
+		}
+		if isEmoji(r) {
+			switch config.NormalizeText.EmojiHandling {
+			case "strip":
+				continue
+			case "space":
+				b.WriteRune(' ')
+				continue
+			}
+		}
+		b.WriteRune(r)
+	}
+
+	// Collassa gli spazi consecutivi preservando gli a capo singoli
+	result := strings.Join(strings.Fields(b.String()), " ")
+	result = strings.TrimSpace(result)
+
+	if result == message || result == "" {
+		return message, false
+	}
+	log.Printf("NORMALIZE_APPLIED: \"%s\" -> \"%s\"", message, result)
+	return result, true
+}
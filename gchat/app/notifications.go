@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Inbox notifiche per i risultati asincroni: con i job in coda e le
+// escalation al supporto l'esito può arrivare quando l'utente ha già
+// chiuso la chat. Le notifiche vengono persistite su file, elencate via
+// GET /api/notifications e consegnate anche sul canale eventi live
+// (evento SSE "notification") quando l'utente è online.
+
+const (
+	notificationsFile = "data/notifications.json"
+	// notificationsMaxPerSender: le più vecchie oltre la soglia vengono scartate
+	notificationsMaxPerSender = 50
+)
+
+// Notification è una singola voce dell'inbox utente
+type Notification struct {
+	ID        string `json:"id"`
+	Sender    string `json:"sender"`
+	Kind      string `json:"kind"` // job_completed | job_failed | escalation | info
+	Title     string `json:"title"`
+	Body      string `json:"body,omitempty"`
+	JobID     string `json:"job_id,omitempty"`
+	Read      bool   `json:"read"`
+	Delivered bool   `json:"delivered"` // già consegnata su un canale live
+	CreatedAt int64  `json:"created_at"`
+}
+
+type notificationStore struct {
+	items  map[string]*Notification
+	mu     sync.Mutex
+	loaded bool
+}
+
+var notificationInbox = &notificationStore{items: map[string]*Notification{}}
+
+func (s *notificationStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(notificationsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("NOTIFY_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	var list []*Notification
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("NOTIFY_LOAD_ERROR: parse failed: %v", err)
+		return
+	}
+	for _, n := range list {
+		s.items[n.ID] = n
+	}
+}
+
+// persist salva su file (chiamare con lock acquisito)
+func (s *notificationStore) persist() {
+	list := make([]*Notification, 0, len(s.items))
+	for _, n := range s.items {
+		list = append(list, n)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		log.Printf("NOTIFY_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.WriteFile(notificationsFile, data, 0644); err != nil {
+		log.Printf("NOTIFY_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// pruneSender scarta le notifiche più vecchie oltre la soglia per sender
+// (chiamare con lock acquisito)
+func (s *notificationStore) pruneSender(sender string) {
+	var list []*Notification
+	for _, n := range s.items {
+		if n.Sender == sender {
+			list = append(list, n)
+		}
+	}
+	if len(list) <= notificationsMaxPerSender {
+		return
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt < list[j].CreatedAt })
+	for _, n := range list[:len(list)-notificationsMaxPerSender] {
+		delete(s.items, n.ID)
+	}
+}
+
+// NotifyUser accoda una notifica nell'inbox dell'utente
+func NotifyUser(sender, kind, title, body, jobID string) {
+	if sender == "" {
+		return
+	}
+
+	notificationInbox.mu.Lock()
+	defer notificationInbox.mu.Unlock()
+	notificationInbox.ensureLoaded()
+
+	n := &Notification{
+		ID:        newContinuationHandle(),
+		Sender:    sender,
+		Kind:      kind,
+		Title:     title,
+		Body:      body,
+		JobID:     jobID,
+		CreatedAt: time.Now().Unix(),
+	}
+	notificationInbox.items[n.ID] = n
+	notificationInbox.pruneSender(sender)
+	notificationInbox.persist()
+
+	log.Printf("NOTIFY_NEW: id=%s, sender=%s, kind=%s, title=%s", n.ID, sender, kind, title)
+}
+
+// PendingNotifications ritorna le notifiche non ancora consegnate su un
+// canale live e le marca come consegnate (ma non lette): usata all'apertura
+// di uno stream per recapitarle all'utente online.
+func PendingNotifications(sender string) []*Notification {
+	notificationInbox.mu.Lock()
+	defer notificationInbox.mu.Unlock()
+	notificationInbox.ensureLoaded()
+
+	var pending []*Notification
+	for _, n := range notificationInbox.items {
+		if n.Sender == sender && !n.Delivered {
+			n.Delivered = true
+			copied := *n
+			pending = append(pending, &copied)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	notificationInbox.persist()
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt < pending[j].CreatedAt })
+	return pending
+}
+
+// HandleListNotifications elenca l'inbox di un utente, più recenti per prime
+// GET /api/notifications?sender=&unread=true
+func HandleListNotifications(c *gin.Context) {
+	sender := c.Query("sender")
+	if sender == "" {
+		AbortWithError(c, ErrInvalidParameter, "parametro sender obbligatorio")
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	notificationInbox.mu.Lock()
+	notificationInbox.ensureLoaded()
+	var list []*Notification
+	unread := 0
+	for _, n := range notificationInbox.items {
+		if n.Sender != sender {
+			continue
+		}
+		if !n.Read {
+			unread++
+		}
+		if unreadOnly && n.Read {
+			continue
+		}
+		copied := *n
+		list = append(list, &copied)
+	}
+	notificationInbox.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt > list[j].CreatedAt })
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":        "success",
+		"notifications": list,
+		"unread_count":  unread,
+	})
+}
+
+// HandleMarkNotificationRead marca una notifica come letta
+// POST /api/notifications/:id/read
+func HandleMarkNotificationRead(c *gin.Context) {
+	id := c.Param("id")
+
+	notificationInbox.mu.Lock()
+	defer notificationInbox.mu.Unlock()
+	notificationInbox.ensureLoaded()
+
+	n, ok := notificationInbox.items[id]
+	if !ok {
+		AbortWithError(c, ErrNotFound, "notifica non trovata")
+		return
+	}
+	if !n.Read {
+		n.Read = true
+		n.Delivered = true
+		notificationInbox.persist()
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "id": id, "read": true})
+}
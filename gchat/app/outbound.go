@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Trasporti HTTP outbound configurabili: il datacenter obbliga l'egress
+// attraverso un proxy autenticato con CA interna. La sezione outbound di
+// config.json permette di indicare, per upstream (llm, whisper), il proxy,
+// un bundle CA custom e opzionalmente certificato/chiave client per mTLS.
+// Senza configurazione si usa il comportamento di default di net/http
+// (incluse le variabili HTTP(S)_PROXY di ambiente).
+
+// UpstreamTransportConfig descrive il trasporto verso un singolo upstream
+type UpstreamTransportConfig struct {
+	ProxyURL   string `json:"proxy_url"`   // proxy dedicato (vuoto = env HTTP(S)_PROXY)
+	CABundle   string `json:"ca_bundle"`   // path PEM della CA interna
+	ClientCert string `json:"client_cert"` // certificato client per mTLS (path PEM)
+	ClientKey  string `json:"client_key"`  // chiave privata del certificato client
+}
+
+// OutboundConfig raggruppa i trasporti per upstream in config.json
+type OutboundConfig struct {
+	LLM     UpstreamTransportConfig `json:"llm"`
+	Whisper UpstreamTransportConfig `json:"whisper"`
+}
+
+type transportCache struct {
+	transports map[string]*http.Transport
+	mu         sync.Mutex
+}
+
+var outboundTransports = &transportCache{
+	transports: make(map[string]*http.Transport),
+}
+
+// upstreamTransportConfig risolve la configurazione per nome upstream
+func upstreamTransportConfig(config *Config, upstream string) UpstreamTransportConfig {
+	switch upstream {
+	case "whisper":
+		return config.Outbound.Whisper
+	default:
+		return config.Outbound.LLM
+	}
+}
+
+// buildTransport costruisce il trasporto per un upstream dalla config
+func buildTransport(upstream string, cfg UpstreamTransportConfig) *http.Transport {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+	}
+
+	if cfg.ProxyURL != "" {
+		proxyURL, err := url.Parse(cfg.ProxyURL)
+		if err != nil {
+			log.Printf("OUTBOUND_CONFIG_ERROR: upstream=%s, proxy_url non valido: %v", upstream, err)
+		} else {
+			transport.Proxy = http.ProxyURL(proxyURL)
+			log.Printf("OUTBOUND_PROXY: upstream=%s, proxy=%s", upstream, proxyURL.Host)
+		}
+	}
+
+	tlsConfig := &tls.Config{}
+	configured := false
+
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			log.Printf("OUTBOUND_CONFIG_ERROR: upstream=%s, lettura CA bundle fallita: %v", upstream, err)
+		} else {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				log.Printf("OUTBOUND_CONFIG_ERROR: upstream=%s, nessun certificato valido in %s", upstream, cfg.CABundle)
+			} else {
+				tlsConfig.RootCAs = pool
+				configured = true
+				log.Printf("OUTBOUND_CA: upstream=%s, bundle=%s", upstream, cfg.CABundle)
+			}
+		}
+	}
+
+	if cfg.ClientCert != "" && cfg.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			log.Printf("OUTBOUND_CONFIG_ERROR: upstream=%s, caricamento certificato client fallito: %v", upstream, err)
+		} else {
+			tlsConfig.Certificates = []tls.Certificate{cert}
+			configured = true
+			log.Printf("OUTBOUND_MTLS: upstream=%s, certificato client caricato", upstream)
+		}
+	}
+
+	if configured {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport
+}
+
+// upstreamTransport ritorna il trasporto (cacheato) per l'upstream
+func upstreamTransport(upstream string) *http.Transport {
+	outboundTransports.mu.Lock()
+	defer outboundTransports.mu.Unlock()
+
+	if transport, ok := outboundTransports.transports[upstream]; ok {
+		return transport
+	}
+	transport := buildTransport(upstream, upstreamTransportConfig(LoadConfig(), upstream))
+	outboundTransports.transports[upstream] = transport
+	return transport
+}
+
+// NewUpstreamClient costruisce un client HTTP con il trasporto configurato
+// per l'upstream indicato ("llm" o "whisper") e il timeout richiesto
+func NewUpstreamClient(upstream string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: upstreamTransport(upstream),
+	}
+}
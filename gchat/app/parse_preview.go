@@ -0,0 +1,145 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Anteprima intent in tempo reale per la debug UI: mentre l'utente
+// digita, il pannello mostra l'intent previsto. L'endpoint incapsula
+// ParseMessage con cache e rate limiting per non martellare il backend,
+// ritorna solo intent e confidenza (niente entity con PII) ed è attivo
+// solo con il feature flag debug_parse_preview.
+
+const (
+	parsePreviewCacheTTL    = 30 * time.Second
+	parsePreviewMaxPerSec   = 5   // richieste al secondo per client IP
+	parsePreviewMaxMsgChars = 500 // testo più lungo viene rifiutato
+)
+
+type parsePreviewEntry struct {
+	intent     string
+	confidence float64
+	cachedAt   time.Time
+}
+
+type parsePreviewState struct {
+	cache     map[string]*parsePreviewEntry
+	lastCalls map[string][]time.Time // client IP -> timestamp richieste recenti
+	mu        sync.Mutex
+}
+
+var parsePreview = &parsePreviewState{
+	cache:     make(map[string]*parsePreviewEntry),
+	lastCalls: make(map[string][]time.Time),
+}
+
+// parsePreviewThrottled verifica il rate limit per il client (true = bloccato)
+func parsePreviewThrottled(clientIP string) bool {
+	now := time.Now()
+	cutoff := now.Add(-time.Second)
+
+	parsePreview.mu.Lock()
+	defer parsePreview.mu.Unlock()
+
+	recent := parsePreview.lastCalls[clientIP][:0]
+	for _, t := range parsePreview.lastCalls[clientIP] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= parsePreviewMaxPerSec {
+		parsePreview.lastCalls[clientIP] = recent
+		return true
+	}
+	parsePreview.lastCalls[clientIP] = append(recent, now)
+	return false
+}
+
+// HandleParsePreview ritorna intent e confidenza per il testo in digitazione
+// POST /api/parse-preview  body: {"message": "...", "asl": "..."}
+func HandleParsePreview(c *gin.Context) {
+	var req struct {
+		Message string `json:"message"`
+		ASL     string `json:"asl,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	if !FeatureEnabled("debug_parse_preview", req.ASL) {
+		AbortWithError(c, ErrNotFound, "anteprima intent non attiva")
+		return
+	}
+
+	message := strings.TrimSpace(req.Message)
+	if message == "" || len(message) > parsePreviewMaxMsgChars {
+		AbortWithError(c, ErrInvalidParameter, "messaggio vuoto o troppo lungo")
+		return
+	}
+
+	clientIP := c.ClientIP()
+	if parsePreviewThrottled(clientIP) {
+		c.Header("Retry-After", "1")
+		AbortWithError(c, ErrRateLimited, "troppe richieste di anteprima, riprovare tra un secondo")
+		return
+	}
+
+	cacheKey := normalizeFAQText(message)
+
+	parsePreview.mu.Lock()
+	if entry, ok := parsePreview.cache[cacheKey]; ok && time.Since(entry.cachedAt) < parsePreviewCacheTTL {
+		parsePreview.mu.Unlock()
+		log.Printf("PARSE_PREVIEW_CACHE_HIT: client_ip=%s, intent=%s", clientIP, entry.intent)
+		c.JSON(http.StatusOK, gin.H{
+			"status":     "success",
+			"intent":     entry.intent,
+			"confidence": entry.confidence,
+			"cached":     true,
+		})
+		return
+	}
+	parsePreview.mu.Unlock()
+
+	config := LoadConfig()
+	context := map[string]interface{}{}
+	if req.ASL != "" {
+		context["asl"] = req.ASL
+	}
+
+	parseResp, err := ParseMessage(message, config.LLMServer.URL, config.LLMServer.Timeout, context)
+	if err != nil {
+		log.Printf("PARSE_PREVIEW_ERROR: client_ip=%s, error=%v", clientIP, err)
+		AbortWithError(c, ErrBackendError, err.Error())
+		return
+	}
+
+	parsePreview.mu.Lock()
+	parsePreview.cache[cacheKey] = &parsePreviewEntry{
+		intent:     parseResp.Intent,
+		confidence: parseResp.Confidence,
+		cachedAt:   time.Now(),
+	}
+	// Contenimento memoria: la cache non cresce oltre qualche centinaio di voci
+	if len(parsePreview.cache) > 500 {
+		for key, entry := range parsePreview.cache {
+			if time.Since(entry.cachedAt) >= parsePreviewCacheTTL {
+				delete(parsePreview.cache, key)
+			}
+		}
+	}
+	parsePreview.mu.Unlock()
+
+	log.Printf("PARSE_PREVIEW: client_ip=%s, intent=%s, confidence=%.2f", clientIP, parseResp.Intent, parseResp.Confidence)
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"intent":     parseResp.Intent,
+		"confidence": parseResp.Confidence,
+	})
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry dei partial per data_type: ogni nuovo data_type del backend
+// richiedeva un blocco template copiato e incollato. I partial vivono in
+// template/partials/<data_type>.html, vengono scoperti all'avvio (basta
+// aggiungere un file, nessuna modifica al codice) e un renderer tabellare
+// generico fa da fallback per i data_type tabulari senza partial dedicato.
+
+const partialsDir = "template/partials"
+
+// partialData è il contesto passato a ogni partial
+type partialData struct {
+	DataType string
+	Data     interface{}
+}
+
+// tableData è il contesto del renderer tabellare generico
+type tableData struct {
+	Headers []string
+	Rows    [][]string
+}
+
+var (
+	partialTemplates = map[string]*template.Template{}
+	partialsMu       sync.RWMutex
+)
+
+// LoadPartials scopre e compila i partial presenti in template/partials
+// (il nome file senza estensione è il data_type servito)
+func LoadPartials() {
+	files, err := filepath.Glob(filepath.Join(partialsDir, "*.html"))
+	if err != nil {
+		log.Printf("PARTIAL_LOAD_ERROR: %v", err)
+		return
+	}
+
+	partialsMu.Lock()
+	defer partialsMu.Unlock()
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".html")
+		tmpl, err := template.ParseFiles(file)
+		if err != nil {
+			log.Printf("PARTIAL_PARSE_ERROR: file=%s, error=%v", file, err)
+			continue
+		}
+		partialTemplates[name] = tmpl
+		log.Printf("PARTIAL_LOADED: data_type=%s, file=%s", name, file)
+	}
+}
+
+// normalizeTable converte un payload tabulare ([]map o map di colonne)
+// in headers ordinati e righe testuali; false se la forma non è tabulare
+func normalizeTable(data interface{}) (*tableData, bool) {
+	rows, ok := data.([]interface{})
+	if !ok || len(rows) == 0 {
+		return nil, false
+	}
+
+	headerSet := map[string]bool{}
+	maps := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		rowMap, ok := row.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		maps = append(maps, rowMap)
+		for key := range rowMap {
+			headerSet[key] = true
+		}
+	}
+
+	headers := make([]string, 0, len(headerSet))
+	for key := range headerSet {
+		headers = append(headers, key)
+	}
+	sort.Strings(headers)
+
+	table := &tableData{Headers: headers}
+	for _, rowMap := range maps {
+		row := make([]string, len(headers))
+		for i, header := range headers {
+			if value, ok := rowMap[header]; ok && value != nil {
+				row[i] = fmt.Sprintf("%v", value)
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, true
+}
+
+// RenderPartial renderizza il partial registrato per il data_type, con
+// fallback sul renderer tabellare generico per i payload tabulari.
+// Ritorna false se non c'è nulla di renderizzabile.
+func RenderPartial(dataType string, data interface{}) (string, bool) {
+	if dataType == "" || data == nil {
+		return "", false
+	}
+
+	partialsMu.RLock()
+	tmpl, ok := partialTemplates[dataType]
+	fallback := partialTemplates["generic_table"]
+	partialsMu.RUnlock()
+
+	var buf strings.Builder
+	if ok {
+		if err := tmpl.Execute(&buf, partialData{DataType: dataType, Data: data}); err != nil {
+			log.Printf("PARTIAL_RENDER_ERROR: data_type=%s, error=%v", dataType, err)
+			return "", false
+		}
+		return buf.String(), true
+	}
+
+	// Fallback: tabella generica per i payload tabulari
+	if fallback != nil {
+		if table, tabular := normalizeTable(data); tabular {
+			if err := fallback.Execute(&buf, table); err != nil {
+				log.Printf("PARTIAL_RENDER_ERROR: data_type=%s (fallback), error=%v", dataType, err)
+				return "", false
+			}
+			return buf.String(), true
+		}
+	}
+	return "", false
+}
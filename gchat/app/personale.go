@@ -3,51 +3,56 @@ package main
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 type PersonaleRecord struct {
-	ASL                             string `json:"asl"`
+	ASL                               string `json:"asl"`
 	DescrizioneAreaStrutturaComplessa string `json:"descrizione_area_struttura_complessa"`
-	Descrizione                     string `json:"descrizione"`
-	NameFirst                       string `json:"namefirst"`
-	NameLast                        string `json:"namelast"`
-	CodiceFiscale                   string `json:"codice_fiscale"`
-	UserID                          int    `json:"user_id"`
+	Descrizione                       string `json:"descrizione"`
+	NameFirst                         string `json:"namefirst"`
+	NameLast                          string `json:"namelast"`
+	CodiceFiscale                     string `json:"codice_fiscale"`
+	UserID                            int    `json:"user_id"`
 }
 
 // Cache structure for personale data
 type personaleCache struct {
-	data     map[int]PersonaleRecord
-	modTime  time.Time
-	mu       sync.RWMutex
+	data      map[int]PersonaleRecord
+	fileTimes map[string]time.Time // mtime per file sorgente all'ultimo load
+	fileStats map[string]PersonaleFileStat
+	loadedAt  time.Time
+	skipped   map[string]int // righe scartate per motivo nell'ultimo load
+	mu        sync.RWMutex
 }
 
 var (
-	cache     = &personaleCache{}
-	csvFile   = "data/personale.csv"
+	cache   = &personaleCache{}
+	csvFile = "data/personale.csv"
 )
 
-// LoadPersonaleData loads data with caching based on file modification time
+// LoadPersonaleData loads data with caching based on file modification time.
+// Le sorgenti sono i CSV per-ASL in data/personale/ se la directory esiste,
+// altrimenti il singolo data/personale.csv (vedi personale_multi.go).
 func LoadPersonaleData() (map[int]PersonaleRecord, error) {
-	cache.mu.RLock()
-
-	// Check if file exists and get modification time
-	info, err := os.Stat(csvFile)
+	sources, err := personaleSources()
 	if err != nil {
-		cache.mu.RUnlock()
-		return nil, fmt.Errorf("error accessing personale.csv: %v", err)
+		return nil, err
 	}
 
-	// If cache is valid (file hasn't been modified), return cached data
-	if cache.data != nil && !info.ModTime().After(cache.modTime) {
+	cache.mu.RLock()
+
+	// If cache is valid (no source file modified), return cached data
+	if cache.data != nil && personaleCacheValid(sources) {
 		data := cache.data
 		cache.mu.RUnlock()
-		log.Printf("PERSONALE_CACHE: Using cached data (file unchanged since %s)", cache.modTime.Format("2006-01-02 15:04:05"))
+		log.Printf("PERSONALE_CACHE: Using cached data (sources unchanged since %s)", cache.loadedAt.Format("2006-01-02 15:04:05"))
 		return data, nil
 	}
 
@@ -58,65 +63,182 @@ func LoadPersonaleData() (map[int]PersonaleRecord, error) {
 	defer cache.mu.Unlock()
 
 	// Double-check pattern - another goroutine might have loaded while we waited
-	if cache.data != nil && !info.ModTime().After(cache.modTime) {
+	if cache.data != nil && personaleCacheValid(sources) {
 		log.Printf("PERSONALE_CACHE: Using cached data (loaded by another goroutine)")
 		return cache.data, nil
 	}
 
-	log.Printf("PERSONALE_CACHE: Loading CSV file (last modified: %s)", info.ModTime().Format("2006-01-02 15:04:05"))
+	return loadPersonaleSources(sources)
+}
+
+// personaleColumns sono gli indici delle colonne rilevanti nel CSV,
+// risolti dall'header per nome (con fallback posizionale per i file
+// storici senza header riconoscibile)
+type personaleColumns struct {
+	asl, area, descrizione, nameFirst, nameLast, cf, userID int
+	minFields                                               int
+}
+
+// personaleDefaultColumns è il layout posizionale storico del CSV:
+// ASL,DescrizioneAreaStrutturaComplessa,Descrizione,NameFirst,NameLast,CodiceFiscale,UserID
+var personaleDefaultColumns = personaleColumns{
+	asl: 0, area: 1, descrizione: 2, nameFirst: 3, nameLast: 4, cf: 5, userID: 6,
+	minFields: 7,
+}
+
+// resolvePersonaleColumns mappa le colonne dall'header per nome
+// (case-insensitive, spazi e underscore ignorati). Se l'header non
+// contiene le colonne obbligatorie si resta sul layout posizionale.
+func resolvePersonaleColumns(header []string) personaleColumns {
+	normalize := func(s string) string {
+		var sb strings.Builder
+		for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+			if r != ' ' && r != '_' && r != '-' {
+				sb.WriteRune(r)
+			}
+		}
+		return sb.String()
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		index[normalize(name)] = i
+	}
+
+	cols := personaleColumns{asl: -1, area: -1, descrizione: -1, nameFirst: -1, nameLast: -1, cf: -1, userID: -1}
+	lookup := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := index[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	cols.asl = lookup("asl")
+	cols.area = lookup("descrizioneareastrutturacomplessa", "uoc")
+	cols.descrizione = lookup("descrizione")
+	cols.nameFirst = lookup("namefirst", "nome")
+	cols.nameLast = lookup("namelast", "cognome")
+	cols.cf = lookup("codicefiscale", "cf")
+	cols.userID = lookup("userid")
+
+	// Colonne obbligatorie: senza ASL e UserID nell'header il file usa il
+	// layout storico senza nomi colonna
+	if cols.asl < 0 || cols.userID < 0 {
+		return personaleDefaultColumns
+	}
+	for _, i := range []int{cols.asl, cols.area, cols.descrizione, cols.nameFirst, cols.nameLast, cols.cf, cols.userID} {
+		if i >= cols.minFields {
+			cols.minFields = i + 1
+		}
+	}
+	return cols
+}
+
+// colValue ritorna il valore della colonna, o stringa vuota se la colonna
+// è assente dall'header (campo opzionale non esportato dall'ASL)
+func colValue(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return record[index]
+}
+
+// parsePersonaleFile legge un singolo CSV in streaming riga per riga
+// (niente ReadAll: con 200k+ righe il doppio buffering in memoria pesa) e
+// accumula le righe valide in personaleMap, registrando gli scarti (e i
+// conflitti tra file) in skipped
+func parsePersonaleFile(path string, personaleMap map[int]PersonaleRecord, owner map[int]string, skipped map[string]int) (PersonaleFileStat, error) {
+	stat := PersonaleFileStat{}
 
-	file, err := os.Open(csvFile)
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("error opening personale.csv: %v", err)
+		return stat, fmt.Errorf("error opening %s: %v", path, err)
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return nil, fmt.Errorf("error reading CSV: %v", err)
-	}
+	// Righe con numero di campi variabile gestite a mano; il buffer della
+	// riga viene riusato perché i valori sono copiati nel record
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
 
-	if len(records) == 0 {
-		return nil, fmt.Errorf("empty CSV file")
+	header, err := reader.Read()
+	if err != nil {
+		return stat, fmt.Errorf("error reading CSV header %s: %v", path, err)
 	}
+	cols := resolvePersonaleColumns(header)
 
-	// Skip header row
-	records = records[1:]
-
-	personaleMap := make(map[int]PersonaleRecord)
-	validRecords := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stat, fmt.Errorf("error reading CSV %s: %v", path, err)
+		}
 
-	for _, record := range records {
-		if len(record) < 7 {
+		if len(record) < cols.minFields {
+			skipped["colonne insufficienti"]++
+			stat.RowsSkipped++
 			continue // Skip malformed records
 		}
 
-		userID, err := strconv.Atoi(record[6])
+		userID, err := strconv.Atoi(record[cols.userID])
 		if err != nil {
+			skipped["user_id non numerico"]++
+			stat.RowsSkipped++
 			continue // Skip records with invalid user_id
 		}
 
+		if prev, dup := owner[userID]; dup {
+			if prev != path {
+				// Conflitto tra file diversi: vince il primo, la riga
+				// successiva viene scartata e segnalata
+				skipped["conflitto user_id tra file"]++
+				stat.RowsSkipped++
+				log.Printf("PERSONALE_CONFLICT: user_id=%d presente sia in %s che in %s, mantengo il primo", userID, prev, path)
+				continue
+			}
+			skipped["user_id duplicato"]++
+		}
+
 		personale := PersonaleRecord{
-			ASL:                             record[0],
-			DescrizioneAreaStrutturaComplessa: record[1],
-			Descrizione:                     record[2],
-			NameFirst:                       record[3],
-			NameLast:                        record[4],
-			CodiceFiscale:                   record[5],
-			UserID:                          userID,
+			ASL:                               colValue(record, cols.asl),
+			DescrizioneAreaStrutturaComplessa: colValue(record, cols.area),
+			Descrizione:                       colValue(record, cols.descrizione),
+			NameFirst:                         colValue(record, cols.nameFirst),
+			NameLast:                          colValue(record, cols.nameLast),
+			CodiceFiscale:                     colValue(record, cols.cf),
+			UserID:                            userID,
 		}
 
 		personaleMap[userID] = personale
-		validRecords++
+		owner[userID] = path
+		stat.RowsLoaded++
 	}
 
-	// Update cache
-	cache.data = personaleMap
-	cache.modTime = info.ModTime()
+	return stat, nil
+}
 
-	log.Printf("PERSONALE_CACHE: Loaded %d valid records from CSV", validRecords)
-	return personaleMap, nil
+// InvalidatePersonaleCache forza il reload dei CSV alla prossima lettura
+// (usata dal reload esplicito via SIGHUP/admin, vedi personale_reload.go)
+func InvalidatePersonaleCache() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.data = nil
+	cache.fileTimes = nil
+}
+
+// PersonaleLoadStats ritorna i conteggi dell'ultimo caricamento dei CSV
+func PersonaleLoadStats() (loaded int, skipped map[string]int, loadedAt time.Time) {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	skipped = make(map[string]int, len(cache.skipped))
+	for reason, n := range cache.skipped {
+		skipped[reason] = n
+	}
+	return len(cache.data), skipped, cache.loadedAt
 }
 
 func GetPersonaleByUserID(userID int) (*PersonaleRecord, error) {
@@ -130,4 +252,4 @@ func GetPersonaleByUserID(userID int) (*PersonaleRecord, error) {
 	}
 
 	return nil, fmt.Errorf("user with ID %d not found", userID)
-}
\ No newline at end of file
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supporto multi-file per il personale: ogni ASL esporta il proprio CSV e
+// l'unione manuale in un unico personale.csv è fonte di errori. Se esiste
+// la directory data/personale/ il gateway carica e fonde tutti i suoi
+// *.csv (ordine alfabetico, il primo file vince sui conflitti di user_id),
+// con statistiche per file e invalidazione della cache sul mtime di
+// ciascun file. In assenza della directory resta il singolo
+// data/personale.csv.
+
+// personaleDir è la directory opzionale dei CSV per-ASL
+const personaleDir = "data/personale"
+
+// PersonaleFileStat riporta l'esito del caricamento di un singolo CSV
+type PersonaleFileStat struct {
+	RowsLoaded  int   `json:"rows_loaded"`
+	RowsSkipped int   `json:"rows_skipped"`
+	ModTime     int64 `json:"mod_time"`
+}
+
+// personaleSources ritorna i CSV da caricare: i file della directory
+// per-ASL se presente, altrimenti il singolo personale.csv
+func personaleSources() ([]string, error) {
+	if info, err := os.Stat(personaleDir); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(personaleDir)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", personaleDir, err)
+		}
+		var sources []string
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".csv") {
+				continue
+			}
+			sources = append(sources, filepath.Join(personaleDir, entry.Name()))
+		}
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("no CSV files in %s", personaleDir)
+		}
+		sort.Strings(sources)
+		return sources, nil
+	}
+
+	if _, err := os.Stat(csvFile); err != nil {
+		return nil, fmt.Errorf("error accessing personale.csv: %v", err)
+	}
+	return []string{csvFile}, nil
+}
+
+// personaleCacheValid verifica che l'insieme dei file e i loro mtime
+// coincidano con l'ultimo caricamento (chiamare con il lock della cache)
+func personaleCacheValid(sources []string) bool {
+	if len(cache.fileTimes) != len(sources) {
+		return false
+	}
+	for _, path := range sources {
+		cached, known := cache.fileTimes[path]
+		if !known {
+			return false
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().After(cached) {
+			return false
+		}
+	}
+	return true
+}
+
+// loadPersonaleSources carica e fonde i CSV indicati aggiornando la cache
+// (chiamare con il write lock della cache)
+func loadPersonaleSources(sources []string) (map[int]PersonaleRecord, error) {
+	log.Printf("PERSONALE_CACHE: Loading %d CSV file(s)", len(sources))
+
+	// Preallocazione delle mappe sulla stima delle righe (dimensione file /
+	// byte medi per riga): evita i rehash ripetuti con 200k+ voci
+	estimated := estimatePersonaleRows(sources)
+	personaleMap := make(map[int]PersonaleRecord, estimated)
+	owner := make(map[int]string, estimated)
+	skipped := make(map[string]int)
+	fileTimes := make(map[string]time.Time, len(sources))
+	fileStats := make(map[string]PersonaleFileStat, len(sources))
+
+	for _, path := range sources {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error accessing %s: %v", path, err)
+		}
+
+		stat, err := parsePersonaleFile(path, personaleMap, owner, skipped)
+		if err != nil {
+			return nil, err
+		}
+		stat.ModTime = info.ModTime().Unix()
+		fileTimes[path] = info.ModTime()
+		fileStats[path] = stat
+		log.Printf("PERSONALE_CACHE: %s: %d loaded, %d skipped", path, stat.RowsLoaded, stat.RowsSkipped)
+	}
+
+	// Update cache
+	cache.data = personaleMap
+	cache.fileTimes = fileTimes
+	cache.fileStats = fileStats
+	cache.loadedAt = time.Now()
+	cache.skipped = skipped
+
+	log.Printf("PERSONALE_CACHE: Loaded %d valid records from %d file(s)", len(personaleMap), len(sources))
+	return personaleMap, nil
+}
+
+// personaleAvgRowBytes è la dimensione media osservata di una riga del
+// CSV personale, usata per stimare il numero di righe dalla dimensione file
+const personaleAvgRowBytes = 100
+
+// estimatePersonaleRows stima le righe complessive dalla dimensione dei
+// file sorgente, per preallocare le mappe
+func estimatePersonaleRows(sources []string) int {
+	var total int64
+	for _, path := range sources {
+		if info, err := os.Stat(path); err == nil {
+			total += info.Size()
+		}
+	}
+	return int(total / personaleAvgRowBytes)
+}
+
+// PersonaleFileStats ritorna le statistiche per file dell'ultimo load
+func PersonaleFileStats() map[string]PersonaleFileStat {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	stats := make(map[string]PersonaleFileStat, len(cache.fileStats))
+	for path, stat := range cache.fileStats {
+		stats[path] = stat
+	}
+	return stats
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Reload esplicito della cache personale: la cache si rinfresca solo al
+// cambio di mtime del CSV e solo alla prima richiesta successiva, quindi
+// dopo un deploy del file l'operatore non ha conferma dell'avvenuto
+// ricaricamento. SIGHUP e POST /admin/api/personale/reload invalidano la
+// cache e ricaricano subito, riportando righe caricate e righe scartate
+// con i motivi.
+
+// reloadPersonale invalida la cache, ricarica il CSV e ritorna l'esito
+func reloadPersonale() (gin.H, error) {
+	start := time.Now()
+	InvalidatePersonaleCache()
+
+	_, err := LoadPersonaleData()
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, skipped, loadedAt := PersonaleLoadStats()
+	skippedTotal := 0
+	for _, n := range skipped {
+		skippedTotal += n
+	}
+	// Report memoria: heap del processo dopo il load, per verificare che
+	// la crescita del CSV resti sostenibile
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return gin.H{
+		"rows_loaded":  loaded,
+		"rows_skipped": skippedTotal,
+		"skip_reasons": skipped,
+		"files":        PersonaleFileStats(),
+		"loaded_at":    loadedAt.Unix(),
+		"duration_ms":  time.Since(start).Milliseconds(),
+		"memory": gin.H{
+			"heap_alloc_bytes": mem.HeapAlloc,
+			"heap_sys_bytes":   mem.HeapSys,
+			"num_gc":           mem.NumGC,
+		},
+	}, nil
+}
+
+// StartPersonaleReloadOnSignal ricarica la cache personale alla ricezione
+// di SIGHUP. Da chiamare in una goroutine all'avvio.
+func StartPersonaleReloadOnSignal() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	for range sigCh {
+		result, err := reloadPersonale()
+		if err != nil {
+			log.Printf("PERSONALE_RELOAD_ERROR: SIGHUP: %v", err)
+			continue
+		}
+		log.Printf("PERSONALE_RELOAD_OK: SIGHUP: rows_loaded=%v, rows_skipped=%v", result["rows_loaded"], result["rows_skipped"])
+	}
+}
+
+// HandlePersonaleReload forza il reload della cache personale
+// POST /admin/api/personale/reload
+func HandlePersonaleReload(c *gin.Context) {
+	result, err := reloadPersonale()
+	if err != nil {
+		log.Printf("PERSONALE_RELOAD_ERROR: admin: %v", err)
+		AbortWithError(c, ErrInternal, "reload personale fallito: "+err.Error())
+		return
+	}
+	log.Printf("PERSONALE_RELOAD_OK: admin: rows_loaded=%v, rows_skipped=%v, client_ip=%s",
+		result["rows_loaded"], result["rows_skipped"], c.ClientIP())
+	c.JSON(200, result)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Mascheramento PII nelle trascrizioni audio: il microfono cattura a volte
+// nomi di colleghi e numeri di telefono in sottofondo. Con
+// transcription.mask_pii attivo, un filtro post-trascrizione (regex più
+// lista nomi da personale.csv) maschera i dati rilevati prima che il testo
+// torni alla UI e venga inviato all'LLM.
+
+const piiPlaceholder = "[OMISSIS]"
+
+// Pattern PII: telefoni italiani, codici fiscali, email
+var (
+	phonePattern = regexp.MustCompile(`(\+39[\s.]?)?\b3\d{2}[\s./-]?\d{6,7}\b|\b0\d{1,3}[\s./-]?\d{6,8}\b`)
+	piiCFPattern = regexp.MustCompile(`\b[A-Za-z]{6}\d{2}[A-Za-z]\d{2}[A-Za-z]\d{3}[A-Za-z]\b`)
+	emailPattern = regexp.MustCompile(`\b[\w.+-]+@[\w-]+\.[\w.]+\b`)
+)
+
+// maskPersonaleNames maschera le coppie nome+cognome presenti in
+// personale.csv (match case-insensitive sulla coppia completa, per non
+// censurare nomi comuni usati da soli)
+func maskPersonaleNames(text string) string {
+	personale, err := LoadPersonaleData()
+	if err != nil {
+		log.Printf("PII_MASK_WARNING: personale non disponibile per il filtro nomi: %v", err)
+		return text
+	}
+
+	lower := strings.ToLower(text)
+	for _, record := range personale {
+		first := strings.TrimSpace(record.NameFirst)
+		last := strings.TrimSpace(record.NameLast)
+		if first == "" || last == "" {
+			continue
+		}
+		for _, fullName := range []string{first + " " + last, last + " " + first} {
+			needle := strings.ToLower(fullName)
+			for {
+				idx := strings.Index(lower, needle)
+				if idx == -1 {
+					break
+				}
+				text = text[:idx] + piiPlaceholder + text[idx+len(needle):]
+				lower = lower[:idx] + strings.ToLower(piiPlaceholder) + lower[idx+len(needle):]
+			}
+		}
+	}
+	return text
+}
+
+// MaskTranscriptionPII applica il filtro PII al testo trascritto
+func MaskTranscriptionPII(text string) string {
+	masked := phonePattern.ReplaceAllString(text, piiPlaceholder)
+	masked = piiCFPattern.ReplaceAllString(masked, piiPlaceholder)
+	masked = emailPattern.ReplaceAllString(masked, piiPlaceholder)
+	masked = maskPersonaleNames(masked)
+
+	if masked != text {
+		log.Printf("PII_MASK: dati personali mascherati nella trascrizione (%d -> %d caratteri)",
+			len(text), len(masked))
+	}
+	return masked
+}
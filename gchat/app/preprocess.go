@@ -0,0 +1,135 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Preprocessing delle domande prima dell'invio all'LLM: le abbreviazioni
+// veterinarie (SUAP, OSA, CU) e i refusi penalizzano l'accuratezza NLU.
+// La pipeline espande le sigle da un dizionario configurabile (sezione
+// preprocess) e, se abilitato, applica una correzione ortografica leggera
+// sul lessico di dominio. Il testo originale resta nel contesto
+// (original_message) per la vista debug.
+
+// PreprocessConfig configura la pipeline in config.json (preprocess)
+type PreprocessConfig struct {
+	Enabled bool `json:"enabled"`
+	// Abbreviations: sigle aggiuntive o override dei default
+	Abbreviations map[string]string `json:"abbreviations"`
+	// SpellCheck: correzione a distanza di edit 1 sul lessico di dominio
+	SpellCheck bool `json:"spell_check"`
+}
+
+// Sigle di dominio espanse di default (estendibili/sovrascrivibili da config)
+var defaultAbbreviations = map[string]string{
+	"suap": "sportello unico attività produttive",
+	"osa":  "operatore del settore alimentare",
+	"cu":   "controllo ufficiale",
+}
+
+// Lessico di dominio per la correzione ortografica
+var spellLexicon = []string{
+	"piano", "piani", "stabilimento", "stabilimenti", "controllo",
+	"controlli", "ufficiale", "ufficiali", "ispezione", "ispezioni",
+	"monitoraggio", "attività", "allevamento", "allevamenti", "rischio",
+	"priorità", "veterinario", "masterlist", "programmazione",
+}
+
+var preprocessWordPattern = regexp.MustCompile(`[A-Za-zÀ-ÿ]+`)
+
+// editDistanceOne verifica se due parole distano al più una modifica
+// (sostituzione, inserimento o cancellazione di un carattere)
+func editDistanceOne(a, b string) bool {
+	if a == b {
+		return false
+	}
+	la, lb := len(a), len(b)
+	if la-lb > 1 || lb-la > 1 {
+		return false
+	}
+	// Stessa lunghezza: ammessa una sola sostituzione
+	if la == lb {
+		diff := 0
+		for i := 0; i < la; i++ {
+			if a[i] != b[i] {
+				diff++
+				if diff > 1 {
+					return false
+				}
+			}
+		}
+		return diff == 1
+	}
+	// Lunghezza diversa di 1: ammesso un solo inserimento/cancellazione
+	longer, shorter := a, b
+	if lb > la {
+		longer, shorter = b, a
+	}
+	for i := 0; i < len(shorter); i++ {
+		if shorter[i] != longer[i] {
+			return shorter[i:] == longer[i+1:]
+		}
+	}
+	return true
+}
+
+// spellCorrect cerca nel lessico una parola a distanza di edit 1 (stessa
+// iniziale, per contenere i falsi positivi)
+func spellCorrect(word string) (string, bool) {
+	if len(word) < 5 {
+		return "", false
+	}
+	for _, known := range spellLexicon {
+		if word == known {
+			return "", false
+		}
+	}
+	for _, known := range spellLexicon {
+		if known[0] == word[0] && editDistanceOne(word, known) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// preprocessAbbreviations fonde i default con il dizionario configurato
+func preprocessAbbreviations(config *Config) map[string]string {
+	merged := make(map[string]string, len(defaultAbbreviations)+len(config.Preprocess.Abbreviations))
+	for abbr, expansion := range defaultAbbreviations {
+		merged[abbr] = expansion
+	}
+	for abbr, expansion := range config.Preprocess.Abbreviations {
+		merged[strings.ToLower(abbr)] = expansion
+	}
+	return merged
+}
+
+// PreprocessMessage applica espansione sigle e correzione ortografica.
+// Ritorna il testo elaborato e true se è cambiato rispetto all'originale.
+func PreprocessMessage(config *Config, message string) (string, bool) {
+	if !config.Preprocess.Enabled {
+		return message, false
+	}
+
+	abbreviations := preprocessAbbreviations(config)
+	processed := preprocessWordPattern.ReplaceAllStringFunc(message, func(word string) string {
+		lower := strings.ToLower(word)
+		if expansion, ok := abbreviations[lower]; ok && expansion != "" {
+			return expansion
+		}
+		if config.Preprocess.SpellCheck {
+			if corrected, ok := spellCorrect(lower); ok {
+				return corrected
+			}
+		}
+		return word
+	})
+
+	if processed == message {
+		return message, false
+	}
+	log.Printf("PREPROCESS_APPLIED: \"%s\" -> \"%s\"", message, processed)
+	return processed, true
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+)
+
+// Profili di configurazione con ereditarietà: invece di mantenere tre
+// config.json quasi identici per dev/staging/prod, il file contiene una
+// sezione "profiles" con i soli override per ambiente. Il profilo attivo
+// viene selezionato con la variabile d'ambiente GIAS_PROFILE e applicato
+// in deep-merge sulla configurazione base; il sottocomando CLI "profile"
+// stampa la configurazione risolta per verifica.
+
+const profileEnvVar = "GIAS_PROFILE"
+
+var profileLogOnce sync.Once
+
+// ActiveProfile ritorna il nome del profilo selezionato ("" = solo base)
+func ActiveProfile() string {
+	return os.Getenv(profileEnvVar)
+}
+
+// deepMergeJSON applica overlay su base ricorsivamente: le mappe vengono
+// fuse chiave per chiave, ogni altro valore (inclusi gli array) viene
+// sostituito per intero
+func deepMergeJSON(base, overlay map[string]interface{}) map[string]interface{} {
+	for key, overlayValue := range overlay {
+		baseMap, baseOK := base[key].(map[string]interface{})
+		overlayMap, overlayOK := overlayValue.(map[string]interface{})
+		if baseOK && overlayOK {
+			base[key] = deepMergeJSON(baseMap, overlayMap)
+			continue
+		}
+		base[key] = overlayValue
+	}
+	return base
+}
+
+// ResolveProfileJSON applica il profilo indicato al JSON di configurazione
+// grezzo e ritorna il JSON risolto (senza la sezione "profiles").
+// Con profilo vuoto ritorna la sola base; un profilo inesistente è errore.
+func ResolveProfileJSON(data []byte, profile string) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	profiles, _ := raw["profiles"].(map[string]interface{})
+	delete(raw, "profiles")
+
+	if profile != "" {
+		overlay, ok := profiles[profile].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("profilo %q non presente nella sezione profiles", profile)
+		}
+		raw = deepMergeJSON(raw, overlay)
+	}
+
+	return json.Marshal(raw)
+}
+
+// applyActiveProfile risolve il profilo da GIAS_PROFILE sul JSON grezzo;
+// in caso di errore ritorna la base e logga (il server parte comunque)
+func applyActiveProfile(data []byte) []byte {
+	profile := ActiveProfile()
+	if profile == "" {
+		return data
+	}
+	resolved, err := ResolveProfileJSON(data, profile)
+	if err != nil {
+		log.Printf("PROFILE_ERROR: %v, uso configurazione base", err)
+		return data
+	}
+	profileLogOnce.Do(func() {
+		log.Printf("PROFILE_ACTIVE: profilo=%s", profile)
+	})
+	return resolved
+}
+
+// RunProfileCmd stampa la configurazione risolta per il profilo richiesto.
+// Uso: ./gchat profile [nome]   (default: valore di GIAS_PROFILE)
+func RunProfileCmd(args []string) {
+	profile := ActiveProfile()
+	if len(args) > 0 {
+		profile = args[0]
+	}
+
+	data, err := os.ReadFile("config/config.json")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "errore lettura config/config.json: %v\n", err)
+		os.Exit(1)
+	}
+
+	resolved, err := ResolveProfileJSON(data, profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "errore risoluzione profilo: %v\n", err)
+		os.Exit(1)
+	}
+
+	var pretty map[string]interface{}
+	if err := json.Unmarshal(resolved, &pretty); err == nil {
+		if out, err := json.MarshalIndent(pretty, "", "  "); err == nil {
+			resolved = out
+		}
+	}
+	fmt.Println(string(resolved))
+}
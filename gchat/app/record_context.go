@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log"
+	"regexp"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Contesto record GISA via deep-link: quando la chat viene aperta dalla
+// pagina di uno stabilimento o di un piano, il portale passa context_type
+// e context_id. I parametri validati finiscono in sessione e nei metadata
+// verso il backend, così le risposte sono automaticamente scopate sul
+// record di partenza; la UI mostra un chip di contesto.
+
+// validContextTypes mappa i tipi di record ammessi alla loro etichetta
+// italiana mostrata nel chip
+var validContextTypes = map[string]string{
+	"stabilimento": "Stabilimento",
+	"piano":        "Piano",
+	"controllo":    "Controllo ufficiale",
+	"masterlist":   "Masterlist",
+}
+
+// contextIDPattern: identificativi GISA alfanumerici (es. A22, 12345)
+var contextIDPattern = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,64}$`)
+
+// ValidateRecordContext verifica tipo e identificativo del contesto
+func ValidateRecordContext(contextType, contextID string) bool {
+	if _, ok := validContextTypes[contextType]; !ok {
+		return false
+	}
+	return contextIDPattern.MatchString(contextID)
+}
+
+// RecordContextLabel ritorna l'etichetta da mostrare nel chip di contesto
+func RecordContextLabel(contextType, contextID string) string {
+	label, ok := validContextTypes[contextType]
+	if !ok {
+		return ""
+	}
+	return label + " " + contextID
+}
+
+// MergeRecordContext fonde i parametri di contesto record con la sessione
+// (priorità query > sessione, come MergeSessionParams) e li valida.
+// Valori non validi vengono scartati e loggati.
+func MergeRecordContext(c *gin.Context) (contextType, contextID string) {
+	session := sessions.Default(c)
+
+	getString := func(key string) string {
+		if val := session.Get(key); val != nil {
+			if str, ok := val.(string); ok {
+				return str
+			}
+		}
+		return ""
+	}
+
+	contextType = getString("context_type")
+	contextID = getString("context_id")
+
+	queryType := c.Query("context_type")
+	queryID := c.Query("context_id")
+	if queryType != "" || queryID != "" {
+		if ValidateRecordContext(queryType, queryID) {
+			contextType = queryType
+			contextID = queryID
+		} else {
+			log.Printf("CONTEXT_REJECTED: parametri contesto non validi - context_type='%s', context_id='%s'",
+				queryType, queryID)
+		}
+	}
+
+	if contextType == "" || contextID == "" {
+		return "", ""
+	}
+
+	// Difesa in profondità: anche il valore salvato in sessione deve
+	// superare la validazione (cookie manomessi)
+	if !ValidateRecordContext(contextType, contextID) {
+		return "", ""
+	}
+
+	session.Set("context_type", contextType)
+	session.Set("context_id", contextID)
+	if err := session.Save(); err != nil {
+		log.Printf("CONTEXT_SESSION_ERROR: %v", err)
+	}
+
+	log.Printf("CONTEXT_ACTIVE: context_type=%s, context_id=%s", contextType, contextID)
+	return contextType, contextID
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Replay di conversazioni storiche contro il backend corrente: prima di
+// promuovere un upgrade del backend l'unico modo per sapere se le risposte
+// cambiano è rigiocare traffico reale. POST /admin/api/replay prende una
+// conversazione salvata nel chat-log, rimanda i turni utente al backend
+// con un sender sandbox (nessuna sessione reale viene toccata) e mette a
+// confronto intent e risposte nuove con gli originali, segnalando i turni
+// divergenti.
+
+const (
+	// replayMaxTurns limita i turni rigiocati per richiesta (ogni turno è
+	// una chiamata chat completa al backend)
+	replayMaxTurns = 20
+	// replayExcerptChars: lunghezza degli estratti di risposta nel diff
+	replayExcerptChars = 200
+)
+
+// replayTurn è l'esito del replay di un singolo turno utente
+type replayTurn struct {
+	Turn            int    `json:"turn"`
+	Question        string `json:"question"`
+	OriginalIntent  string `json:"original_intent,omitempty"`
+	ReplayIntent    string `json:"replay_intent"`
+	IntentChanged   bool   `json:"intent_changed"`
+	AnswerChanged   bool   `json:"answer_changed"`
+	OriginalExcerpt string `json:"original_excerpt,omitempty"`
+	ReplayExcerpt   string `json:"replay_excerpt,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// replaySourceTurn è un turno originale estratto dal chat-log
+type replaySourceTurn struct {
+	question string
+	answer   string
+	intent   string
+}
+
+// fetchConversationForReplay recupera i turni originali dal chat-log in
+// forma tollerante allo schema (stessa API proxata dalla cronologia)
+func fetchConversationForReplay(config *Config, convID string) ([]replaySourceTurn, error) {
+	client := NewUpstreamClient("llm", time.Duration(config.LLMServer.Timeout)*time.Second)
+	resp, err := upstreamGet(client, config.LLMServer.URL+"/api/chat-log/conversation/"+convID)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("backend status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	var items []interface{}
+	for _, key := range []string{"messages", "results", "data", "conversation"} {
+		if list, ok := payload[key].([]interface{}); ok {
+			items = list
+			break
+		}
+	}
+
+	var turns []replaySourceTurn
+	for _, item := range items {
+		msg, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		question, _ := msg["user_message"].(string)
+		if question == "" {
+			continue
+		}
+		turn := replaySourceTurn{question: question}
+		turn.answer, _ = msg["bot_response"].(string)
+		turn.intent, _ = msg["intent"].(string)
+		turns = append(turns, turn)
+	}
+	return turns, nil
+}
+
+// replayExcerpt riduce una risposta all'estratto mostrato nel diff
+func replayExcerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) > replayExcerptChars {
+		return text[:replayExcerptChars] + "…"
+	}
+	return text
+}
+
+// replayAnswersEqual confronta le risposte ignorando spazi e maiuscole
+// (il backend varia la formattazione tra release senza cambiare contenuto)
+func replayAnswersEqual(a, b string) bool {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	return normalize(a) == normalize(b)
+}
+
+// HandleReplay rigioca una conversazione storica contro il backend corrente
+// POST /admin/api/replay {"conversation_id": "...", "asl": "..."}
+func HandleReplay(c *gin.Context) {
+	var req struct {
+		ConversationID string `json:"conversation_id"`
+		ASL            string `json:"asl"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	if req.ConversationID == "" {
+		AbortWithError(c, ErrInvalidParameter, "conversation_id mancante")
+		return
+	}
+
+	config := LoadConfig()
+	source, err := fetchConversationForReplay(config, req.ConversationID)
+	if err != nil {
+		log.Printf("REPLAY_FETCH_ERROR: conversation=%s, error=%v", req.ConversationID, err)
+		AbortWithError(c, ErrBackendError, "recupero conversazione fallito: "+err.Error())
+		return
+	}
+	if len(source) == 0 {
+		AbortWithError(c, ErrNotFound, "conversazione non trovata o senza turni utente")
+		return
+	}
+
+	truncated := false
+	if len(source) > replayMaxTurns {
+		source = source[:replayMaxTurns]
+		truncated = true
+	}
+
+	// Sender sandbox: stato conversazionale isolato dalle sessioni reali
+	sandboxSender := "replay-" + newContinuationHandle()
+	context := map[string]interface{}{"replay": true}
+	if canonical, ok := NormalizeASLName(req.ASL); ok {
+		context["asl"] = canonical
+	}
+
+	start := time.Now()
+	results := make([]replayTurn, 0, len(source))
+	intentChanged, answerChanged := 0, 0
+
+	for i, original := range source {
+		result := replayTurn{
+			Turn:           i + 1,
+			Question:       original.question,
+			OriginalIntent: original.intent,
+		}
+
+		v1Resp, err := SendToLLMV1(original.question, sandboxSender, config.LLMServer.URL, config.LLMServer.Timeout, context)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.ReplayIntent = v1Resp.Result.Intent
+		result.IntentChanged = original.intent != "" && original.intent != v1Resp.Result.Intent
+		result.AnswerChanged = original.answer != "" && !replayAnswersEqual(original.answer, v1Resp.Result.Text)
+		if result.IntentChanged {
+			intentChanged++
+		}
+		if result.AnswerChanged {
+			answerChanged++
+			result.OriginalExcerpt = replayExcerpt(original.answer)
+			result.ReplayExcerpt = replayExcerpt(v1Resp.Result.Text)
+		}
+		results = append(results, result)
+	}
+
+	log.Printf("REPLAY_DONE: conversation=%s, turns=%d, intent_changed=%d, answer_changed=%d, duration=%v",
+		req.ConversationID, len(results), intentChanged, answerChanged, time.Since(start))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":          "success",
+		"conversation_id": req.ConversationID,
+		"sandbox_sender":  sandboxSender,
+		"turns_replayed":  len(results),
+		"truncated":       truncated,
+		"intent_changed":  intentChanged,
+		"answer_changed":  answerChanged,
+		"turns":           results,
+		"duration_ms":     time.Since(start).Milliseconds(),
+	})
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Upload resumabili stile tus per la trascrizione audio: le connessioni
+// mobili cadono durante l'upload e senza ripresa l'utente deve
+// ri-registrare. Flusso: POST crea la sessione di upload (Upload-Length),
+// PATCH invia i chunk con Upload-Offset, HEAD interroga l'offset corrente,
+// POST /finalize esegue la trascrizione sul file assemblato.
+
+const (
+	uploadTTL       = 30 * time.Minute
+	uploadMaxBytes  = 25 * 1024 * 1024 // 25 MB di audio al massimo
+	uploadOffsetHdr = "Upload-Offset"
+	uploadLengthHdr = "Upload-Length"
+)
+
+type resumableUpload struct {
+	ID        string
+	Path      string
+	Length    int64
+	Offset    int64
+	ExpiresAt time.Time
+}
+
+type uploadStore struct {
+	uploads map[string]*resumableUpload
+	mu      sync.Mutex
+}
+
+var resumableUploads = &uploadStore{
+	uploads: make(map[string]*resumableUpload),
+}
+
+// cleanupUpload rimuove la sessione e il file parziale
+func cleanupUpload(id string) {
+	resumableUploads.mu.Lock()
+	upload, ok := resumableUploads.uploads[id]
+	if ok {
+		delete(resumableUploads.uploads, id)
+	}
+	resumableUploads.mu.Unlock()
+
+	if ok {
+		if err := os.Remove(upload.Path); err != nil && !os.IsNotExist(err) {
+			log.Printf("UPLOAD_CLEANUP_ERROR: id=%s, error=%v", id, err)
+		}
+	}
+}
+
+// HandleUploadCreate crea una nuova sessione di upload resumabile
+// POST /api/transcribe/uploads  (header Upload-Length: dimensione totale)
+func HandleUploadCreate(c *gin.Context) {
+	length, err := strconv.ParseInt(c.GetHeader(uploadLengthHdr), 10, 64)
+	if err != nil || length <= 0 {
+		AbortWithError(c, ErrInvalidRequest, "header Upload-Length mancante o non valido")
+		return
+	}
+	if length > uploadMaxBytes {
+		AbortWithError(c, ErrInvalidParameter, fmt.Sprintf("dimensione massima %d byte", uploadMaxBytes))
+		return
+	}
+
+	id := newContinuationHandle()
+	if id == "" {
+		AbortWithError(c, ErrInternal, "impossibile generare l'identificativo upload")
+		return
+	}
+
+	tmpFile, err := os.CreateTemp("", "resumable-*.webm")
+	if err != nil {
+		AbortWithError(c, ErrInternal, "errore creazione file temporaneo")
+		return
+	}
+	tmpFile.Close()
+
+	upload := &resumableUpload{
+		ID:        id,
+		Path:      tmpFile.Name(),
+		Length:    length,
+		ExpiresAt: time.Now().Add(uploadTTL),
+	}
+
+	resumableUploads.mu.Lock()
+	resumableUploads.uploads[id] = upload
+	resumableUploads.mu.Unlock()
+
+	time.AfterFunc(uploadTTL, func() { cleanupUpload(id) })
+
+	log.Printf("UPLOAD_CREATED: id=%s, length=%d, client_ip=%s", id, length, c.ClientIP())
+	c.Header(uploadOffsetHdr, "0")
+	c.JSON(http.StatusCreated, gin.H{
+		"status":    "success",
+		"upload_id": id,
+		"offset":    0,
+	})
+}
+
+// HandleUploadStatus ritorna l'offset corrente (per riprendere dopo un blip)
+// HEAD /api/transcribe/uploads/:id
+func HandleUploadStatus(c *gin.Context) {
+	id := c.Param("id")
+
+	resumableUploads.mu.Lock()
+	upload, ok := resumableUploads.uploads[id]
+	var offset, length int64
+	if ok {
+		offset, length = upload.Offset, upload.Length
+	}
+	resumableUploads.mu.Unlock()
+
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	c.Header(uploadOffsetHdr, strconv.FormatInt(offset, 10))
+	c.Header(uploadLengthHdr, strconv.FormatInt(length, 10))
+	c.Status(http.StatusOK)
+}
+
+// HandleUploadChunk accoda un chunk all'offset dichiarato
+// PATCH /api/transcribe/uploads/:id  (header Upload-Offset, body binario)
+func HandleUploadChunk(c *gin.Context) {
+	id := c.Param("id")
+
+	offset, err := strconv.ParseInt(c.GetHeader(uploadOffsetHdr), 10, 64)
+	if err != nil || offset < 0 {
+		AbortWithError(c, ErrInvalidRequest, "header Upload-Offset mancante o non valido")
+		return
+	}
+
+	resumableUploads.mu.Lock()
+	upload, ok := resumableUploads.uploads[id]
+	resumableUploads.mu.Unlock()
+
+	if !ok {
+		AbortWithError(c, ErrNotFound, "sessione di upload inesistente o scaduta")
+		return
+	}
+
+	// L'offset dichiarato deve combaciare con quello corrente: i chunk già
+	// ricevuti non vengono riscritti (idempotenza dei retry)
+	if offset != upload.Offset {
+		log.Printf("UPLOAD_OFFSET_MISMATCH: id=%s, atteso=%d, ricevuto=%d", id, upload.Offset, offset)
+		c.Header(uploadOffsetHdr, strconv.FormatInt(upload.Offset, 10))
+		AbortWithError(c, ErrInvalidParameter, fmt.Sprintf("offset atteso %d", upload.Offset))
+		return
+	}
+
+	file, err := os.OpenFile(upload.Path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		AbortWithError(c, ErrInternal, "errore apertura file parziale")
+		return
+	}
+	defer file.Close()
+
+	written, err := io.Copy(file, io.LimitReader(c.Request.Body, uploadMaxBytes-upload.Offset))
+	if err != nil {
+		log.Printf("UPLOAD_CHUNK_ERROR: id=%s, error=%v", id, err)
+		AbortWithError(c, ErrInternal, "errore scrittura chunk")
+		return
+	}
+
+	resumableUploads.mu.Lock()
+	upload.Offset += written
+	newOffset := upload.Offset
+	resumableUploads.mu.Unlock()
+
+	log.Printf("UPLOAD_CHUNK: id=%s, bytes=%d, offset=%d/%d", id, written, newOffset, upload.Length)
+	c.Header(uploadOffsetHdr, strconv.FormatInt(newOffset, 10))
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"offset": newOffset,
+	})
+}
+
+// HandleUploadFinalize completa l'upload ed esegue la trascrizione
+// POST /api/transcribe/uploads/:id/finalize  (form: language, asl)
+func HandleUploadFinalize(c *gin.Context) {
+	gaugeTranscribeStarted()
+	defer gaugeTranscribeFinished()
+
+	id := c.Param("id")
+
+	resumableUploads.mu.Lock()
+	upload, ok := resumableUploads.uploads[id]
+	resumableUploads.mu.Unlock()
+
+	if !ok {
+		AbortWithError(c, ErrNotFound, "sessione di upload inesistente o scaduta")
+		return
+	}
+	if upload.Offset < upload.Length {
+		AbortWithError(c, ErrInvalidParameter,
+			fmt.Sprintf("upload incompleto: %d/%d byte", upload.Offset, upload.Length))
+		return
+	}
+
+	language := c.PostForm("language")
+	if language == "" {
+		language = "it"
+	}
+
+	whisperURL := os.Getenv("WHISPER_URL")
+	if whisperURL == "" {
+		whisperURL = "http://localhost:8090/inference"
+	}
+
+	log.Printf("UPLOAD_FINALIZE: id=%s, bytes=%d, language=%s", id, upload.Offset, language)
+
+	transcription, audioDuration, _, err := callWhisper(upload.Path, whisperURL, language, false)
+	cleanupUpload(id)
+	if err != nil {
+		log.Printf("UPLOAD_TRANSCRIBE_ERROR: id=%s, error=%v", id, err)
+		AbortWithError(c, ErrTranscription, err.Error())
+		return
+	}
+
+	// Stesse regole del percorso non resumabile: filtro PII e metering
+	if LoadConfig().Transcription.MaskPII {
+		transcription = MaskTranscriptionPII(transcription)
+	}
+	MeterTranscriptionMinutes(c.PostForm("asl"), audioDuration/60.0)
+
+	c.JSON(http.StatusOK, TranscriptionResponse{
+		Text:     transcription,
+		Language: language,
+	})
+}
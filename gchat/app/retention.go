@@ -0,0 +1,206 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Motore di retention per i dati conservati dal gateway: la direttiva
+// interna impone scadenze diverse per classe di dato (cronologia 90
+// giorni, audit/utilizzo 2 anni, log di debug 14 giorni). Un purger
+// schedulato applica le policy configurate in config.json (sezione
+// retention), con modalità dry-run e report dei conteggi eliminati.
+
+const (
+	retentionDefaultIntervalHours = 24
+	retentionMaxReports           = 30 // report delle ultime esecuzioni conservati
+)
+
+// Default per classe (giorni) quando la config non specifica nulla
+var retentionDefaults = map[string]int{
+	"sessions":   90,  // analytics di sessione (data/sessions.json)
+	"usage":      730, // metering di utilizzo (data/usage_metering.json)
+	"debug_logs": 14,  // log di debug API (log/*.log, log/*.log.old)
+}
+
+// RetentionConfig definisce le policy in config.json
+type RetentionConfig struct {
+	Enabled       bool           `json:"enabled"`
+	DryRun        bool           `json:"dry_run"`        // se true, conta senza eliminare
+	IntervalHours int            `json:"interval_hours"` // frequenza del purger (default 24)
+	MaxAgeDays    map[string]int `json:"max_age_days"`   // override per classe
+}
+
+// RetentionReport è l'esito di una esecuzione del purger
+type RetentionReport struct {
+	RunAt  time.Time      `json:"run_at"`
+	DryRun bool           `json:"dry_run"`
+	Purged map[string]int `json:"purged"` // classe -> elementi eliminati (o eliminabili in dry-run)
+}
+
+type retentionState struct {
+	reports []RetentionReport
+	mu      sync.Mutex
+}
+
+var retention = &retentionState{}
+
+// retentionMaxAge risolve la scadenza per classe (0 = classe disattivata)
+func retentionMaxAge(config *Config, class string) int {
+	if days, ok := config.Retention.MaxAgeDays[class]; ok {
+		return days
+	}
+	return retentionDefaults[class]
+}
+
+// purgeSessions elimina le sessioni analytics più vecchie del cutoff
+func purgeSessions(cutoff time.Time, dryRun bool) int {
+	sessionAnalytics.mu.Lock()
+	defer sessionAnalytics.mu.Unlock()
+	sessionAnalytics.ensureLoaded()
+
+	purged := 0
+	for id, rec := range sessionAnalytics.records {
+		if rec.LastSeenAt.Before(cutoff) {
+			purged++
+			if !dryRun {
+				delete(sessionAnalytics.records, id)
+			}
+		}
+	}
+	if purged > 0 && !dryRun {
+		sessionAnalytics.persist()
+	}
+	return purged
+}
+
+// purgeUsage elimina i record di metering dei mesi oltre la scadenza
+func purgeUsage(cutoff time.Time, dryRun bool) int {
+	meterStore.mu.Lock()
+	defer meterStore.mu.Unlock()
+	meterStore.ensureLoaded()
+
+	cutoffMonth := cutoff.Format(monthFormat)
+	purged := 0
+	for key, rec := range meterStore.records {
+		if rec.Month < cutoffMonth {
+			purged++
+			if !dryRun {
+				delete(meterStore.records, key)
+			}
+		}
+	}
+	if purged > 0 && !dryRun {
+		meterStore.persist()
+	}
+	return purged
+}
+
+// purgeDebugLogs elimina i file di log più vecchi del cutoff (il log
+// applicativo corrente viene risparmiato, si toccano solo i ruotati e
+// il debug log API)
+func purgeDebugLogs(cutoff time.Time, dryRun bool) int {
+	patterns := []string{"log/*.log.old", "log/gias_api_debug.log"}
+	purged := 0
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			continue
+		}
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().After(cutoff) {
+				continue
+			}
+			purged++
+			if !dryRun {
+				if err := os.Remove(path); err != nil {
+					log.Printf("RETENTION_ERROR: rimozione %s fallita: %v", path, err)
+					purged--
+				}
+			}
+		}
+	}
+	return purged
+}
+
+// RunRetention esegue una passata di retention e registra il report
+func RunRetention(dryRun bool) RetentionReport {
+	config := LoadConfig()
+	now := time.Now()
+	report := RetentionReport{
+		RunAt:  now,
+		DryRun: dryRun,
+		Purged: make(map[string]int),
+	}
+
+	purgers := map[string]func(time.Time, bool) int{
+		"sessions":   purgeSessions,
+		"usage":      purgeUsage,
+		"debug_logs": purgeDebugLogs,
+	}
+
+	for class, purge := range purgers {
+		days := retentionMaxAge(config, class)
+		if days <= 0 {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -days)
+		report.Purged[class] = purge(cutoff, dryRun)
+	}
+
+	retention.mu.Lock()
+	retention.reports = append(retention.reports, report)
+	if len(retention.reports) > retentionMaxReports {
+		retention.reports = retention.reports[len(retention.reports)-retentionMaxReports:]
+	}
+	retention.mu.Unlock()
+
+	log.Printf("RETENTION_RUN: dry_run=%v, purged=%v", dryRun, report.Purged)
+	return report
+}
+
+// StartRetentionScheduler avvia il purger periodico. Da chiamare in una
+// goroutine all'avvio; no-op se la retention è spenta.
+func StartRetentionScheduler(config *Config) {
+	if !config.Retention.Enabled {
+		log.Printf("RETENTION_SKIP: retention disabilitata")
+		return
+	}
+
+	interval := time.Duration(config.Retention.IntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = retentionDefaultIntervalHours * time.Hour
+	}
+
+	RunRetention(config.Retention.DryRun)
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		RunRetention(LoadConfig().Retention.DryRun)
+	}
+}
+
+// HandleRetentionRun esegue una passata on demand
+// POST /admin/api/retention/run?dry_run=true
+func HandleRetentionRun(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true" || LoadConfig().Retention.DryRun
+	report := RunRetention(dryRun)
+	c.JSON(http.StatusOK, gin.H{"status": "success", "report": report})
+}
+
+// HandleRetentionReport restituisce i report delle ultime esecuzioni
+// GET /admin/api/retention/report
+func HandleRetentionReport(c *gin.Context) {
+	retention.mu.Lock()
+	reports := make([]RetentionReport, len(retention.reports))
+	copy(reports, retention.reports)
+	retention.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "reports": reports})
+}
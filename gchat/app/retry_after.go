@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Gestione trasparente del Retry-After del backend: quando il backend
+// risponde 429 o 503 con l'header Retry-After il gateway finora falliva
+// subito. Se l'attesa indicata rientra nel budget della richiesta
+// (llm_server.timeout) il turno viene riaccodato e ritentato una volta in
+// modo trasparente; altrimenti l'indicazione viene tradotta in una
+// risposta strutturata "occupato, riprova tra N secondi" (HTTP 429 con
+// retry_in) o in un evento SSE "busy" sul percorso streaming.
+
+// busyRetryMaxWait: attesa massima che il gateway assorbe in proprio prima
+// di delegare il retry al client
+const busyRetryMaxWait = 15 * time.Second
+
+// BackendBusyError segnala che il backend è saturo e indica tra quanti
+// secondi il client può riprovare
+type BackendBusyError struct {
+	StatusCode int
+	RetryAfter int // secondi
+}
+
+func (e *BackendBusyError) Error() string {
+	return fmt.Sprintf("LLM server busy (status %d), retry after %ds", e.StatusCode, e.RetryAfter)
+}
+
+// BusyMessage è il testo utente in italiano per la risposta strutturata
+func (e *BackendBusyError) BusyMessage() string {
+	return fmt.Sprintf("Il sistema è momentaneamente occupato, riprova tra %d secondi.", e.RetryAfter)
+}
+
+// parseRetryAfterHeader interpreta l'header Retry-After (secondi o data
+// HTTP); ritorna 0 se assente o non interpretabile
+func parseRetryAfterHeader(value string) int {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return seconds
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		seconds := int(time.Until(when).Seconds())
+		if seconds < 0 {
+			return 0
+		}
+		return seconds
+	}
+	return 0
+}
+
+// backendBusyRetry decide come gestire una risposta 429/503 del backend.
+// Ritorna (wait, true, nil) se il gateway può assorbire l'attesa e
+// ritentare entro la deadline, altrimenti (0, false, *BackendBusyError)
+// da propagare al client. Per status diversi ritorna (0, false, nil).
+func backendBusyRetry(resp *http.Response, attempt int, deadline time.Time) (time.Duration, bool, error) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false, nil
+	}
+	seconds := parseRetryAfterHeader(resp.Header.Get("Retry-After"))
+	if seconds == 0 {
+		// Nessuna indicazione utilizzabile: si applica il percorso d'errore esistente
+		return 0, false, nil
+	}
+	wait := time.Duration(seconds) * time.Second
+	// Un solo retry trasparente, e solo se l'attesa lascia margine alla deadline
+	if attempt == 1 && wait <= busyRetryMaxWait && time.Now().Add(wait).Before(deadline.Add(-time.Second)) {
+		return wait, true, nil
+	}
+	return 0, false, &BackendBusyError{StatusCode: resp.StatusCode, RetryAfter: seconds}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+
+	"golang.org/x/net/html/atom"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Sandbox per contenuti rich del backend: il backend può inviare piccoli
+// frammenti HTML (bottoni, card) nei payload custom. Prima di arrivare al
+// browser passano da un sanitizzatore a whitelist (tag, attributi e schemi
+// URL ammessi) oppure dal renderer di card a schema, così i contenuti
+// ricchi non aprono falle XSS.
+
+// Tag ammessi con i rispettivi attributi consentiti
+var allowedTags = map[string][]string{
+	"a":      {"href", "title", "target"},
+	"b":      {},
+	"br":     {},
+	"button": {"data-query", "type"},
+	"div":    {"class"},
+	"em":     {},
+	"i":      {},
+	"li":     {},
+	"ol":     {},
+	"p":      {},
+	"span":   {"class"},
+	"strong": {},
+	"table":  {},
+	"tbody":  {},
+	"td":     {},
+	"th":     {},
+	"thead":  {},
+	"tr":     {},
+	"ul":     {},
+}
+
+// Schemi URL ammessi negli attributi href
+var allowedSchemes = []string{"http://", "https://", "mailto:", "/"}
+
+// safeURL verifica che un URL usi uno schema ammesso (niente javascript:)
+func safeURL(raw string) bool {
+	trimmed := strings.TrimSpace(strings.ToLower(raw))
+	// "//host" è un URL protocol-relative che naviga fuori sito: non deve
+	// passare come link relativo
+	if strings.HasPrefix(trimmed, "//") {
+		return false
+	}
+	for _, scheme := range allowedSchemes {
+		if strings.HasPrefix(trimmed, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedAttr verifica se l'attributo è in whitelist per il tag
+func allowedAttr(tag, attr string) bool {
+	for _, allowed := range allowedTags[tag] {
+		if allowed == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeNode riscrive l'albero tenendo solo tag/attributi in whitelist;
+// i tag non ammessi vengono rimossi ma il loro testo sopravvive
+func sanitizeNode(node *xhtml.Node, out *strings.Builder) {
+	switch node.Type {
+	case xhtml.TextNode:
+		out.WriteString(html.EscapeString(node.Data))
+		return
+	case xhtml.ElementNode:
+		tag := strings.ToLower(node.Data)
+		if _, ok := allowedTags[tag]; ok {
+			out.WriteString("<" + tag)
+			for _, attr := range node.Attr {
+				name := strings.ToLower(attr.Key)
+				if !allowedAttr(tag, name) {
+					continue
+				}
+				if name == "href" && !safeURL(attr.Val) {
+					continue
+				}
+				out.WriteString(fmt.Sprintf(" %s=%q", name, html.EscapeString(attr.Val)))
+			}
+			out.WriteString(">")
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				sanitizeNode(child, out)
+			}
+			out.WriteString("</" + tag + ">")
+			return
+		}
+		// Tag fuori whitelist (script, style, iframe...): scarta anche il
+		// contenuto per i tag che veicolano codice, tieni il testo per gli altri
+		if tag == "script" || tag == "style" || tag == "iframe" || tag == "object" {
+			return
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		sanitizeNode(child, out)
+	}
+}
+
+// SanitizeHTML riduce un frammento HTML alla whitelist di tag/attributi.
+// In caso di errore di parsing ritorna il testo escapato (fail-safe).
+func SanitizeHTML(fragment string) string {
+	nodes, err := xhtml.ParseFragment(strings.NewReader(fragment), &xhtml.Node{
+		Type:     xhtml.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+	})
+	if err != nil {
+		log.Printf("SANITIZE_ERROR: parsing fallito, testo escapato: %v", err)
+		return html.EscapeString(fragment)
+	}
+
+	var out strings.Builder
+	for _, node := range nodes {
+		sanitizeNode(node, &out)
+	}
+
+	sanitized := out.String()
+	if sanitized != fragment {
+		log.Printf("SANITIZE_APPLIED: frammento ridotto da %d a %d caratteri", len(fragment), len(sanitized))
+	}
+	return sanitized
+}
+
+// RichCard è lo schema strutturato per le card inviate dal backend
+// (alternativa sicura all'HTML libero: il gateway la rende lui stesso)
+type RichCard struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Body     string `json:"body,omitempty"`
+	LinkURL  string `json:"link_url,omitempty"`
+	LinkText string `json:"link_text,omitempty"`
+	Actions  []struct {
+		Label string `json:"label"`
+		Query string `json:"query"`
+	} `json:"actions,omitempty"`
+}
+
+// ApplyRichContent estrae i payload custom dagli slot del backend
+// (custom_html: frammento HTML libero, cards: lista di RichCard) e li
+// pubblica nella risposta già sanitizzati/renderizzati
+func ApplyRichContent(chatResp *ChatResponse, slots map[string]interface{}) {
+	var fragments []string
+
+	if raw, ok := slots["custom_html"].(string); ok && raw != "" {
+		fragments = append(fragments, SanitizeHTML(raw))
+	}
+
+	if rawCards, ok := slots["cards"]; ok {
+		encoded, err := json.Marshal(rawCards)
+		if err == nil {
+			var cards []RichCard
+			if err := json.Unmarshal(encoded, &cards); err == nil {
+				for _, card := range cards {
+					fragments = append(fragments, RenderCard(card))
+				}
+			} else {
+				log.Printf("SANITIZE_CARDS_ERROR: schema card non valido: %v", err)
+			}
+		}
+	}
+
+	if len(fragments) > 0 {
+		chatResp.FullData = strings.Join(fragments, "")
+		chatResp.DataType = "html"
+	}
+}
+
+// RenderCard produce l'HTML di una card a partire dallo schema: tutti i
+// valori vengono escapati, i link passano dal controllo schemi
+func RenderCard(card RichCard) string {
+	var out strings.Builder
+	out.WriteString(`<div class="rich-card">`)
+	if card.Title != "" {
+		out.WriteString(`<div class="rich-card-title">` + html.EscapeString(card.Title) + `</div>`)
+	}
+	if card.Subtitle != "" {
+		out.WriteString(`<div class="rich-card-subtitle">` + html.EscapeString(card.Subtitle) + `</div>`)
+	}
+	if card.Body != "" {
+		out.WriteString(`<div class="rich-card-body">` + html.EscapeString(card.Body) + `</div>`)
+	}
+	if card.LinkURL != "" && safeURL(card.LinkURL) {
+		text := card.LinkText
+		if text == "" {
+			text = card.LinkURL
+		}
+		out.WriteString(fmt.Sprintf(`<a class="rich-card-link" href=%q target="_blank">%s</a>`,
+			html.EscapeString(card.LinkURL), html.EscapeString(text)))
+	}
+	for _, action := range card.Actions {
+		if action.Label == "" || action.Query == "" {
+			continue
+		}
+		out.WriteString(fmt.Sprintf(`<button class="rich-card-action" type="button" data-query=%q>%s</button>`,
+			html.EscapeString(action.Query), html.EscapeString(action.Label)))
+	}
+	out.WriteString(`</div>`)
+	return out.String()
+}
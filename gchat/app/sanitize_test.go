@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSafeURLSchemes(t *testing.T) {
+	cases := []struct {
+		url  string
+		safe bool
+	}{
+		{"https://gisa.regione.campania.it/piani", true},
+		{"http://localhost:5005/status", true},
+		{"mailto:supporto@example.org", true},
+		{"/gias/webchat/history", true},
+		{"javascript:alert(1)", false},
+		{"data:text/html;base64,PGI+", false},
+		// Protocol-relative: passa il prefisso "/" ma naviga fuori sito
+		{"//evil.example/phish", false},
+		{"  //evil.example", false},
+	}
+	for _, tc := range cases {
+		if got := safeURL(tc.url); got != tc.safe {
+			t.Errorf("safeURL(%q) = %v, atteso %v", tc.url, got, tc.safe)
+		}
+	}
+}
+
+func TestSanitizeHTMLDropsProtocolRelativeHref(t *testing.T) {
+	out := SanitizeHTML(`<a href="//evil.example">link</a>`)
+	if strings.Contains(out, "evil.example") {
+		t.Errorf("href protocol-relative non rimosso: %q", out)
+	}
+	if !strings.Contains(out, "link") {
+		t.Errorf("testo del link perso: %q", out)
+	}
+}
+
+func TestSanitizeHTMLDropsScriptAndJavascriptHref(t *testing.T) {
+	out := SanitizeHTML(`<script>alert(1)</script><a href="javascript:alert(1)">x</a>`)
+	if strings.Contains(out, "script") || strings.Contains(out, "javascript:") {
+		t.Errorf("contenuto pericoloso sopravvissuto: %q", out)
+	}
+}
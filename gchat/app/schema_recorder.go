@@ -0,0 +1,186 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Registrazione degli schemi dei payload custom del backend: nuovi campi
+// nei data_type vengono scoperti per caso durante il debug. Un recorder
+// opt-in campiona le risposte, registra la struttura osservata dei campi
+// per ogni data_type e segnala il type drift (stesso campo visto con tipi
+// diversi nel tempo). Report su GET /admin/api/schema-report.
+
+const (
+	schemaDefaultSampleRate = 0.1 // frazione di risposte campionate
+	schemaMaxDepth          = 4   // profondità massima di esplorazione
+	schemaMaxFields         = 200 // campi tracciati per shape
+)
+
+// SchemaRecorderConfig abilita il recorder in config.json (schema_recorder)
+type SchemaRecorderConfig struct {
+	Enabled    bool    `json:"enabled"`
+	SampleRate float64 `json:"sample_rate"` // default 0.1
+}
+
+type schemaFieldInfo struct {
+	Types     map[string]int // tipo JSON osservato -> occorrenze
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+type schemaShape struct {
+	Samples   int
+	Fields    map[string]*schemaFieldInfo // percorso campo -> tipi
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+var schemaRec = struct {
+	shapes map[string]*schemaShape
+	mu     sync.Mutex
+}{shapes: map[string]*schemaShape{}}
+
+// schemaJSONType mappa un valore decodificato sul suo tipo JSON
+func schemaJSONType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case nil:
+		return "null"
+	default:
+		return "unknown"
+	}
+}
+
+// schemaWalk registra ricorsivamente i tipi dei campi (percorsi puntati,
+// elementi array come "campo[]")
+func schemaWalk(shape *schemaShape, path string, value interface{}, depth int, now time.Time) {
+	if len(shape.Fields) >= schemaMaxFields {
+		return
+	}
+
+	info, ok := shape.Fields[path]
+	if !ok {
+		info = &schemaFieldInfo{Types: map[string]int{}, FirstSeen: now}
+		shape.Fields[path] = info
+	}
+	jsonType := schemaJSONType(value)
+	if info.Types[jsonType] == 0 && len(info.Types) > 0 {
+		log.Printf("SCHEMA_DRIFT: campo %s visto come %s (tipi precedenti: %v)", path, jsonType, info.Types)
+	}
+	info.Types[jsonType]++
+	info.LastSeen = now
+
+	if depth >= schemaMaxDepth {
+		return
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			schemaWalk(shape, path+"."+key, nested, depth+1, now)
+		}
+	case []interface{}:
+		// basta il primo elemento: gli array del backend sono omogenei
+		if len(typed) > 0 {
+			schemaWalk(shape, path+"[]", typed[0], depth+1, now)
+		}
+	}
+}
+
+// SchemaObserve campiona una risposta backend e ne registra la struttura.
+// Lo shape key è il data_type dichiarato negli slot, o l'intent in assenza.
+func SchemaObserve(intent string, slots map[string]interface{}) {
+	config := LoadConfig()
+	if config.SchemaRecorder == nil || !config.SchemaRecorder.Enabled || len(slots) == 0 {
+		return
+	}
+	sampleRate := config.SchemaRecorder.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = schemaDefaultSampleRate
+	}
+	if rand.Float64() >= sampleRate {
+		return
+	}
+
+	shapeKey := "intent:" + intent
+	if dataType, ok := slots["data_type"].(string); ok && dataType != "" {
+		shapeKey = dataType
+	}
+
+	now := time.Now()
+	schemaRec.mu.Lock()
+	defer schemaRec.mu.Unlock()
+
+	shape, ok := schemaRec.shapes[shapeKey]
+	if !ok {
+		shape = &schemaShape{Fields: map[string]*schemaFieldInfo{}, FirstSeen: now}
+		schemaRec.shapes[shapeKey] = shape
+		log.Printf("SCHEMA_NEW_SHAPE: %s", shapeKey)
+	}
+	shape.Samples++
+	shape.LastSeen = now
+
+	for key, value := range slots {
+		schemaWalk(shape, key, value, 1, now)
+	}
+}
+
+// HandleSchemaReport espone gli schemi osservati e il type drift rilevato
+// GET /admin/api/schema-report
+func HandleSchemaReport(c *gin.Context) {
+	schemaRec.mu.Lock()
+	defer schemaRec.mu.Unlock()
+
+	shapes := make(map[string]gin.H, len(schemaRec.shapes))
+	var drift []gin.H
+	for shapeKey, shape := range schemaRec.shapes {
+		fields := make(map[string]gin.H, len(shape.Fields))
+		paths := make([]string, 0, len(shape.Fields))
+		for path := range shape.Fields {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			info := shape.Fields[path]
+			fields[path] = gin.H{
+				"types":      info.Types,
+				"first_seen": info.FirstSeen,
+				"last_seen":  info.LastSeen,
+			}
+			if len(info.Types) > 1 {
+				drift = append(drift, gin.H{
+					"shape": shapeKey,
+					"field": path,
+					"types": info.Types,
+				})
+			}
+		}
+		shapes[shapeKey] = gin.H{
+			"samples":    shape.Samples,
+			"first_seen": shape.FirstSeen,
+			"last_seen":  shape.LastSeen,
+			"fields":     fields,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"shapes": shapes,
+		"drift":  drift,
+	})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Cambio di scope ASL al volo: i supervisori regionali afferiscono a più
+// ASL ma la sessione ne tiene una sola, e oggi per cambiare prospettiva
+// devono rientrare dal portale con parametri diversi. POST /api/scope
+// cambia l'ASL attiva della sessione, validandola contro la lista di ASL
+// consentite all'utente: quella di personale.csv più le eventuali ASL
+// aggiuntive dichiarate in config (sezione scope.supervisors, con "*"
+// per lo scope regionale completo).
+
+// ScopeConfig dichiara gli utenti multi-ASL (sezione scope)
+type ScopeConfig struct {
+	// Supervisors: user_id -> ASL aggiuntive consentite oltre a quella
+	// di personale.csv ("*" = tutte le ASL della regione)
+	Supervisors map[string][]string `json:"supervisors"`
+}
+
+// AllowedASLsFor ritorna le ASL consentite all'utente in forma canonica:
+// quella risultante da personale.csv più le voci di scope.supervisors
+func AllowedASLsFor(config *Config, userID string) []string {
+	allowed := map[string]bool{}
+
+	if userID != "" {
+		if uid, err := strconv.Atoi(userID); err == nil {
+			if record, err := GetPersonaleByUserID(uid); err == nil {
+				if canonical, ok := NormalizeASLName(record.ASL); ok {
+					allowed[canonical] = true
+				}
+			}
+		}
+	}
+
+	for _, entry := range config.Scope.Supervisors[userID] {
+		if strings.TrimSpace(entry) == "*" {
+			for _, canonical := range canonicalASLs {
+				allowed[canonical] = true
+			}
+			break
+		}
+		if canonical, ok := NormalizeASLName(entry); ok {
+			allowed[canonical] = true
+		}
+	}
+
+	var result []string
+	for asl := range allowed {
+		result = append(result, asl)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// HandleScopeSwitch cambia l'ASL attiva della sessione
+// POST /api/scope {"asl": "SALERNO"}
+func HandleScopeSwitch(c *gin.Context) {
+	var req struct {
+		ASL string `json:"asl"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	canonical, ok := NormalizeASLName(req.ASL)
+	if !ok {
+		AbortWithError(c, ErrInvalidParameter, "ASL non riconosciuta: "+req.ASL)
+		return
+	}
+
+	session := sessions.Default(c)
+	userID := ""
+	if val, sok := session.Get("user_id").(string); sok {
+		userID = val
+	}
+	if userID == "" {
+		AbortWithError(c, ErrForbidden, "nessuna identità in sessione")
+		return
+	}
+
+	config := LoadConfig()
+	allowed := AllowedASLsFor(config, userID)
+	permitted := false
+	for _, asl := range allowed {
+		if asl == canonical {
+			permitted = true
+			break
+		}
+	}
+	if !permitted {
+		log.Printf("SCOPE_DENIED: user_id=%s, asl_richiesta=%s, consentite=%v", userID, canonical, allowed)
+		AbortWithError(c, ErrForbidden, "ASL non consentita per questo utente")
+		return
+	}
+
+	// Aggiorna la sessione: l'asl_id viene azzerato perché riferito alla
+	// vecchia ASL (il backend risolve dal nome)
+	session.Set("asl_name", canonical)
+	session.Set("asl_id", "")
+	session.Set("timestamp", time.Now().Unix())
+	if err := session.Save(); err != nil {
+		log.Printf("SCOPE_SAVE_ERROR: %v", err)
+		AbortWithError(c, ErrInternal, "impossibile aggiornare la sessione")
+		return
+	}
+
+	log.Printf("SCOPE_SWITCHED: user_id=%s, asl=%s", userID, canonical)
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "success",
+		"asl":         canonical,
+		"allowed_asl": allowed,
+	})
+}
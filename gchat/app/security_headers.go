@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware di security header configurabili (CSP, frame-ancestors, HSTS,
+// Referrer-Policy, Permissions-Policy) richiesti dalla scansione di
+// sicurezza dell'hosting. I valori arrivano da config: la modalità embed
+// (widget incorporato nel portale) richiede una frame-ancestors diversa.
+
+type SecurityHeadersConfig struct {
+	Enabled               bool   `json:"enabled"`
+	ContentSecurityPolicy string `json:"content_security_policy"`
+	FrameAncestors        string `json:"frame_ancestors"` // usata in CSP; vuota = deny via X-Frame-Options
+	HSTSMaxAge            int    `json:"hsts_max_age"`    // secondi; 0 = header non emesso
+	ReferrerPolicy        string `json:"referrer_policy"`
+	PermissionsPolicy     string `json:"permissions_policy"`
+}
+
+// defaultSecurityHeaders ritorna una policy restrittiva di base
+func defaultSecurityHeaders() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		Enabled:               true,
+		ContentSecurityPolicy: "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; script-src 'self' 'unsafe-inline'",
+		FrameAncestors:        "'self'",
+		HSTSMaxAge:            0, // abilitare solo con HTTPS in produzione
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		PermissionsPolicy:     "microphone=(self), camera=(), geolocation=()",
+	}
+}
+
+// SecurityHeadersMiddleware emette gli header di sicurezza configurati
+func SecurityHeadersMiddleware(cfg SecurityHeadersConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		csp := cfg.ContentSecurityPolicy
+		if cfg.FrameAncestors != "" {
+			if csp != "" {
+				csp += "; "
+			}
+			csp += "frame-ancestors " + cfg.FrameAncestors
+		} else {
+			c.Header("X-Frame-Options", "DENY")
+		}
+		if csp != "" {
+			c.Header("Content-Security-Policy", csp)
+		}
+
+		if cfg.HSTSMaxAge > 0 {
+			c.Header("Strict-Transport-Security", "max-age="+strconv.Itoa(cfg.HSTSMaxAge)+"; includeSubDomains")
+		}
+		if cfg.ReferrerPolicy != "" {
+			c.Header("Referrer-Policy", cfg.ReferrerPolicy)
+		}
+		if cfg.PermissionsPolicy != "" {
+			c.Header("Permissions-Policy", cfg.PermissionsPolicy)
+		}
+		c.Header("X-Content-Type-Options", "nosniff")
+
+		c.Next()
+	}
+}
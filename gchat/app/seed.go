@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+)
+
+// Generatore di dati seed per sviluppo locale e staging: produce un
+// personale.csv realistico ma fittizio, una config di esempio e dati mock
+// per le dashboard (usage metering, job, tag conversazioni), così nuovi
+// contributori possono avviare tutto senza dati di produzione.
+// Uso: ./bin/gchat seed [-size N]
+
+var seedASLs = []string{
+	"AVELLINO", "BENEVENTO", "CASERTA",
+	"NAPOLI 1 CENTRO", "NAPOLI 2 NORD", "NAPOLI 3 SUD", "SALERNO",
+}
+
+var seedFirstNames = []string{
+	"Mario", "Giulia", "Antonio", "Francesca", "Giuseppe", "Anna",
+	"Luigi", "Maria", "Salvatore", "Carmela", "Vincenzo", "Rosa",
+}
+
+var seedLastNames = []string{
+	"Esposito", "Russo", "Romano", "Ricci", "Marino", "Greco",
+	"Bruno", "Gallo", "Conti", "DeLuca", "Costa", "Giordano",
+}
+
+// seedCodiceFiscale genera un codice fiscale sintatticamente plausibile ma fittizio
+func seedCodiceFiscale(rng *rand.Rand) string {
+	letters := "ABCDEFGHILMNOPQRSTUVZ"
+	var sb strings.Builder
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(letters[rng.Intn(len(letters))])
+	}
+	sb.WriteString(fmt.Sprintf("%02d", 50+rng.Intn(40)))
+	sb.WriteByte(letters[rng.Intn(len(letters))])
+	sb.WriteString(fmt.Sprintf("%02d", 1+rng.Intn(28)))
+	sb.WriteString("A783")
+	sb.WriteByte(letters[rng.Intn(len(letters))])
+	return sb.String()
+}
+
+// RunSeed genera i dati fittizi e termina. Invocato dal sottocomando "seed".
+func RunSeed(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	size := fs.Int("size", 50, "numero di record personale da generare")
+	outDir := fs.String("out", "data", "directory di output per i dati generati")
+	fs.Parse(args)
+
+	rng := rand.New(rand.NewSource(42)) // seme fisso: output riproducibile
+
+	if err := os.MkdirAll(*outDir, 0755); err != nil {
+		log.Fatalf("SEED_ERROR: cannot create output directory: %v", err)
+	}
+
+	// 1. personale.csv fittizio
+	var csv strings.Builder
+	csv.WriteString("ASL,DescrizioneAreaStrutturaComplessa,Descrizione,NameFirst,NameLast,CodiceFiscale,UserID\n")
+	for i := 0; i < *size; i++ {
+		asl := seedASLs[rng.Intn(len(seedASLs))]
+		first := seedFirstNames[rng.Intn(len(seedFirstNames))]
+		last := seedLastNames[rng.Intn(len(seedLastNames))]
+		uoc := fmt.Sprintf("UOC VETERINARIA %c", 'A'+rng.Intn(3))
+		descrizione := fmt.Sprintf("ASL %s->%s->SERVIZIO IGIENE ALIMENTI", asl, uoc)
+		csv.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s,%s,%d\n",
+			asl, uoc, descrizione, first, last, seedCodiceFiscale(rng), 1000+i))
+	}
+	personalePath := *outDir + "/personale.csv"
+	if err := os.WriteFile(personalePath, []byte(csv.String()), 0644); err != nil {
+		log.Fatalf("SEED_ERROR: cannot write %s: %v", personalePath, err)
+	}
+	fmt.Printf("Generato %s (%d record)\n", personalePath, *size)
+
+	// 2. Config di esempio (non sovrascrive config/config.json esistente)
+	sampleConfig := getDefaultConfig()
+	if data, err := json.MarshalIndent(sampleConfig, "", "  "); err == nil {
+		samplePath := "config/config.sample.json"
+		if err := os.MkdirAll("config", 0755); err == nil {
+			if err := os.WriteFile(samplePath, data, 0644); err == nil {
+				fmt.Printf("Generato %s\n", samplePath)
+			}
+		}
+	}
+
+	// 3. Dati mock usage metering (ultimi 3 mesi)
+	now := time.Now()
+	var usage []*UsageRecord
+	for m := 0; m < 3; m++ {
+		month := now.AddDate(0, -m, 0).Format(monthFormat)
+		for _, asl := range seedASLs {
+			usage = append(usage, &UsageRecord{
+				Month:                month,
+				ASL:                  asl,
+				Turns:                int64(50 + rng.Intn(500)),
+				StreamedSeconds:      float64(rng.Intn(10000)),
+				TranscriptionMinutes: float64(rng.Intn(120)),
+				BackendTokens:        int64(rng.Intn(1000000)),
+			})
+		}
+	}
+	if data, err := json.MarshalIndent(usage, "", "  "); err == nil {
+		usagePath := *outDir + "/usage_metering.json"
+		if err := os.WriteFile(usagePath, data, 0644); err == nil {
+			fmt.Printf("Generato %s (%d record)\n", usagePath, len(usage))
+		}
+	}
+
+	// 4. Tag conversazioni mock
+	tags := []*ConversationMeta{
+		{SessionID: "seed-conv-1", Owner: "user", Tags: []string{"procedura campionamento"}, Bookmarked: true, UpdatedAt: now.Unix()},
+		{SessionID: "seed-conv-2", Owner: "user", Tags: []string{"piani monitoraggio"}, UpdatedAt: now.Unix()},
+	}
+	if data, err := json.MarshalIndent(tags, "", "  "); err == nil {
+		tagsPath := *outDir + "/conversation_tags.json"
+		if err := os.WriteFile(tagsPath, data, 0644); err == nil {
+			fmt.Printf("Generato %s (%d record)\n", tagsPath, len(tags))
+		}
+	}
+
+	fmt.Println("Seed completato. Avviare il server con ./all.sh")
+}
@@ -10,10 +10,43 @@ import (
 )
 
 const (
-	// SessionTTL definisce il timeout della sessione in secondi (5 minuti, come backend Python)
-	SessionTTL = 300
+	// DefaultSessionTTL è il timeout di default della sessione in secondi
+	// (5 minuti, come backend Python); configurabile via session.ttl_seconds
+	DefaultSessionTTL = 300
+
+	// SessionExpiryWarning: secondi prima della scadenza a cui emettere
+	// l'evento SSE session_expiring per avvisare la UI
+	SessionExpiryWarning = 60
 )
 
+// SessionTTLSeconds ritorna il TTL di sessione configurato (o il default)
+func SessionTTLSeconds() int {
+	cfg := LoadConfig()
+	if cfg.Session.TTLSeconds > 0 {
+		return cfg.Session.TTLSeconds
+	}
+	return DefaultSessionTTL
+}
+
+// SessionRemainingSeconds ritorna i secondi rimanenti prima della scadenza
+// della sessione, o -1 se la sessione non ha timestamp (nessuna identità)
+func SessionRemainingSeconds(c *gin.Context) int64 {
+	session := sessions.Default(c)
+	timestamp := session.Get("timestamp")
+	if timestamp == nil {
+		return -1
+	}
+	ts, ok := timestamp.(int64)
+	if !ok {
+		return -1
+	}
+	remaining := int64(SessionTTLSeconds()) - (time.Now().Unix() - ts)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
 // SessionMiddleware verifica la validità della sessione basandosi sul TTL
 func SessionMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -22,7 +55,7 @@ func SessionMiddleware() gin.HandlerFunc {
 		// Verifica TTL
 		if timestamp := session.Get("timestamp"); timestamp != nil {
 			if ts, ok := timestamp.(int64); ok {
-				if time.Now().Unix()-ts > SessionTTL {
+				if time.Now().Unix()-ts > int64(SessionTTLSeconds()) {
 					// Sessione scaduta, pulisci
 					session.Clear()
 					if err := session.Save(); err != nil {
@@ -166,3 +199,30 @@ func MergeSessionParams(c *gin.Context) (userID, aslID, aslName, cf, username st
 
 	return
 }
+
+// HandleSessionPing estende la sessione corrente aggiornandone il timestamp,
+// così la UI può mantenere viva l'identità durante conversazioni lunghe.
+// POST /api/session/ping
+func HandleSessionPing(c *gin.Context) {
+	session := sessions.Default(c)
+
+	if session.Get("timestamp") == nil {
+		// Nessuna sessione attiva da estendere
+		AbortWithError(c, ErrNotFound, "nessuna sessione attiva")
+		return
+	}
+
+	session.Set("timestamp", time.Now().Unix())
+	if err := session.Save(); err != nil {
+		log.Printf("SESSION_PING_ERROR: %v", err)
+		AbortWithError(c, ErrInternal, "impossibile estendere la sessione")
+		return
+	}
+
+	ttl := SessionTTLSeconds()
+	log.Printf("SESSION_PING: sessione estesa di %ds, client_ip=%s", ttl, c.ClientIP())
+	c.JSON(200, gin.H{
+		"status":      "success",
+		"ttl_seconds": ttl,
+	})
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Analytics di sessione persistite: registriamo inizio/fine sessione,
+// snapshot dell'identità, user agent, pagina di ingresso e conversazioni
+// collegate, così le domande "quante sessioni al giorno per ASL?" e
+// "quanti turni in media?" hanno una risposta senza spulciare i log grezzi.
+
+const (
+	sessionAnalyticsFile = "data/sessions.json"
+	sessionAnalyticsKey  = "analytics_session_id" // id sessione nel cookie
+	sessionDayFormat     = "2006-01-02"
+)
+
+// SessionRecord è una sessione utente con le conversazioni collegate
+type SessionRecord struct {
+	ID            string    `json:"id"`
+	StartedAt     time.Time `json:"started_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	ASL           string    `json:"asl,omitempty"`
+	UserID        string    `json:"user_id,omitempty"`
+	CodiceFiscale string    `json:"codice_fiscale,omitempty"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	EntryPage     string    `json:"entry_page,omitempty"`
+	Turns         int64     `json:"turns"`
+	Variant       string    `json:"variant,omitempty"`       // variante backend servita (stable/canary)
+	Conversations []string  `json:"conversations,omitempty"` // sender id collegati
+}
+
+type sessionAnalyticsStore struct {
+	records map[string]*SessionRecord
+	mu      sync.Mutex
+	loaded  bool
+}
+
+var sessionAnalytics = &sessionAnalyticsStore{
+	records: make(map[string]*SessionRecord),
+}
+
+// ensureLoaded carica i dati persistiti al primo accesso (lazy loading)
+func (s *sessionAnalyticsStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(sessionAnalyticsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("SESSION_ANALYTICS_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+
+	var records []*SessionRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		log.Printf("SESSION_ANALYTICS_LOAD_ERROR: parse failed: %v", err)
+		return
+	}
+	for _, r := range records {
+		s.records[r.ID] = r
+	}
+	log.Printf("SESSION_ANALYTICS_LOADED: %d sessioni caricate da %s", len(records), sessionAnalyticsFile)
+}
+
+// persist salva lo stato corrente su file (chiamare con lock acquisito)
+func (s *sessionAnalyticsStore) persist() {
+	records := make([]*SessionRecord, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		log.Printf("SESSION_ANALYTICS_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.MkdirAll("data", 0755); err != nil {
+		log.Printf("SESSION_ANALYTICS_PERSIST_ERROR: cannot create data directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(sessionAnalyticsFile, data, 0644); err != nil {
+		log.Printf("SESSION_ANALYTICS_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// analyticsSessionID recupera (o crea) l'id analytics nel cookie di sessione
+func analyticsSessionID(c *gin.Context) string {
+	session := sessions.Default(c)
+	if id, ok := session.Get(sessionAnalyticsKey).(string); ok && id != "" {
+		return id
+	}
+	id := newContinuationHandle()
+	if id == "" {
+		return ""
+	}
+	session.Set(sessionAnalyticsKey, id)
+	if err := session.Save(); err != nil {
+		log.Printf("SESSION_ANALYTICS_COOKIE_ERROR: %v", err)
+	}
+	return id
+}
+
+// SessionTouch apre o aggiorna la sessione analytics al rendering pagina
+// (snapshot identità + pagina di ingresso alla prima visita)
+func SessionTouch(c *gin.Context, asl, userID, codiceFiscale string) {
+	id := analyticsSessionID(c)
+	if id == "" {
+		return
+	}
+
+	sessionAnalytics.mu.Lock()
+	defer sessionAnalytics.mu.Unlock()
+	sessionAnalytics.ensureLoaded()
+
+	rec, ok := sessionAnalytics.records[id]
+	if !ok {
+		rec = &SessionRecord{
+			ID:        id,
+			StartedAt: time.Now(),
+			EntryPage: c.Request.URL.Path,
+			UserAgent: c.Request.UserAgent(),
+		}
+		sessionAnalytics.records[id] = rec
+		log.Printf("SESSION_ANALYTICS_START: id=%s, asl=%s, entry=%s", id, asl, rec.EntryPage)
+	}
+	rec.LastSeenAt = time.Now()
+	if asl != "" {
+		rec.ASL = asl
+	}
+	if userID != "" {
+		rec.UserID = userID
+	}
+	if codiceFiscale != "" {
+		rec.CodiceFiscale = codiceFiscale
+	}
+	sessionAnalytics.persist()
+}
+
+// SessionRecordTurn collega un turno di chat alla sessione analytics
+func SessionRecordTurn(c *gin.Context, sender string) {
+	session := sessions.Default(c)
+	id, ok := session.Get(sessionAnalyticsKey).(string)
+	if !ok || id == "" {
+		return
+	}
+
+	sessionAnalytics.mu.Lock()
+	defer sessionAnalytics.mu.Unlock()
+	sessionAnalytics.ensureLoaded()
+
+	rec, ok := sessionAnalytics.records[id]
+	if !ok {
+		return
+	}
+	rec.Turns++
+	rec.LastSeenAt = time.Now()
+
+	found := false
+	for _, conv := range rec.Conversations {
+		if conv == sender {
+			found = true
+			break
+		}
+	}
+	if !found && sender != "" {
+		rec.Conversations = append(rec.Conversations, sender)
+	}
+	sessionAnalytics.persist()
+}
+
+// SessionTagVariant tagga la sessione con la variante di backend servita
+// (canary routing, vedi canary.go)
+func SessionTagVariant(c *gin.Context, variant string) {
+	if variant == "" {
+		return
+	}
+	session := sessions.Default(c)
+	id, ok := session.Get(sessionAnalyticsKey).(string)
+	if !ok || id == "" {
+		return
+	}
+
+	sessionAnalytics.mu.Lock()
+	defer sessionAnalytics.mu.Unlock()
+	sessionAnalytics.ensureLoaded()
+
+	rec, ok := sessionAnalytics.records[id]
+	if !ok || rec.Variant == variant {
+		return
+	}
+	rec.Variant = variant
+	sessionAnalytics.persist()
+}
+
+// HandleSessionAnalytics espone le aggregazioni sulle sessioni registrate
+// GET /api/analytics/sessions?from=YYYY-MM-DD&to=YYYY-MM-DD
+func HandleSessionAnalytics(c *gin.Context) {
+	var from, to time.Time
+	var err error
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.Parse(sessionDayFormat, raw); err != nil {
+			AbortWithError(c, ErrInvalidParameter, "parametro from non valido, formato atteso YYYY-MM-DD")
+			return
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.Parse(sessionDayFormat, raw); err != nil {
+			AbortWithError(c, ErrInvalidParameter, "parametro to non valido, formato atteso YYYY-MM-DD")
+			return
+		}
+		to = to.Add(24 * time.Hour)
+	}
+
+	sessionAnalytics.mu.Lock()
+	sessionAnalytics.ensureLoaded()
+
+	perDayASL := make(map[string]map[string]int64) // giorno -> asl -> sessioni
+	var totalSessions, totalTurns int64
+	for _, rec := range sessionAnalytics.records {
+		if !from.IsZero() && rec.StartedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !rec.StartedAt.Before(to) {
+			continue
+		}
+		day := rec.StartedAt.Format(sessionDayFormat)
+		asl := rec.ASL
+		if asl == "" {
+			asl = meterNoASL
+		}
+		if perDayASL[day] == nil {
+			perDayASL[day] = make(map[string]int64)
+		}
+		perDayASL[day][asl]++
+		totalSessions++
+		totalTurns += rec.Turns
+	}
+	sessionAnalytics.mu.Unlock()
+
+	days := make([]string, 0, len(perDayASL))
+	for day := range perDayASL {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	sessionsPerDay := make([]gin.H, 0, len(days))
+	for _, day := range days {
+		sessionsPerDay = append(sessionsPerDay, gin.H{
+			"day":     day,
+			"per_asl": perDayASL[day],
+		})
+	}
+
+	avgTurns := 0.0
+	if totalSessions > 0 {
+		avgTurns = float64(totalTurns) / float64(totalSessions)
+	}
+
+	log.Printf("SESSION_ANALYTICS_REPORT: sessioni=%d, turni=%d, client_ip=%s",
+		totalSessions, totalTurns, c.ClientIP())
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":                "success",
+		"total_sessions":        totalSessions,
+		"total_turns":           totalTurns,
+		"avg_turns_per_session": avgTurns,
+		"sessions_per_day":      sessionsPerDay,
+	})
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Link di condivisione conversazione: gli ispettori oggi condividono le
+// risposte con i colleghi via screenshot. Un link firmato HMAC e a
+// scadenza rende leggibile la trascrizione in sola lettura, con la PII di
+// terzi oscurata dallo stesso filtro usato per le trascrizioni audio. Il
+// segreto di firma arriva dalla variabile d'ambiente SHARE_LINK_SECRET
+// (fallback share.secret in config).
+
+const shareDefaultTTLHours = 72
+
+// ShareConfig abilita la condivisione in config.json (sezione share)
+type ShareConfig struct {
+	Enabled  bool   `json:"enabled"`
+	TTLHours int    `json:"ttl_hours"` // validità del link (default 72)
+	Secret   string `json:"secret"`    // preferire env SHARE_LINK_SECRET
+}
+
+// shareSecret ritorna il segreto di firma: env var > config
+func shareSecret(config *Config) string {
+	if secret := os.Getenv("SHARE_LINK_SECRET"); secret != "" {
+		return secret
+	}
+	return config.Share.Secret
+}
+
+// signShareToken firma id conversazione e scadenza con HMAC-SHA256
+func signShareToken(secret, convID string, exp int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s|%d", convID, exp)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HandleCreateShare crea un link firmato a scadenza per la conversazione
+// POST /api/conversations/:sender/share (il sender è l'ID conversazione,
+// come per tag e bookmark)
+func HandleCreateShare(c *gin.Context) {
+	config := LoadConfig()
+	convID := c.Param("sender")
+
+	if !config.Share.Enabled {
+		AbortWithError(c, ErrForbidden, "condivisione conversazioni disabilitata (share.enabled)")
+		return
+	}
+	secret := shareSecret(config)
+	if secret == "" {
+		log.Printf("SHARE_ERROR: nessun segreto di firma configurato (SHARE_LINK_SECRET o share.secret)")
+		AbortWithError(c, ErrInternal, "condivisione non configurata sul server")
+		return
+	}
+
+	ttlHours := config.Share.TTLHours
+	if ttlHours <= 0 {
+		ttlHours = shareDefaultTTLHours
+	}
+	exp := time.Now().Add(time.Duration(ttlHours) * time.Hour).Unix()
+	sig := signShareToken(secret, convID, exp)
+	shareURL := fmt.Sprintf("%s/share/%s?exp=%d&sig=%s", BasePath(config), convID, exp, sig)
+
+	log.Printf("SHARE_CREATED: conversation=%s, expires=%s, client_ip=%s",
+		convID, time.Unix(exp, 0).Format("2006-01-02 15:04:05"), c.ClientIP())
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"url":        shareURL,
+		"expires_at": time.Unix(exp, 0),
+	})
+}
+
+// shareTranscript recupera la trascrizione con la PII di terzi oscurata
+func shareTranscript(config *Config, convID string) ([]gin.H, error) {
+	return conversationTurns(config, convID, true)
+}
+
+// conversationTurns recupera i messaggi della conversazione dal backend
+// (stessa API proxata dalla cronologia) in forma tollerante allo schema;
+// con maskPII attivo i testi passano dal filtro PII
+func conversationTurns(config *Config, convID string, maskPII bool) ([]gin.H, error) {
+	client := NewUpstreamClient("llm", time.Duration(config.LLMServer.Timeout)*time.Second)
+	resp, err := upstreamGet(client, config.LLMServer.URL+"/api/chat-log/conversation/"+convID)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("backend status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	var items []interface{}
+	for _, key := range []string{"messages", "results", "data", "conversation"} {
+		if list, ok := payload[key].([]interface{}); ok {
+			items = list
+			break
+		}
+	}
+
+	var turns []gin.H
+	for _, item := range items {
+		msg, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Con maskPII la PII di terzi (nomi, telefoni, CF, email) è oscurata
+		if text, ok := msg["user_message"].(string); ok && text != "" {
+			if maskPII {
+				text = MaskTranscriptionPII(text)
+			}
+			turns = append(turns, gin.H{"role": "user", "text": text})
+		}
+		if text, ok := msg["bot_response"].(string); ok && text != "" {
+			if maskPII {
+				text = MaskTranscriptionPII(text)
+			}
+			turns = append(turns, gin.H{"role": "bot", "text": text})
+		}
+	}
+	return turns, nil
+}
+
+// HandleShareView renderizza la trascrizione in sola lettura se il link è
+// valido e non scaduto
+// GET /share/:id?exp=...&sig=...
+func HandleShareView(c *gin.Context) {
+	config := LoadConfig()
+	convID := c.Param("id")
+
+	if !config.Share.Enabled {
+		AbortWithError(c, ErrForbidden, "condivisione conversazioni disabilitata (share.enabled)")
+		return
+	}
+	secret := shareSecret(config)
+	exp, err := strconv.ParseInt(c.Query("exp"), 10, 64)
+	if secret == "" || err != nil {
+		AbortWithError(c, ErrForbidden, "link di condivisione non valido")
+		return
+	}
+	expected := signShareToken(secret, convID, exp)
+	if !hmac.Equal([]byte(expected), []byte(c.Query("sig"))) {
+		log.Printf("SHARE_INVALID_SIG: conversation=%s, client_ip=%s", convID, c.ClientIP())
+		AbortWithError(c, ErrForbidden, "link di condivisione non valido")
+		return
+	}
+	if time.Now().Unix() > exp {
+		log.Printf("SHARE_EXPIRED: conversation=%s, client_ip=%s", convID, c.ClientIP())
+		AbortWithError(c, ErrForbidden, "link di condivisione scaduto")
+		return
+	}
+
+	turns, err := shareTranscript(config, convID)
+	if err != nil {
+		log.Printf("SHARE_ERROR: recupero trascrizione fallito - conversation=%s, error=%v", convID, err)
+		AbortWithError(c, ErrBackendError, "trascrizione non disponibile")
+		return
+	}
+
+	log.Printf("SHARE_VIEW: conversation=%s, turns=%d, client_ip=%s", convID, len(turns), c.ClientIP())
+
+	c.HTML(http.StatusOK, "share.html", gin.H{
+		"title":     "Conversazione condivisa - Assistente Gisa",
+		"turns":     turns,
+		"expiresAt": time.Unix(exp, 0).Format("02/01/2006 15:04"),
+	})
+}
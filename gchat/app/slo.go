@@ -0,0 +1,175 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tracciamento SLO di latenza per intent: un saluto deve rispondere in
+// frazioni di secondo, un report completo può impiegare decine di secondi.
+// I target (p95 in millisecondi) sono configurabili per intent nella
+// sezione slo di config.json; le violazioni vengono loggate ed esposte
+// sulla API del monitor per alimentare l'allertamento.
+
+const (
+	sloDefaultWindow = 200 // campioni per intent nella finestra mobile
+	sloMaxViolations = 50  // violazioni recenti conservate
+	sloMinSamples    = 10  // campioni minimi prima di valutare il p95
+)
+
+// SLOConfig definisce i target di latenza in config.json
+type SLOConfig struct {
+	Enabled         bool               `json:"enabled"`
+	DefaultTargetMs float64            `json:"default_target_ms"` // fallback per intent non mappati
+	TargetsMs       map[string]float64 `json:"targets_ms"`        // intent -> target p95 in ms
+	WindowSize      int                `json:"window_size"`       // campioni della finestra mobile
+}
+
+// sloSeries è la finestra mobile di campioni di latenza per un intent
+type sloSeries struct {
+	samples []float64
+	pos     int
+	count   int
+}
+
+// SLOViolation registra un superamento del target al momento dell'osservazione
+type SLOViolation struct {
+	Intent    string    `json:"intent"`
+	P95Ms     float64   `json:"p95_ms"`
+	TargetMs  float64   `json:"target_ms"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type sloTracker struct {
+	series     map[string]*sloSeries
+	violations []SLOViolation
+	mu         sync.Mutex
+}
+
+var sloState = &sloTracker{
+	series: make(map[string]*sloSeries),
+}
+
+// sloTargetFor risolve il target p95 per un intent (0 = non tracciato)
+func sloTargetFor(config *Config, intent string) float64 {
+	if target, ok := config.SLO.TargetsMs[intent]; ok {
+		return target
+	}
+	return config.SLO.DefaultTargetMs
+}
+
+// percentile calcola il percentile richiesto su una copia ordinata dei campioni
+func percentile(samples []float64, p float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(samples))
+	copy(sorted, samples)
+	sort.Float64s(sorted)
+
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
+
+// RecordIntentLatency registra un campione di latenza per l'intent e
+// verifica lo SLO sulla finestra corrente. Chiamata dai percorsi chat
+// (sincrono e streaming) a risposta completata.
+func RecordIntentLatency(intent string, elapsed time.Duration) {
+	config := LoadConfig()
+	if !config.SLO.Enabled || intent == "" {
+		return
+	}
+
+	window := config.SLO.WindowSize
+	if window <= 0 {
+		window = sloDefaultWindow
+	}
+
+	sloState.mu.Lock()
+	defer sloState.mu.Unlock()
+
+	series, ok := sloState.series[intent]
+	if !ok {
+		series = &sloSeries{samples: make([]float64, window)}
+		sloState.series[intent] = series
+	}
+
+	series.samples[series.pos] = elapsed.Seconds() * 1000
+	series.pos = (series.pos + 1) % len(series.samples)
+	if series.count < len(series.samples) {
+		series.count++
+	}
+
+	target := sloTargetFor(config, intent)
+	if target <= 0 || series.count < sloMinSamples {
+		return
+	}
+
+	p95 := percentile(series.samples[:series.count], 0.95)
+	if p95 > target {
+		violation := SLOViolation{
+			Intent:    intent,
+			P95Ms:     p95,
+			TargetMs:  target,
+			Timestamp: time.Now(),
+		}
+		sloState.violations = append(sloState.violations, violation)
+		if len(sloState.violations) > sloMaxViolations {
+			sloState.violations = sloState.violations[len(sloState.violations)-sloMaxViolations:]
+		}
+		log.Printf("SLO_VIOLATION: intent=%s, p95=%.0fms, target=%.0fms, campioni=%d",
+			intent, p95, target, series.count)
+	}
+}
+
+// SLOReport produce lo stato corrente per intent (per il monitor e gli alert)
+func SLOReport() []gin.H {
+	config := LoadConfig()
+
+	sloState.mu.Lock()
+	defer sloState.mu.Unlock()
+
+	intents := make([]string, 0, len(sloState.series))
+	for intent := range sloState.series {
+		intents = append(intents, intent)
+	}
+	sort.Strings(intents)
+
+	report := make([]gin.H, 0, len(intents))
+	for _, intent := range intents {
+		series := sloState.series[intent]
+		target := sloTargetFor(config, intent)
+		p95 := percentile(series.samples[:series.count], 0.95)
+		report = append(report, gin.H{
+			"intent":    intent,
+			"samples":   series.count,
+			"p95_ms":    p95,
+			"target_ms": target,
+			"violated":  target > 0 && series.count >= sloMinSamples && p95 > target,
+		})
+	}
+	return report
+}
+
+// HandleSLOReport espone lo stato SLO e le violazioni recenti
+// GET /admin/api/slo
+func HandleSLOReport(c *gin.Context) {
+	config := LoadConfig()
+
+	sloState.mu.Lock()
+	violations := make([]SLOViolation, len(sloState.violations))
+	copy(violations, sloState.violations)
+	sloState.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"enabled":    config.SLO.Enabled,
+		"intents":    SLOReport(),
+		"violations": violations,
+	})
+}
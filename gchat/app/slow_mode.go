@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Slow-mode progressivo: il rate limit a soglia secca penalizza anche gli
+// utenti legittimi più entusiasti (un veterinario che incalza con dieci
+// domande di fila non è un bot). Invece di rifiutare con un 429, oltre le
+// prime N richieste al minuto il gateway inserisce ritardi artificiali
+// crescenti accompagnati da un avviso visibile "slow mode"; il 429 duro
+// resta riservato ai volumi da abuso vero (vedi anche abuse.go, che
+// continua a gestire i pattern di messaggi identici).
+
+const (
+	slowModeDefaultFreePerMinute = 6
+	slowModeDefaultDelayStepMs   = 1500
+	slowModeDefaultMaxDelayMs    = 10000
+	slowModeDefaultHardPerMinute = 30
+)
+
+// SlowModeConfig configura la politica slow-mode (sezione slow_mode)
+type SlowModeConfig struct {
+	Enabled bool `json:"enabled"`
+	// FreePerMinute: messaggi al minuto senza alcun ritardo (default 6)
+	FreePerMinute int `json:"free_per_minute"`
+	// DelayStepMs: incremento del ritardo per ogni messaggio oltre la
+	// soglia (default 1500)
+	DelayStepMs int `json:"delay_step_ms"`
+	// MaxDelayMs: tetto del ritardo artificiale (default 10000)
+	MaxDelayMs int `json:"max_delay_ms"`
+	// HardPerMinute: oltre questo volume scatta il 429 (default 30)
+	HardPerMinute int `json:"hard_per_minute"`
+}
+
+type slowModeState struct {
+	hits map[string][]time.Time // chiave utente/IP -> timestamp nella finestra
+	mu   sync.Mutex
+}
+
+var slowMode = &slowModeState{hits: map[string][]time.Time{}}
+
+// slowModeParams ritorna i parametri effettivi con i default
+func slowModeParams(config *Config) (free, stepMs, maxMs, hard int) {
+	free = config.SlowMode.FreePerMinute
+	if free <= 0 {
+		free = slowModeDefaultFreePerMinute
+	}
+	stepMs = config.SlowMode.DelayStepMs
+	if stepMs <= 0 {
+		stepMs = slowModeDefaultDelayStepMs
+	}
+	maxMs = config.SlowMode.MaxDelayMs
+	if maxMs <= 0 {
+		maxMs = slowModeDefaultMaxDelayMs
+	}
+	hard = config.SlowMode.HardPerMinute
+	if hard <= free {
+		hard = slowModeDefaultHardPerMinute
+	}
+	return free, stepMs, maxMs, hard
+}
+
+// SlowModeObserve conta il messaggio nella finestra di un minuto e ritorna
+// il ritardo artificiale da applicare con l'avviso per l'utente. hardBlock
+// true significa volume da abuso: la richiesta va rifiutata con 429.
+func SlowModeObserve(config *Config, userID, clientIP string) (delay time.Duration, notice string, hardBlock bool) {
+	if !config.SlowMode.Enabled {
+		return 0, "", false
+	}
+	free, stepMs, maxMs, hard := slowModeParams(config)
+	key := abuseKey(userID, clientIP)
+
+	slowMode.mu.Lock()
+	hits := pruneWindow(slowMode.hits[key], time.Minute)
+	hits = append(hits, time.Now())
+	slowMode.hits[key] = hits
+	count := len(hits)
+	slowMode.mu.Unlock()
+
+	if count > hard {
+		return 0, "", true
+	}
+	if count <= free {
+		return 0, "", false
+	}
+
+	delayMs := (count - free) * stepMs
+	if delayMs > maxMs {
+		delayMs = maxMs
+	}
+	notice = fmt.Sprintf("Stai inviando messaggi molto velocemente: risposta rallentata di %d secondi. "+
+		"Attendi qualche istante tra una domanda e l'altra.", (delayMs+999)/1000)
+	return time.Duration(delayMs) * time.Millisecond, notice, false
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// Smoke test conversazionali post-deploy: uno script YAML descrive una
+// sequenza di turni da eseguire contro il backend configurato, con
+// asserzioni su intent, sottostringhe della risposta e budget di latenza.
+// Sostituisce il check manuale "umano che digita le domande".
+// Uso: ./bin/gchat smoke -script scripts/smoke.yaml
+// oppure POST /admin/api/smoke/run con lo YAML nel body.
+
+// SmokeTurn è un singolo turno di conversazione con le sue asserzioni
+type SmokeTurn struct {
+	Message        string   `yaml:"message" json:"message"`
+	ExpectIntent   string   `yaml:"expect_intent" json:"expect_intent,omitempty"`
+	ExpectContains []string `yaml:"expect_contains" json:"expect_contains,omitempty"`
+	MaxLatencyMs   int      `yaml:"max_latency_ms" json:"max_latency_ms,omitempty"`
+}
+
+// SmokeScript è lo script YAML completo
+type SmokeScript struct {
+	Name     string            `yaml:"name" json:"name"`
+	Metadata map[string]string `yaml:"metadata" json:"metadata,omitempty"`
+	Turns    []SmokeTurn       `yaml:"turns" json:"turns"`
+}
+
+// SmokeTurnResult è l'esito di un singolo turno
+type SmokeTurnResult struct {
+	Message   string   `json:"message"`
+	Intent    string   `json:"intent"`
+	LatencyMs int64    `json:"latency_ms"`
+	Passed    bool     `json:"passed"`
+	Failures  []string `json:"failures,omitempty"`
+}
+
+// SmokeReport è il report pass/fail complessivo
+type SmokeReport struct {
+	Name       string            `json:"name"`
+	StartedAt  string            `json:"started_at"`
+	DurationMs int64             `json:"duration_ms"`
+	Passed     bool              `json:"passed"`
+	TurnsTotal int               `json:"turns_total"`
+	TurnsOK    int               `json:"turns_ok"`
+	Results    []SmokeTurnResult `json:"results"`
+}
+
+// ParseSmokeScript valida e deserializza uno script YAML
+func ParseSmokeScript(data []byte) (*SmokeScript, error) {
+	var script SmokeScript
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("YAML non valido: %v", err)
+	}
+	if len(script.Turns) == 0 {
+		return nil, fmt.Errorf("lo script non contiene turni")
+	}
+	for i, turn := range script.Turns {
+		if strings.TrimSpace(turn.Message) == "" {
+			return nil, fmt.Errorf("turno %d senza message", i+1)
+		}
+	}
+	return &script, nil
+}
+
+// RunSmokeScript esegue lo script contro il backend e produce il report.
+// I turni condividono lo stesso sender, così il backend mantiene il
+// contesto conversazionale tra un turno e l'altro.
+func RunSmokeScript(script *SmokeScript, llmServerURL string, timeout int) *SmokeReport {
+	start := time.Now()
+	sender := fmt.Sprintf("smoke_%d", start.UnixNano())
+
+	context := make(map[string]interface{})
+	for k, v := range script.Metadata {
+		context[k] = v
+	}
+
+	report := &SmokeReport{
+		Name:      script.Name,
+		StartedAt: start.Format(time.RFC3339),
+		Passed:    true,
+	}
+
+	for i, turn := range script.Turns {
+		turnStart := time.Now()
+		resp, err := SendToLLMV1(turn.Message, sender, llmServerURL, timeout, context)
+		latency := time.Since(turnStart).Milliseconds()
+
+		result := SmokeTurnResult{
+			Message:   turn.Message,
+			LatencyMs: latency,
+			Passed:    true,
+		}
+
+		if err != nil {
+			result.Passed = false
+			result.Failures = append(result.Failures, fmt.Sprintf("errore backend: %v", err))
+		} else {
+			result.Intent = resp.Result.Intent
+			if turn.ExpectIntent != "" && resp.Result.Intent != turn.ExpectIntent {
+				result.Passed = false
+				result.Failures = append(result.Failures,
+					fmt.Sprintf("intent atteso '%s', ottenuto '%s'", turn.ExpectIntent, resp.Result.Intent))
+			}
+			for _, substr := range turn.ExpectContains {
+				if !strings.Contains(strings.ToLower(resp.Result.Text), strings.ToLower(substr)) {
+					result.Passed = false
+					result.Failures = append(result.Failures,
+						fmt.Sprintf("risposta non contiene '%s'", substr))
+				}
+			}
+		}
+		if turn.MaxLatencyMs > 0 && latency > int64(turn.MaxLatencyMs) {
+			result.Passed = false
+			result.Failures = append(result.Failures,
+				fmt.Sprintf("latenza %dms oltre il budget di %dms", latency, turn.MaxLatencyMs))
+		}
+
+		if result.Passed {
+			report.TurnsOK++
+		} else {
+			report.Passed = false
+		}
+		report.Results = append(report.Results, result)
+
+		log.Printf("SMOKE_TURN: %d/%d passed=%v, intent=%s, latency=%dms",
+			i+1, len(script.Turns), result.Passed, result.Intent, latency)
+	}
+
+	report.TurnsTotal = len(script.Turns)
+	report.DurationMs = time.Since(start).Milliseconds()
+	return report
+}
+
+// RunSmoke esegue il sottocomando "smoke" da linea di comando e termina
+// con exit code 1 se almeno un turno fallisce (integrabile in CI/deploy)
+func RunSmoke(args []string) {
+	fs := flag.NewFlagSet("smoke", flag.ExitOnError)
+	scriptPath := fs.String("script", "scripts/smoke.yaml", "percorso dello script YAML")
+	fs.Parse(args)
+
+	data, err := os.ReadFile(*scriptPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Impossibile leggere lo script: %v\n", err)
+		os.Exit(1)
+	}
+
+	script, err := ParseSmokeScript(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Script non valido: %v\n", err)
+		os.Exit(1)
+	}
+
+	config := LoadConfig()
+	report := RunSmokeScript(script, config.LLMServer.URL, config.LLMServer.Timeout)
+
+	fmt.Printf("Smoke test '%s': %d/%d turni OK in %dms\n",
+		report.Name, report.TurnsOK, report.TurnsTotal, report.DurationMs)
+	for i, result := range report.Results {
+		status := "PASS"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		fmt.Printf("  [%s] %d. %s (intent=%s, %dms)\n", status, i+1, result.Message, result.Intent, result.LatencyMs)
+		for _, failure := range result.Failures {
+			fmt.Printf("         -> %s\n", failure)
+		}
+	}
+
+	if !report.Passed {
+		os.Exit(1)
+	}
+}
+
+// HandleSmokeRun esegue uno script smoke inviato nel body (YAML)
+// POST /admin/api/smoke/run
+func HandleSmokeRun(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	script, err := ParseSmokeScript(body)
+	if err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+
+	config := LoadConfig()
+	log.Printf("SMOKE_RUN: client_ip=%s, script=%s, turns=%d", c.ClientIP(), script.Name, len(script.Turns))
+
+	report := RunSmokeScript(script, config.LLMServer.URL, config.LLMServer.Timeout)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"report": report,
+	})
+}
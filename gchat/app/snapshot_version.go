@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Versioning dello snapshot dati: le risposte dipendono dall'anno di
+// riferimento e dalla sorgente dati del backend (/config). Il gateway
+// registra lo snapshot con cui ogni conversazione ha ricevuto l'ultima
+// risposta; quando un utente riprende una conversazione dopo un cambio di
+// snapshot (es. passaggio d'anno, ricarica dei dati) la risposta riporta
+// un avviso di staleness nei metadata, così la UI può segnalare che le
+// risposte precedenti potrebbero non essere più attuali.
+
+const (
+	snapshotFile     = "data/answer_snapshots.json"
+	snapshotCacheTTL = 5 * time.Minute
+)
+
+// snapshotRecord è lo snapshot registrato per una conversazione
+type snapshotRecord struct {
+	Snapshot  string    `json:"snapshot"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type snapshotStore struct {
+	records map[string]*snapshotRecord
+	loaded  bool
+
+	// cache dello snapshot corrente dal /config del backend
+	current   string
+	fetchedAt time.Time
+	mu        sync.Mutex
+}
+
+var snapshots = &snapshotStore{}
+
+func (s *snapshotStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.records = map[string]*snapshotRecord{}
+
+	data, err := os.ReadFile(snapshotFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("SNAPSHOT_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		log.Printf("SNAPSHOT_LOAD_ERROR: parse: %v", err)
+		s.records = map[string]*snapshotRecord{}
+	}
+}
+
+func (s *snapshotStore) persist() {
+	data, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		log.Printf("SNAPSHOT_PERSIST_ERROR: marshal: %v", err)
+		return
+	}
+	if err := os.WriteFile(snapshotFile, data, 0644); err != nil {
+		log.Printf("SNAPSHOT_PERSIST_ERROR: write: %v", err)
+	}
+}
+
+// currentDataSnapshot interroga /config del backend (con cache) e compone
+// l'identificatore di snapshot anno|sorgente (es. "2026|postgresql")
+func currentDataSnapshot() string {
+	snapshots.mu.Lock()
+	if snapshots.current != "" && time.Since(snapshots.fetchedAt) < snapshotCacheTTL {
+		cached := snapshots.current
+		snapshots.mu.Unlock()
+		return cached
+	}
+	snapshots.mu.Unlock()
+
+	config := LoadConfig()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var cfgResp ServerConfigResponse
+	if err := fetchBackendJSON(ctx, config.LLMServer.URL+"/config", &cfgResp); err != nil {
+		log.Printf("SNAPSHOT_FETCH_ERROR: %v", err)
+		snapshots.mu.Lock()
+		cached := snapshots.current
+		snapshots.mu.Unlock()
+		return cached // meglio lo snapshot scaduto che nessuno
+	}
+
+	snapshot := fmt.Sprintf("%d|%s", cfgResp.CurrentYear, cfgResp.DataSourceType)
+	snapshots.mu.Lock()
+	snapshots.current = snapshot
+	snapshots.fetchedAt = time.Now()
+	snapshots.mu.Unlock()
+	return snapshot
+}
+
+// SnapshotObserveTurn registra lo snapshot corrente per la conversazione e
+// ritorna (snapshot, avviso): l'avviso è valorizzato solo se la
+// conversazione aveva risposte prodotte con uno snapshot diverso.
+func SnapshotObserveTurn(sender string) (string, string) {
+	current := currentDataSnapshot()
+	if current == "" || sender == "" {
+		return current, ""
+	}
+
+	snapshots.mu.Lock()
+	defer snapshots.mu.Unlock()
+	snapshots.ensureLoaded()
+
+	warning := ""
+	if prev, ok := snapshots.records[sender]; ok && prev.Snapshot != current {
+		warning = fmt.Sprintf("I dati di riferimento sono cambiati dall'ultima risposta di questa conversazione (%s → %s): le risposte precedenti potrebbero non essere più attuali.",
+			prev.Snapshot, current)
+		log.Printf("SNAPSHOT_STALE: sender=%s, previous=%s, current=%s", sender, prev.Snapshot, current)
+	}
+
+	snapshots.records[sender] = &snapshotRecord{Snapshot: current, UpdatedAt: time.Now()}
+	snapshots.persist()
+	return current, warning
+}
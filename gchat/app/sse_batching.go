@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Micro-batching degli eventi SSE: lo streaming token-per-token produce
+// centinaia di piccole write e flush che bruciano CPU su gateway e client.
+// Con sse_batching.enabled gli eventi intermedi vengono accumulati e
+// scaricati al superamento di una soglia di tempo o di byte; final ed
+// error vengono sempre scaricati subito per non aggiungere latenza
+// percepita a fine risposta.
+
+const (
+	sseBatchDefaultDelayMs = 40   // latenza massima aggiunta a un token
+	sseBatchDefaultBytes   = 2048 // soglia di flush in byte
+)
+
+// SSEBatchingConfig configura il micro-batching in config.json (sse_batching)
+type SSEBatchingConfig struct {
+	Enabled    bool `json:"enabled"`
+	MaxDelayMs int  `json:"max_delay_ms"` // default 40
+	MaxBytes   int  `json:"max_bytes"`    // default 2048
+	// MaxEventBytes: dimensione massima di un singolo evento SSE dal
+	// backend, lato parser (default 1 MiB, vedi sse_parser.go)
+	MaxEventBytes int `json:"max_event_bytes"`
+}
+
+type sseBatcher struct {
+	w        io.Writer
+	flusher  http.Flusher
+	buf      bytes.Buffer
+	maxDelay time.Duration
+	maxBytes int
+	events   int
+	flushes  int
+}
+
+// newSSEBatcher crea il batcher, o nil se il batching è disabilitato
+func newSSEBatcher(w io.Writer, flusher http.Flusher, config *Config) *sseBatcher {
+	if !config.SSEBatching.Enabled {
+		return nil
+	}
+	maxDelay := time.Duration(config.SSEBatching.MaxDelayMs) * time.Millisecond
+	if maxDelay <= 0 {
+		maxDelay = sseBatchDefaultDelayMs * time.Millisecond
+	}
+	maxBytes := config.SSEBatching.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = sseBatchDefaultBytes
+	}
+	return &sseBatcher{w: w, flusher: flusher, maxDelay: maxDelay, maxBytes: maxBytes}
+}
+
+// MaxDelay è la finestra massima di accumulo prima del flush a tempo
+func (b *sseBatcher) MaxDelay() time.Duration {
+	return b.maxDelay
+}
+
+// Add accoda un evento già serializzato; final, error e superamento della
+// soglia byte forzano il flush immediato
+func (b *sseBatcher) Add(eventType string, eventJSON []byte) {
+	fmt.Fprintf(&b.buf, "event: %s\ndata: %s\n\n", eventType, eventJSON)
+	b.events++
+	if eventType == "final" || eventType == "error" || b.buf.Len() >= b.maxBytes {
+		b.Flush()
+	}
+}
+
+// Flush scarica il buffer accumulato verso il client
+func (b *sseBatcher) Flush() {
+	if b.buf.Len() == 0 {
+		return
+	}
+	if _, err := b.w.Write(b.buf.Bytes()); err != nil {
+		log.Printf("SSE_BATCH_ERROR: write fallita: %v", err)
+	}
+	b.flusher.Flush()
+	b.flushes++
+	b.buf.Reset()
+}
+
+// Close scarica il residuo e logga il rapporto eventi/flush
+func (b *sseBatcher) Close() {
+	b.Flush()
+	if b.events > 0 {
+		log.Printf("SSE_BATCH_STATS: events=%d, flushes=%d", b.events, b.flushes)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Canale SSE con buffer e timeout configurabili: eventChan aveva un buffer
+// fisso di 10 e l'invio bloccante, quindi un client lento (o che non legge
+// più senza chiudere la connessione) stallava per sempre la goroutine
+// produttrice — i goroutine leak osservati nelle sessioni lunghe. Qui
+// l'invio verso il canale ha un timeout per evento e una policy al
+// superamento: "drop_oldest" scarta l'evento più vecchio in coda (mai i
+// final/error, che trasportano la risposta) e conta gli scarti nelle
+// metriche streaming, "disconnect" abbandona lo stream del client lento.
+
+const (
+	sseChannelDefaultBuffer    = 10
+	sseChannelDefaultTimeoutMs = 5000
+	sseChannelPolicyDropOldest = "drop_oldest"
+	sseChannelPolicyDisconnect = "disconnect"
+)
+
+// SSEChannelConfig configura buffer e policy del canale (sezione sse_channel)
+type SSEChannelConfig struct {
+	// BufferSize: capacità del canale eventi (default 10)
+	BufferSize int `json:"buffer_size"`
+	// SendTimeoutMs: attesa massima per l'invio di un evento (default 5000)
+	SendTimeoutMs int `json:"send_timeout_ms"`
+	// DropPolicy: "drop_oldest" (default) o "disconnect"
+	DropPolicy string `json:"drop_policy"`
+}
+
+// sseChannelBuffer ritorna la capacità configurata del canale eventi
+func sseChannelBuffer(config *Config) int {
+	if config.SSEChannel.BufferSize > 0 {
+		return config.SSEChannel.BufferSize
+	}
+	return sseChannelDefaultBuffer
+}
+
+// sseSendTimeout ritorna il timeout per il singolo invio sul canale
+func sseSendTimeout(config *Config) time.Duration {
+	if config.SSEChannel.SendTimeoutMs > 0 {
+		return time.Duration(config.SSEChannel.SendTimeoutMs) * time.Millisecond
+	}
+	return sseChannelDefaultTimeoutMs * time.Millisecond
+}
+
+// sseDropPolicy ritorna la policy configurata (default drop_oldest)
+func sseDropPolicy(config *Config) string {
+	if config.SSEChannel.DropPolicy == sseChannelPolicyDisconnect {
+		return sseChannelPolicyDisconnect
+	}
+	return sseChannelPolicyDropOldest
+}
+
+// recordDroppedSSEEvent conta un evento scartato nelle metriche streaming
+func recordDroppedSSEEvent(eventType, sender string) {
+	streamStats.mu.Lock()
+	streamStats.DroppedEvents++
+	dropped := streamStats.DroppedEvents
+	streamStats.mu.Unlock()
+	log.Printf("SSE_CHANNEL_DROP: evento %s scartato per client lento - sender=%s, scarti_totali=%d",
+		eventType, sender, dropped)
+}
+
+// sseEventSend invia l'evento sul canale con timeout e policy configurati.
+// Ritorna false se lo stream va abbandonato (policy disconnect, o consumer
+// fermo a metà scrittura con buffer vuoto): il chiamante chiude il canale.
+func sseEventSend(config *Config, ch chan SSEEvent, event SSEEvent, sender string) bool {
+	timeout := sseSendTimeout(config)
+	maxDrops := sseChannelBuffer(config)
+
+	for drops := 0; ; drops++ {
+		select {
+		case ch <- event:
+			return true
+		case <-time.After(timeout):
+		}
+
+		if sseDropPolicy(config) == sseChannelPolicyDisconnect || drops >= maxDrops {
+			log.Printf("SSE_CHANNEL_STALL: invio evento %s oltre %v, stream abbandonato - sender=%s",
+				event.Type, timeout, sender)
+			return false
+		}
+
+		// drop_oldest: libera un posto scartando l'evento più vecchio in
+		// coda; final ed error non si perdono mai (trasportano la risposta),
+		// al loro posto viene scartato l'evento in ingresso
+		select {
+		case oldest := <-ch:
+			if oldest.Type == "final" || oldest.Type == "error" {
+				recordDroppedSSEEvent(event.Type, sender)
+				event = oldest
+			} else {
+				recordDroppedSSEEvent(oldest.Type, sender)
+			}
+		default:
+			// Buffer vuoto ma invio in timeout: il consumer è fermo a metà
+			// scrittura verso il client, insistere non aiuta
+			log.Printf("SSE_CHANNEL_STALL: consumer fermo con buffer vuoto, stream abbandonato - sender=%s", sender)
+			return false
+		}
+	}
+}
+
+// drainSSEChannel svuota il canale in background per sbloccare il
+// produttore quando lo stream del client è stato abbandonato
+func drainSSEChannel(ch <-chan SSEEvent) {
+	for range ch {
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Parser SSE spec-compliant per lo stream del backend: la scansione riga
+// per riga gestiva male i backend che inviano "data:" senza spazio,
+// terminatori CRLF o singoli eventi più grandi del buffer di default dello
+// Scanner. Questo parser segue la specifica EventSource (campo:valore con
+// al più uno spazio dopo i due punti, commenti, data multi-riga, campi
+// id/retry) con un limite configurabile sulla dimensione dell'evento
+// (sse_batching.max_event_bytes).
+
+// sseDefaultMaxEventBytes è il limite di default per singolo evento (1 MiB)
+const sseDefaultMaxEventBytes = 1 << 20
+
+// SSEParsedEvent è un evento completo letto dallo stream
+type SSEParsedEvent struct {
+	Type  string // campo "event" (vuoto = evento di default)
+	Data  string // righe "data" unite con \n
+	ID    string // campo "id" (ultimo visto nell'evento)
+	Retry int    // campo "retry" in millisecondi, -1 se assente
+}
+
+// SSEParser legge eventi SSE da uno stream
+type SSEParser struct {
+	reader       *bufio.Reader
+	maxEventSize int
+}
+
+// NewSSEParser costruisce un parser sul corpo della risposta streaming.
+// maxEventSize <= 0 applica il default di 1 MiB.
+func NewSSEParser(r io.Reader, maxEventSize int) *SSEParser {
+	if maxEventSize <= 0 {
+		maxEventSize = sseDefaultMaxEventBytes
+	}
+	return &SSEParser{
+		reader:       bufio.NewReader(r),
+		maxEventSize: maxEventSize,
+	}
+}
+
+// readLine ritorna la prossima riga senza terminatore (LF o CRLF)
+func (p *SSEParser) readLine() (string, error) {
+	line, err := p.reader.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			// Ultima riga senza newline finale: trattata come riga intera
+			return strings.TrimSuffix(line, "\r"), nil
+		}
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return line, nil
+}
+
+// Next ritorna il prossimo evento con almeno una riga data, io.EOF a fine
+// stream, errore se un evento eccede la dimensione massima.
+func (p *SSEParser) Next() (*SSEParsedEvent, error) {
+	event := &SSEParsedEvent{Retry: -1}
+	var dataLines []string
+	dataSize := 0
+
+	for {
+		line, err := p.readLine()
+		if err != nil {
+			if err == io.EOF && len(dataLines) > 0 {
+				// Stream troncato senza riga vuota finale: consegna
+				// comunque l'evento accumulato
+				event.Data = strings.Join(dataLines, "\n")
+				return event, nil
+			}
+			return nil, err
+		}
+
+		// Riga vuota: dispatch dell'evento se ha dati, altrimenti reset
+		if line == "" {
+			if len(dataLines) == 0 {
+				event = &SSEParsedEvent{Retry: -1}
+				continue
+			}
+			event.Data = strings.Join(dataLines, "\n")
+			return event, nil
+		}
+
+		// Commento (riga che inizia con ":")
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		// campo:valore — al più un singolo spazio dopo i due punti viene
+		// rimosso; una riga senza ":" è un campo con valore vuoto
+		field, value := line, ""
+		if idx := strings.Index(line, ":"); idx >= 0 {
+			field = line[:idx]
+			value = strings.TrimPrefix(line[idx+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			event.Type = value
+		case "data":
+			dataSize += len(value) + 1
+			if dataSize > p.maxEventSize {
+				return nil, fmt.Errorf("SSE event oltre il limite di %d byte", p.maxEventSize)
+			}
+			dataLines = append(dataLines, value)
+		case "id":
+			// La specifica ignora gli id contenenti NUL
+			if !strings.Contains(value, "\x00") {
+				event.ID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				event.Retry = ms
+			}
+			// Campi sconosciuti: ignorati come da specifica
+		}
+	}
+}
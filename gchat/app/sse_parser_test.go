@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func parseAll(t *testing.T, stream string, maxEventSize int) []*SSEParsedEvent {
+	t.Helper()
+	parser := NewSSEParser(strings.NewReader(stream), maxEventSize)
+	var events []*SSEParsedEvent
+	for {
+		event, err := parser.Next()
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Fatalf("errore inatteso dal parser: %v", err)
+		}
+		events = append(events, event)
+	}
+}
+
+func TestSSEParserDataWithoutSpace(t *testing.T) {
+	events := parseAll(t, "event:final\ndata:{\"a\":1}\n\n", 0)
+	if len(events) != 1 {
+		t.Fatalf("attesi 1 evento, ottenuti %d", len(events))
+	}
+	if events[0].Type != "final" || events[0].Data != `{"a":1}` {
+		t.Errorf("evento senza spazio dopo i due punti parsato male: %+v", events[0])
+	}
+}
+
+func TestSSEParserCRLF(t *testing.T) {
+	events := parseAll(t, "event: token\r\ndata: ciao\r\n\r\n", 0)
+	if len(events) != 1 {
+		t.Fatalf("attesi 1 evento, ottenuti %d", len(events))
+	}
+	if events[0].Type != "token" || events[0].Data != "ciao" {
+		t.Errorf("terminatori CRLF parsati male: %+v", events[0])
+	}
+}
+
+func TestSSEParserMultilineData(t *testing.T) {
+	events := parseAll(t, "data: riga1\ndata: riga2\ndata: riga3\n\n", 0)
+	if len(events) != 1 {
+		t.Fatalf("attesi 1 evento, ottenuti %d", len(events))
+	}
+	if events[0].Data != "riga1\nriga2\nriga3" {
+		t.Errorf("data multi-riga non unite con \\n: %q", events[0].Data)
+	}
+}
+
+func TestSSEParserCommentsAndUnknownFieldsIgnored(t *testing.T) {
+	stream := ": keep-alive\nignoreme: x\nsenzaduepunti\ndata: ok\n\n"
+	events := parseAll(t, stream, 0)
+	if len(events) != 1 || events[0].Data != "ok" {
+		t.Fatalf("commenti/campi sconosciuti non ignorati: %+v", events)
+	}
+}
+
+func TestSSEParserIDAndRetry(t *testing.T) {
+	events := parseAll(t, "id: 42\nretry: 3000\ndata: x\n\n", 0)
+	if len(events) != 1 {
+		t.Fatalf("attesi 1 evento, ottenuti %d", len(events))
+	}
+	if events[0].ID != "42" {
+		t.Errorf("id non parsato: %q", events[0].ID)
+	}
+	if events[0].Retry != 3000 {
+		t.Errorf("retry non parsato: %d", events[0].Retry)
+	}
+}
+
+func TestSSEParserRetryAbsent(t *testing.T) {
+	events := parseAll(t, "data: x\n\n", 0)
+	if events[0].Retry != -1 {
+		t.Errorf("retry assente deve valere -1, ottenuto %d", events[0].Retry)
+	}
+}
+
+func TestSSEParserEventWithoutDataNotDispatched(t *testing.T) {
+	events := parseAll(t, "event: ping\n\ndata: reale\n\n", 0)
+	if len(events) != 1 {
+		t.Fatalf("attesi 1 evento, ottenuti %d", len(events))
+	}
+	if events[0].Type != "" || events[0].Data != "reale" {
+		t.Errorf("evento senza data non scartato correttamente: %+v", events[0])
+	}
+}
+
+func TestSSEParserTruncatedStreamDeliversPending(t *testing.T) {
+	// Stream interrotto senza riga vuota finale né newline
+	events := parseAll(t, "event: final\ndata: parziale", 0)
+	if len(events) != 1 {
+		t.Fatalf("attesi 1 evento dallo stream troncato, ottenuti %d", len(events))
+	}
+	if events[0].Type != "final" || events[0].Data != "parziale" {
+		t.Errorf("flush a EOF errato: %+v", events[0])
+	}
+}
+
+func TestSSEParserLargeEventWithinLimit(t *testing.T) {
+	// Un evento oltre i 64 KiB del buffer di default di bufio.Scanner
+	big := strings.Repeat("x", 256*1024)
+	events := parseAll(t, "data: "+big+"\n\n", 0)
+	if len(events) != 1 || len(events[0].Data) != len(big) {
+		t.Fatalf("evento grande entro il limite non parsato")
+	}
+}
+
+func TestSSEParserMaxEventSizeExceeded(t *testing.T) {
+	parser := NewSSEParser(strings.NewReader("data: "+strings.Repeat("x", 2048)+"\n\n"), 1024)
+	if _, err := parser.Next(); err == nil {
+		t.Fatal("atteso errore per evento oltre il limite configurato")
+	}
+}
+
+func TestSSEParserMultipleEvents(t *testing.T) {
+	stream := "event: token\ndata: a\n\nevent: token\ndata: b\n\nevent: final\ndata: fine\n\n"
+	events := parseAll(t, stream, 0)
+	if len(events) != 3 {
+		t.Fatalf("attesi 3 eventi, ottenuti %d", len(events))
+	}
+	if events[2].Type != "final" || events[2].Data != "fine" {
+		t.Errorf("sequenza di eventi parsata male: %+v", events[2])
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metriche di qualità dello streaming SSE: time-to-first-event, gap tra
+// eventi, eventi persi per client lenti, stream interrotti da client vs
+// server. Servono a diagnosticare le segnalazioni "la risposta si blocca
+// a metà".
+
+type streamQualityStats struct {
+	TotalStreams       int64   `json:"total_streams"`
+	CompletedStreams   int64   `json:"completed_streams"`
+	AbortedByClient    int64   `json:"aborted_by_client"`
+	AbortedByServer    int64   `json:"aborted_by_server"`
+	DroppedEvents      int64   `json:"dropped_events"`
+	TotalEvents        int64   `json:"total_events"`
+	TTFESumMs          float64 `json:"ttfe_sum_ms"`
+	TTFEMaxMs          float64 `json:"ttfe_max_ms"`
+	TTFECount          int64   `json:"ttfe_count"`
+	InterEventGapMaxMs float64 `json:"inter_event_gap_max_ms"`
+	InterEventGapSumMs float64 `json:"inter_event_gap_sum_ms"`
+	InterEventGapCount int64   `json:"inter_event_gap_count"`
+	mu                 sync.Mutex
+}
+
+var streamStats = &streamQualityStats{}
+
+// streamRecorder misura la qualità di un singolo stream SSE
+type streamRecorder struct {
+	start      time.Time
+	firstEvent time.Time
+	lastEvent  time.Time
+	events     int64
+}
+
+func newStreamRecorder() *streamRecorder {
+	streamStats.mu.Lock()
+	streamStats.TotalStreams++
+	streamStats.mu.Unlock()
+	return &streamRecorder{start: time.Now()}
+}
+
+// RecordEvent registra la trasmissione di un evento al client
+func (r *streamRecorder) RecordEvent() {
+	now := time.Now()
+	streamStats.mu.Lock()
+	defer streamStats.mu.Unlock()
+
+	streamStats.TotalEvents++
+	if r.firstEvent.IsZero() {
+		r.firstEvent = now
+		ttfe := float64(now.Sub(r.start).Milliseconds())
+		streamStats.TTFESumMs += ttfe
+		streamStats.TTFECount++
+		if ttfe > streamStats.TTFEMaxMs {
+			streamStats.TTFEMaxMs = ttfe
+		}
+	} else {
+		gap := float64(now.Sub(r.lastEvent).Milliseconds())
+		streamStats.InterEventGapSumMs += gap
+		streamStats.InterEventGapCount++
+		if gap > streamStats.InterEventGapMaxMs {
+			streamStats.InterEventGapMaxMs = gap
+		}
+	}
+	r.lastEvent = now
+	r.events++
+}
+
+// RecordDropped registra un evento perso (client troppo lento)
+func (r *streamRecorder) RecordDropped() {
+	streamStats.mu.Lock()
+	streamStats.DroppedEvents++
+	streamStats.mu.Unlock()
+}
+
+// Finish chiude la misurazione dello stream classificandone l'esito.
+// clientGone: connessione chiusa dal client prima della fine dello stream.
+// serverError: stream terminato per errore lato server/backend.
+func (r *streamRecorder) Finish(clientGone, serverError bool) {
+	streamStats.mu.Lock()
+	defer streamStats.mu.Unlock()
+
+	switch {
+	case clientGone:
+		streamStats.AbortedByClient++
+	case serverError:
+		streamStats.AbortedByServer++
+	default:
+		streamStats.CompletedStreams++
+	}
+
+	log.Printf("STREAM_METRICS: events=%d, duration=%v, client_gone=%v, server_error=%v",
+		r.events, time.Since(r.start), clientGone, serverError)
+}
+
+// HandleStreamingAnalytics espone le metriche aggregate di qualità streaming
+// GET /api/analytics/streaming
+func HandleStreamingAnalytics(c *gin.Context) {
+	streamStats.mu.Lock()
+	defer streamStats.mu.Unlock()
+
+	avgTTFE := 0.0
+	if streamStats.TTFECount > 0 {
+		avgTTFE = streamStats.TTFESumMs / float64(streamStats.TTFECount)
+	}
+	avgGap := 0.0
+	if streamStats.InterEventGapCount > 0 {
+		avgGap = streamStats.InterEventGapSumMs / float64(streamStats.InterEventGapCount)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":                 "success",
+		"total_streams":          streamStats.TotalStreams,
+		"completed_streams":      streamStats.CompletedStreams,
+		"aborted_by_client":      streamStats.AbortedByClient,
+		"aborted_by_server":      streamStats.AbortedByServer,
+		"dropped_events":         streamStats.DroppedEvents,
+		"total_events":           streamStats.TotalEvents,
+		"avg_ttfe_ms":            avgTTFE,
+		"max_ttfe_ms":            streamStats.TTFEMaxMs,
+		"avg_inter_event_gap_ms": avgGap,
+		"max_inter_event_gap_ms": streamStats.InterEventGapMaxMs,
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Formati sottotitoli per le trascrizioni: i team formazione registrano i
+// walkthrough delle procedure e vogliono i sottotitoli dei video. Dai
+// segmenti con timestamp riportati dal server whisper (response_format
+// verbose_json) vengono prodotti file WebVTT o SRT, in aggiunta alla
+// risposta plain-text.
+
+// WhisperSegment è un segmento temporizzato della trascrizione
+type WhisperSegment struct {
+	Start float64 `json:"start"` // secondi
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// subtitleTimestamp formatta i secondi in HH:MM:SS<sep>mmm
+// (sep "." per WebVTT, "," per SRT)
+func subtitleTimestamp(seconds float64, sep string) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	total := int(seconds)
+	millis := int((seconds - float64(total)) * 1000)
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", total/3600, (total%3600)/60, total%60, sep, millis)
+}
+
+// FormatVTT produce un documento WebVTT dai segmenti
+func FormatVTT(segments []WhisperSegment) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("%s --> %s\n%s\n\n",
+			subtitleTimestamp(seg.Start, "."), subtitleTimestamp(seg.End, "."), text))
+	}
+	return b.String()
+}
+
+// FormatSRT produce un documento SubRip dai segmenti
+func FormatSRT(segments []WhisperSegment) string {
+	var b strings.Builder
+	index := 1
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("%d\n%s --> %s\n%s\n\n",
+			index, subtitleTimestamp(seg.Start, ","), subtitleTimestamp(seg.End, ","), text))
+		index++
+	}
+	return b.String()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Dedup e ranking dei suggerimenti: il backend a volte concatena le
+// suggestions di più risposte e ripete le stesse voci. Il gateway
+// deduplica sul testo normalizzato, ordina per click-through (lo store
+// traccia mostrati e cliccati, alimentato da POST /api/suggestions/click)
+// e taglia al numero massimo configurato prima di restituirle in
+// ChatResponse e nell'evento final dello streaming.
+
+const (
+	suggestionClicksFile       = "data/suggestion_clicks.json"
+	suggestionsDefaultMaxCount = 5
+)
+
+// SuggestionsConfig configura il ranking (sezione suggestions)
+type SuggestionsConfig struct {
+	// MaxCount: numero massimo di suggerimenti restituiti (default 5)
+	MaxCount int `json:"max_count"`
+}
+
+// suggestionStat traccia mostrati e cliccati per testo normalizzato
+type suggestionStat struct {
+	Text    string `json:"text"`
+	Shown   int64  `json:"shown"`
+	Clicked int64  `json:"clicked"`
+}
+
+type suggestionStore struct {
+	stats  map[string]*suggestionStat // chiave: testo normalizzato
+	mu     sync.Mutex
+	loaded bool
+}
+
+var suggestionTracking = &suggestionStore{stats: map[string]*suggestionStat{}}
+
+func (s *suggestionStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(suggestionClicksFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("SUGGEST_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	var list []*suggestionStat
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("SUGGEST_LOAD_ERROR: parse failed: %v", err)
+		return
+	}
+	for _, st := range list {
+		s.stats[normalizeFAQText(st.Text)] = st
+	}
+}
+
+// persist salva su file (chiamare con lock acquisito)
+func (s *suggestionStore) persist() {
+	list := make([]*suggestionStat, 0, len(s.stats))
+	for _, st := range s.stats {
+		list = append(list, st)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		log.Printf("SUGGEST_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.WriteFile(suggestionClicksFile, data, 0644); err != nil {
+		log.Printf("SUGGEST_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// suggestionCTR calcola il click-through (0 se mai mostrato)
+func suggestionCTR(st *suggestionStat) float64 {
+	if st == nil || st.Shown == 0 {
+		return 0
+	}
+	return float64(st.Clicked) / float64(st.Shown)
+}
+
+// RankSuggestions deduplica sul testo normalizzato, ordina per
+// click-through decrescente (ordine originale a parità) e taglia al
+// massimo configurato. Registra anche l'impression di ogni voce servita.
+func RankSuggestions(config *Config, suggestions []map[string]interface{}) []map[string]interface{} {
+	if len(suggestions) == 0 {
+		return suggestions
+	}
+	maxCount := config.Suggestions.MaxCount
+	if maxCount <= 0 {
+		maxCount = suggestionsDefaultMaxCount
+	}
+
+	suggestionTracking.mu.Lock()
+	defer suggestionTracking.mu.Unlock()
+	suggestionTracking.ensureLoaded()
+
+	type ranked struct {
+		item  map[string]interface{}
+		ctr   float64
+		order int
+	}
+	seen := map[string]bool{}
+	var list []ranked
+	for i, item := range suggestions {
+		text, _ := item["text"].(string)
+		norm := normalizeFAQText(text)
+		if norm == "" || seen[norm] {
+			continue
+		}
+		seen[norm] = true
+		list = append(list, ranked{item: item, ctr: suggestionCTR(suggestionTracking.stats[norm]), order: i})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].ctr != list[j].ctr {
+			return list[i].ctr > list[j].ctr
+		}
+		return list[i].order < list[j].order
+	})
+	if len(list) > maxCount {
+		list = list[:maxCount]
+	}
+
+	result := make([]map[string]interface{}, 0, len(list))
+	changed := false
+	for _, r := range list {
+		result = append(result, r.item)
+		text, _ := r.item["text"].(string)
+		norm := normalizeFAQText(text)
+		st, ok := suggestionTracking.stats[norm]
+		if !ok {
+			st = &suggestionStat{Text: text}
+			suggestionTracking.stats[norm] = st
+		}
+		st.Shown++
+		changed = true
+	}
+	if changed {
+		suggestionTracking.persist()
+	}
+	if len(result) != len(suggestions) {
+		log.Printf("SUGGEST_RANKED: %d -> %d suggerimenti (dedup+cap)", len(suggestions), len(result))
+	}
+	return result
+}
+
+// HandleSuggestionClick registra il click su un suggerimento
+// POST /api/suggestions/click {"text": "..."}
+func HandleSuggestionClick(c *gin.Context) {
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Text == "" {
+		AbortWithError(c, ErrInvalidRequest, "campo text mancante")
+		return
+	}
+
+	suggestionTracking.mu.Lock()
+	defer suggestionTracking.mu.Unlock()
+	suggestionTracking.ensureLoaded()
+
+	norm := normalizeFAQText(req.Text)
+	st, ok := suggestionTracking.stats[norm]
+	if !ok {
+		st = &suggestionStat{Text: req.Text}
+		suggestionTracking.stats[norm] = st
+	}
+	st.Clicked++
+	suggestionTracking.persist()
+
+	c.JSON(http.StatusOK, gin.H{"status": "success", "clicked": st.Clicked, "shown": st.Shown})
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Modalità supervisore per il coaching qualità: i responsabili UOC possono
+// vedere le conversazioni streaming in corso (anteprima anonimizzata) e
+// agganciarsi in sola lettura allo stream di un utente. La supervisione è
+// trasparente: gli eventi inoltrati all'utente supervisionato vengono
+// marcati con supervised=true così la UI mostra il banner di consenso, e il
+// supervisore riceve a sua volta un banner di avviso all'aggancio.
+
+// liveStream rappresenta una conversazione streaming attualmente attiva
+type liveStream struct {
+	Sender        string
+	StartedAt     time.Time
+	LastEventType string
+	Preview       string
+	watchers      map[chan SSEEvent]struct{}
+}
+
+type liveRegistry struct {
+	streams map[string]*liveStream
+	mu      sync.Mutex
+}
+
+var supervisorRegistry = &liveRegistry{
+	streams: make(map[string]*liveStream),
+}
+
+// Pattern per anonimizzare l'anteprima: codici fiscali e sequenze numeriche
+// lunghe (user_id, telefoni) non devono comparire nella lista supervisore
+var (
+	cfPattern     = regexp.MustCompile(`[A-Za-z]{6}\d{2}[A-Za-z]\d{2}[A-Za-z]\d{3}[A-Za-z]`)
+	digitsPattern = regexp.MustCompile(`\d{5,}`)
+)
+
+const previewMaxLen = 120
+
+// anonymizePreview maschera dati personali e tronca il testo di anteprima
+func anonymizePreview(text string) string {
+	masked := cfPattern.ReplaceAllString(text, "***CF***")
+	masked = digitsPattern.ReplaceAllString(masked, "***")
+	runes := []rune(masked)
+	if len(runes) > previewMaxLen {
+		return string(runes[:previewMaxLen]) + "…"
+	}
+	return masked
+}
+
+// SupervisorStreamStarted registra l'inizio di uno stream attivo
+func SupervisorStreamStarted(sender string) {
+	supervisorRegistry.mu.Lock()
+	defer supervisorRegistry.mu.Unlock()
+
+	supervisorRegistry.streams[sender] = &liveStream{
+		Sender:    sender,
+		StartedAt: time.Now(),
+		watchers:  make(map[chan SSEEvent]struct{}),
+	}
+}
+
+// SupervisorStreamEnded rimuove lo stream e scollega gli eventuali supervisori
+func SupervisorStreamEnded(sender string) {
+	supervisorRegistry.mu.Lock()
+	defer supervisorRegistry.mu.Unlock()
+
+	if ls, ok := supervisorRegistry.streams[sender]; ok {
+		for ch := range ls.watchers {
+			close(ch)
+		}
+		delete(supervisorRegistry.streams, sender)
+	}
+}
+
+// SupervisorObserveEvent aggiorna l'anteprima e inoltra l'evento ai
+// supervisori agganciati. Se lo stream è supervisionato marca l'evento con
+// supervised=true (banner di consenso lato utente). Fan-out non bloccante:
+// un supervisore lento non deve rallentare la conversazione.
+func SupervisorObserveEvent(sender string, event *SSEEvent) {
+	supervisorRegistry.mu.Lock()
+	defer supervisorRegistry.mu.Unlock()
+
+	ls, ok := supervisorRegistry.streams[sender]
+	if !ok {
+		return
+	}
+
+	ls.LastEventType = event.Type
+	if event.Content != "" {
+		ls.Preview = anonymizePreview(event.Content)
+	}
+
+	if len(ls.watchers) == 0 {
+		return
+	}
+
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]interface{})
+	}
+	event.Metadata["supervised"] = true
+
+	for ch := range ls.watchers {
+		select {
+		case ch <- *event:
+		default:
+			// supervisore troppo lento: l'evento per lui va perso
+		}
+	}
+}
+
+// HandleActiveConversations lista le conversazioni streaming in corso
+// GET /admin/api/conversations/active
+func HandleActiveConversations(c *gin.Context) {
+	supervisorRegistry.mu.Lock()
+	defer supervisorRegistry.mu.Unlock()
+
+	type activeInfo struct {
+		Sender        string `json:"sender"`
+		StartedAt     int64  `json:"started_at"`
+		DurationSecs  int64  `json:"duration_seconds"`
+		LastEventType string `json:"last_event_type"`
+		Preview       string `json:"preview"`
+		Watchers      int    `json:"watchers"`
+	}
+
+	active := make([]activeInfo, 0, len(supervisorRegistry.streams))
+	for _, ls := range supervisorRegistry.streams {
+		active = append(active, activeInfo{
+			Sender:        ls.Sender,
+			StartedAt:     ls.StartedAt.Unix(),
+			DurationSecs:  int64(time.Since(ls.StartedAt).Seconds()),
+			LastEventType: ls.LastEventType,
+			Preview:       ls.Preview,
+			Watchers:      len(ls.watchers),
+		})
+	}
+
+	log.Printf("SUPERVISOR_ACTIVE_LIST: client_ip=%s, active=%d", c.ClientIP(), len(active))
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":        "success",
+		"active":        active,
+		"total_streams": len(active),
+	})
+}
+
+// HandleWatchConversation aggancia un mirror SSE in sola lettura allo
+// stream di un utente, per il coaching qualità dei supervisori
+// GET /admin/api/conversations/:sender/watch
+func HandleWatchConversation(c *gin.Context) {
+	sender := c.Param("sender")
+	clientIP := c.ClientIP()
+
+	supervisorRegistry.mu.Lock()
+	ls, ok := supervisorRegistry.streams[sender]
+	if !ok {
+		supervisorRegistry.mu.Unlock()
+		AbortWithError(c, ErrNotFound, "nessuna conversazione streaming attiva per questo sender")
+		return
+	}
+	mirror := make(chan SSEEvent, 32)
+	ls.watchers[mirror] = struct{}{}
+	supervisorRegistry.mu.Unlock()
+
+	defer func() {
+		supervisorRegistry.mu.Lock()
+		if cur, still := supervisorRegistry.streams[sender]; still {
+			delete(cur.watchers, mirror)
+		}
+		supervisorRegistry.mu.Unlock()
+	}()
+
+	log.Printf("SUPERVISOR_WATCH_ATTACHED: client_ip=%s, sender=%s", clientIP, sender)
+
+	flusher, supported := c.Writer.(http.Flusher)
+	if !supported {
+		AbortWithError(c, ErrStreamingUnsupported, "")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Banner di consenso esplicito: il supervisore vede le regole di
+	// utilizzo prima di qualsiasi evento della conversazione
+	banner := SSEEvent{
+		Type:      "consent_banner",
+		Content:   "Supervisione attiva in sola lettura ai fini del coaching qualità. L'utente vede un avviso di supervisione. Non divulgare i contenuti della conversazione.",
+		Timestamp: time.Now().UnixMilli(),
+		Metadata:  map[string]interface{}{"sender": sender},
+	}
+	if bannerJSON, err := json.Marshal(banner); err == nil {
+		fmt.Fprintf(c.Writer, "event: consent_banner\ndata: %s\n\n", string(bannerJSON))
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case event, open := <-mirror:
+			if !open {
+				log.Printf("SUPERVISOR_WATCH_ENDED: stream terminato - sender=%s", sender)
+				return
+			}
+			eventJSON, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, string(eventJSON))
+			flusher.Flush()
+		case <-c.Request.Context().Done():
+			log.Printf("SUPERVISOR_WATCH_DETACHED: client_ip=%s, sender=%s", clientIP, sender)
+			return
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tagging e bookmark delle conversazioni: permette agli utenti di etichettare
+// conversazioni utili (es. "procedura campionamento") e ritrovarle in seguito.
+// I tag sono salvati lato gateway accanto alla cronologia (che resta nel
+// backend), con controllo di proprietà per utente.
+
+const tagsFile = "data/conversation_tags.json"
+
+type ConversationMeta struct {
+	SessionID  string   `json:"session_id"`
+	Owner      string   `json:"owner"` // sender proprietario della conversazione
+	Tags       []string `json:"tags"`
+	Bookmarked bool     `json:"bookmarked"`
+	UpdatedAt  int64    `json:"updated_at"`
+}
+
+type tagStore struct {
+	conversations map[string]*ConversationMeta // chiave: session_id
+	mu            sync.Mutex
+	loaded        bool
+}
+
+var convTags = &tagStore{
+	conversations: make(map[string]*ConversationMeta),
+}
+
+func (s *tagStore) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+
+	data, err := os.ReadFile(tagsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("TAGS_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	var list []*ConversationMeta
+	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("TAGS_LOAD_ERROR: parse failed: %v", err)
+		return
+	}
+	for _, m := range list {
+		s.conversations[m.SessionID] = m
+	}
+	log.Printf("TAGS_LOADED: %d conversazioni con metadati caricate", len(list))
+}
+
+// persist salva su file (chiamare con lock acquisito)
+func (s *tagStore) persist() {
+	list := make([]*ConversationMeta, 0, len(s.conversations))
+	for _, m := range s.conversations {
+		list = append(list, m)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		log.Printf("TAGS_PERSIST_ERROR: marshal failed: %v", err)
+		return
+	}
+	if err := os.MkdirAll("data", 0755); err != nil {
+		log.Printf("TAGS_PERSIST_ERROR: cannot create data directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(tagsFile, data, 0644); err != nil {
+		log.Printf("TAGS_PERSIST_ERROR: write failed: %v", err)
+	}
+}
+
+// getOrCreate recupera i metadati della conversazione verificando la proprietà.
+// Ritorna nil se la conversazione appartiene a un altro utente.
+func (s *tagStore) getOrCreate(sessionID, owner string) *ConversationMeta {
+	meta, ok := s.conversations[sessionID]
+	if !ok {
+		meta = &ConversationMeta{SessionID: sessionID, Owner: owner}
+		s.conversations[sessionID] = meta
+		return meta
+	}
+	if meta.Owner != owner {
+		return nil
+	}
+	return meta
+}
+
+type tagRequest struct {
+	SessionID  string `json:"session_id"`
+	Tag        string `json:"tag,omitempty"`
+	Bookmarked *bool  `json:"bookmarked,omitempty"`
+}
+
+// HandleAddConversationTag aggiunge un tag a una conversazione dell'utente
+// POST /api/conversations/:sender/tags
+func HandleAddConversationTag(c *gin.Context) {
+	sender := c.Param("sender")
+
+	var req tagRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.SessionID == "" || req.Tag == "" {
+		AbortWithError(c, ErrInvalidRequest, "session_id e tag sono obbligatori")
+		return
+	}
+
+	convTags.mu.Lock()
+	defer convTags.mu.Unlock()
+	convTags.ensureLoaded()
+
+	meta := convTags.getOrCreate(req.SessionID, sender)
+	if meta == nil {
+		AbortWithError(c, ErrForbidden, "la conversazione appartiene a un altro utente")
+		return
+	}
+
+	// Evita duplicati
+	for _, t := range meta.Tags {
+		if t == req.Tag {
+			c.JSON(http.StatusOK, meta)
+			return
+		}
+	}
+	meta.Tags = append(meta.Tags, req.Tag)
+	meta.UpdatedAt = time.Now().Unix()
+	convTags.persist()
+
+	log.Printf("TAGS_ADDED: sender=%s, session_id=%s, tag=%s", sender, req.SessionID, req.Tag)
+	c.JSON(http.StatusOK, meta)
+}
+
+// HandleRemoveConversationTag rimuove un tag da una conversazione dell'utente
+// DELETE /api/conversations/:sender/tags?session_id=&tag=
+func HandleRemoveConversationTag(c *gin.Context) {
+	sender := c.Param("sender")
+	sessionID := c.Query("session_id")
+	tag := c.Query("tag")
+	if sessionID == "" || tag == "" {
+		AbortWithError(c, ErrInvalidParameter, "session_id e tag sono obbligatori")
+		return
+	}
+
+	convTags.mu.Lock()
+	defer convTags.mu.Unlock()
+	convTags.ensureLoaded()
+
+	meta, ok := convTags.conversations[sessionID]
+	if !ok {
+		AbortWithError(c, ErrNotFound, "conversazione non trovata")
+		return
+	}
+	if meta.Owner != sender {
+		AbortWithError(c, ErrForbidden, "la conversazione appartiene a un altro utente")
+		return
+	}
+
+	filtered := meta.Tags[:0]
+	for _, t := range meta.Tags {
+		if t != tag {
+			filtered = append(filtered, t)
+		}
+	}
+	meta.Tags = filtered
+	meta.UpdatedAt = time.Now().Unix()
+	convTags.persist()
+
+	log.Printf("TAGS_REMOVED: sender=%s, session_id=%s, tag=%s", sender, sessionID, tag)
+	c.JSON(http.StatusOK, meta)
+}
+
+// HandleBookmarkConversation imposta/rimuove il bookmark su una conversazione
+// POST /api/conversations/:sender/bookmark
+func HandleBookmarkConversation(c *gin.Context) {
+	sender := c.Param("sender")
+
+	var req tagRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.SessionID == "" || req.Bookmarked == nil {
+		AbortWithError(c, ErrInvalidRequest, "session_id e bookmarked sono obbligatori")
+		return
+	}
+
+	convTags.mu.Lock()
+	defer convTags.mu.Unlock()
+	convTags.ensureLoaded()
+
+	meta := convTags.getOrCreate(req.SessionID, sender)
+	if meta == nil {
+		AbortWithError(c, ErrForbidden, "la conversazione appartiene a un altro utente")
+		return
+	}
+
+	meta.Bookmarked = *req.Bookmarked
+	meta.UpdatedAt = time.Now().Unix()
+	convTags.persist()
+
+	log.Printf("TAGS_BOOKMARK: sender=%s, session_id=%s, bookmarked=%v", sender, req.SessionID, meta.Bookmarked)
+	c.JSON(http.StatusOK, meta)
+}
+
+// HandleListConversationsByTag elenca le conversazioni dell'utente filtrate per tag/bookmark
+// GET /api/conversations?sender=&tag=&bookmarked=true
+func HandleListConversationsByTag(c *gin.Context) {
+	sender := c.Query("sender")
+	if sender == "" {
+		AbortWithError(c, ErrInvalidParameter, "parametro sender obbligatorio")
+		return
+	}
+	tag := c.Query("tag")
+	onlyBookmarked := c.Query("bookmarked") == "true"
+
+	convTags.mu.Lock()
+	convTags.ensureLoaded()
+	var result []*ConversationMeta
+	for _, meta := range convTags.conversations {
+		if meta.Owner != sender {
+			continue // controllo di proprietà: solo le proprie conversazioni
+		}
+		if onlyBookmarked && !meta.Bookmarked {
+			continue
+		}
+		if tag != "" {
+			found := false
+			for _, t := range meta.Tags {
+				if t == tag {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+		result = append(result, meta)
+	}
+	convTags.mu.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UpdatedAt > result[j].UpdatedAt
+	})
+
+	log.Printf("TAGS_LIST: sender=%s, tag=%s, bookmarked=%v, count=%d", sender, tag, onlyBookmarked, len(result))
+	c.JSON(http.StatusOK, gin.H{
+		"conversations": result,
+		"status":        "success",
+	})
+}
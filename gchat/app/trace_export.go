@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Export dei trace di esecuzione in formato OpenTelemetry: il backend
+// riporta execution_path/node_timings in un formato proprio, utile nel
+// debug panel ma invisibile agli strumenti di tracing. Il gateway converte
+// ogni turno in un trace OTLP/JSON (span radice gateway + uno span per
+// nodo LangGraph) consultabile su /api/debug/trace/<codice>-<turno>, così
+// da poterlo importare in Jaeger accanto agli span del gateway.
+
+// traceKeepMax limita i trace tenuti in memoria (solo i turni più recenti)
+const traceKeepMax = 100
+
+type traceStore struct {
+	traces map[string]gin.H
+	order  []string
+	mu     sync.Mutex
+}
+
+var turnTraces = &traceStore{traces: map[string]gin.H{}}
+
+// traceHexID genera un identificatore esadecimale casuale (16 byte per il
+// trace id, 8 per gli span id, come da specifica OTLP)
+func traceHexID(bytes int) string {
+	buf := make([]byte, bytes)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// otelSpan costruisce un singolo span in formato OTLP/JSON
+func otelSpan(traceID, spanID, parentID, name string, start, end time.Time, attrs map[string]string) gin.H {
+	attributes := make([]gin.H, 0, len(attrs))
+	for k, v := range attrs {
+		attributes = append(attributes, gin.H{
+			"key":   k,
+			"value": gin.H{"stringValue": v},
+		})
+	}
+	span := gin.H{
+		"traceId":           traceID,
+		"spanId":            spanID,
+		"name":              name,
+		"kind":              "SPAN_KIND_INTERNAL",
+		"startTimeUnixNano": fmt.Sprintf("%d", start.UnixNano()),
+		"endTimeUnixNano":   fmt.Sprintf("%d", end.UnixNano()),
+		"attributes":        attributes,
+	}
+	if parentID != "" {
+		span["parentSpanId"] = parentID
+	}
+	return span
+}
+
+// TraceRecordTurn converte execution_path/node_timings del turno in un
+// trace OTLP/JSON e lo archivia sotto la chiave <codice>-<turno>. No-op
+// se il backend non ha riportato il tracking di esecuzione.
+func TraceRecordTurn(code string, turn int, sender, intent string, start time.Time, totalDuration time.Duration, exec *ExecutionInfoV1) {
+	if code == "" || exec == nil || len(exec.ExecutionPath) == 0 {
+		return
+	}
+
+	traceID := traceHexID(16)
+	rootID := traceHexID(8)
+	end := start.Add(totalDuration)
+
+	spans := []gin.H{
+		otelSpan(traceID, rootID, "", "gateway.chat_turn", start, end, map[string]string{
+			"gias.sender": sender,
+			"gias.intent": intent,
+			"gias.turn":   fmt.Sprintf("%s-%d", code, turn),
+		}),
+	}
+
+	// I nodi LangGraph diventano span figli sequenziali: il backend
+	// riporta solo le durate, quindi gli offset sono ricostruiti
+	// accodando i nodi nell'ordine del percorso di esecuzione
+	cursor := start
+	for _, node := range exec.ExecutionPath {
+		nodeDur := time.Duration(exec.NodeTimings[node] * float64(time.Millisecond))
+		nodeEnd := cursor.Add(nodeDur)
+		spans = append(spans, otelSpan(traceID, traceHexID(8), rootID, "langgraph."+node, cursor, nodeEnd, map[string]string{
+			"langgraph.node": node,
+		}))
+		cursor = nodeEnd
+	}
+
+	trace := gin.H{
+		"resourceSpans": []gin.H{
+			{
+				"resource": gin.H{
+					"attributes": []gin.H{
+						{"key": "service.name", "value": gin.H{"stringValue": "gchat-gateway"}},
+					},
+				},
+				"scopeSpans": []gin.H{
+					{
+						"scope": gin.H{"name": "gchat/debug-trace"},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+
+	key := fmt.Sprintf("%s-%d", code, turn)
+	turnTraces.mu.Lock()
+	defer turnTraces.mu.Unlock()
+	if _, exists := turnTraces.traces[key]; !exists {
+		turnTraces.order = append(turnTraces.order, key)
+	}
+	turnTraces.traces[key] = trace
+	for len(turnTraces.order) > traceKeepMax {
+		delete(turnTraces.traces, turnTraces.order[0])
+		turnTraces.order = turnTraces.order[1:]
+	}
+	log.Printf("TRACE_RECORDED: turn=%s, trace_id=%s, spans=%d", key, traceID, len(spans))
+}
+
+// HandleDebugTrace serve il trace OTLP/JSON di un turno. La chiave è
+// <conversation_code>-<turno> come riportato nelle risposte chat
+// (es. GIAS-7F3K-2).
+// GET /api/debug/trace/:turn
+func HandleDebugTrace(c *gin.Context) {
+	key := c.Param("turn")
+
+	turnTraces.mu.Lock()
+	trace, ok := turnTraces.traces[key]
+	turnTraces.mu.Unlock()
+
+	if !ok {
+		AbortWithError(c, ErrNotFound, "trace non disponibile per il turno indicato (solo i turni recenti con execution tracking vengono conservati)")
+		return
+	}
+	c.JSON(200, trace)
+}
@@ -25,12 +25,15 @@ type WhisperResponse struct {
 }
 
 func TranscribeHandler(c *gin.Context) {
+	gaugeTranscribeStarted()
+	defer gaugeTranscribeFinished()
+
 	startHandler := time.Now()
 
 	file, err := c.FormFile("audio")
 	if err != nil {
 		log.Printf("ERROR_TRANSCRIBE: failed to receive audio file: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File audio mancante"})
+		AbortWithError(c, ErrInvalidRequest, "file audio mancante")
 		return
 	}
 
@@ -39,14 +42,21 @@ func TranscribeHandler(c *gin.Context) {
 		language = "it"
 	}
 
-	log.Printf("TRANSCRIBE_REQUEST: filename=%s, size=%d bytes, language=%s", file.Filename, file.Size, language)
+	// Formato di output: "" (plain-text JSON), "vtt" o "srt" (sottotitoli)
+	format := strings.ToLower(c.PostForm("format"))
+	if format != "" && format != "vtt" && format != "srt" {
+		AbortWithError(c, ErrInvalidParameter, "format non valido, ammessi: vtt, srt")
+		return
+	}
+
+	log.Printf("TRANSCRIBE_REQUEST: filename=%s, size=%d bytes, language=%s, format=%s", file.Filename, file.Size, language, format)
 	log.Printf("PROFILE_HANDLER_RECEIVE: %.2fms", time.Since(startHandler).Seconds()*1000)
 
 	startTempFile := time.Now()
 	tmpFile, err := os.CreateTemp("", "whisper-*.webm")
 	if err != nil {
 		log.Printf("ERROR_TRANSCRIBE: failed to create temp file: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Errore creazione file temporaneo"})
+		AbortWithError(c, ErrInternal, "errore creazione file temporaneo")
 		return
 	}
 	defer os.Remove(tmpFile.Name())
@@ -55,14 +65,14 @@ func TranscribeHandler(c *gin.Context) {
 	src, err := file.Open()
 	if err != nil {
 		log.Printf("ERROR_TRANSCRIBE: failed to open uploaded file: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Errore apertura file"})
+		AbortWithError(c, ErrInternal, "errore apertura file")
 		return
 	}
 	defer src.Close()
 
 	if _, err := io.Copy(tmpFile, src); err != nil {
 		log.Printf("ERROR_TRANSCRIBE: failed to save uploaded file: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Errore salvataggio file"})
+		AbortWithError(c, ErrInternal, "errore salvataggio file")
 		return
 	}
 
@@ -77,26 +87,54 @@ func TranscribeHandler(c *gin.Context) {
 	log.Printf("TRANSCRIBE_WHISPER: sending to %s", whisperURL)
 
 	startWhisper := time.Now()
-	transcription, err := callWhisper(tmpFile.Name(), whisperURL, language)
+	transcription, audioDuration, segments, err := callWhisper(tmpFile.Name(), whisperURL, language, format != "")
 	log.Printf("PROFILE_HANDLER_WHISPER_CALL: %.2fms (%.2fs)", time.Since(startWhisper).Seconds()*1000, time.Since(startWhisper).Seconds())
 	if err != nil {
 		log.Printf("ERROR_TRANSCRIBE: whisper call failed: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Errore trascrizione audio"})
+		AbortWithError(c, ErrTranscription, err.Error())
 		return
 	}
 
 	log.Printf("TRANSCRIBE_SUCCESS: text_length=%d", len(transcription))
 
+	// Filtro PII opzionale: maschera dati personali captati dal microfono
+	if LoadConfig().Transcription.MaskPII {
+		transcription = MaskTranscriptionPII(transcription)
+		for i := range segments {
+			segments[i].Text = MaskTranscriptionPII(segments[i].Text)
+		}
+	}
+
+	// Metering per-ASL: registra i minuti di audio trascritti (durata riportata dal server whisper)
+	MeterTranscriptionMinutes(c.PostForm("asl"), audioDuration/60.0)
+
 	totalDuration := time.Since(startHandler)
 	log.Printf("PROFILE_HANDLER_TOTAL: %.2fms (%.2fs)", totalDuration.Seconds()*1000, totalDuration.Seconds())
 
+	// Sottotitoli: documento VTT/SRT come file scaricabile (vedi subtitles.go)
+	if format != "" {
+		if len(segments) == 0 {
+			// Server whisper senza segmenti: un unico blocco sull'intera durata
+			segments = []WhisperSegment{{Start: 0, End: audioDuration, Text: transcription}}
+		}
+		var content, contentType string
+		if format == "vtt" {
+			content, contentType = FormatVTT(segments), "text/vtt; charset=utf-8"
+		} else {
+			content, contentType = FormatSRT(segments), "application/x-subrip; charset=utf-8"
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"trascrizione.%s\"", format))
+		c.Data(http.StatusOK, contentType, []byte(content))
+		return
+	}
+
 	c.JSON(http.StatusOK, TranscriptionResponse{
 		Text:     transcription,
 		Language: language,
 	})
 }
 
-func callWhisper(audioPath, whisperURL, language string) (string, error) {
+func callWhisper(audioPath, whisperURL, language string, wantSegments bool) (string, float64, []WhisperSegment, error) {
 	startTotal := time.Now()
 
 	// Use the passed whisperURL parameter instead of reading env again
@@ -112,7 +150,7 @@ func callWhisper(audioPath, whisperURL, language string) (string, error) {
 	startInference := time.Now()
 	audioFile, err := os.Open(audioPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open audio file: %w", err)
+		return "", 0, nil, fmt.Errorf("failed to open audio file: %w", err)
 	}
 	defer audioFile.Close()
 
@@ -120,53 +158,61 @@ func callWhisper(audioPath, whisperURL, language string) (string, error) {
 	writer := multipart.NewWriter(body)
 	part, err := writer.CreateFormFile("file", "audio.wav")
 	if err != nil {
-		return "", fmt.Errorf("failed to create form file: %w", err)
+		return "", 0, nil, fmt.Errorf("failed to create form file: %w", err)
 	}
 	if _, err := io.Copy(part, audioFile); err != nil {
-		return "", fmt.Errorf("failed to copy audio data: %w", err)
+		return "", 0, nil, fmt.Errorf("failed to copy audio data: %w", err)
 	}
 
 	// Add language parameter if provided
 	if language != "" {
 		languageField, err := writer.CreateFormField("language")
 		if err != nil {
-			return "", fmt.Errorf("failed to create language field: %w", err)
+			return "", 0, nil, fmt.Errorf("failed to create language field: %w", err)
 		}
 		if _, err := languageField.Write([]byte(language)); err != nil {
-			return "", fmt.Errorf("failed to write language field: %w", err)
+			return "", 0, nil, fmt.Errorf("failed to write language field: %w", err)
 		}
 		log.Printf("TRANSCRIBE_LANGUAGE: Sending language parameter: %s", language)
 	}
 
+	// Segmenti con timestamp per i formati sottotitoli (vedi subtitles.go)
+	if wantSegments {
+		if err := writer.WriteField("response_format", "verbose_json"); err != nil {
+			return "", 0, nil, fmt.Errorf("failed to write response_format field: %w", err)
+		}
+	}
+
 	writer.Close()
 
 	req, err := http.NewRequest("POST", serverURL, body)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", 0, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	log.Printf("TRANSCRIBE_FASTER_WHISPER: POST %s", serverURL)
 
-	client := &http.Client{Timeout: 20 * time.Second}
+	client := NewUpstreamClient("whisper", 20*time.Second)
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("faster-whisper server request failed: %w", err)
+		return "", 0, nil, fmt.Errorf("faster-whisper server request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("faster-whisper server returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return "", 0, nil, fmt.Errorf("faster-whisper server returned status %d: %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var result struct {
-		Text     string  `json:"text"`
-		Duration float64 `json:"duration"`
-		Language string  `json:"language"`
+		Text     string           `json:"text"`
+		Duration float64          `json:"duration"`
+		Language string           `json:"language"`
+		Segments []WhisperSegment `json:"segments"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return "", 0, nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	inferDuration := time.Since(startInference)
@@ -175,5 +221,5 @@ func callWhisper(audioPath, whisperURL, language string) (string, error) {
 	totalDuration := time.Since(startTotal)
 	log.Printf("PROFILE_TOTAL: %.2fms (%.2fs)", totalDuration.Seconds()*1000, totalDuration.Seconds())
 
-	return strings.TrimSpace(result.Text), nil
+	return strings.TrimSpace(result.Text), result.Duration, result.Segments, nil
 }
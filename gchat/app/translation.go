@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Traduzione inline delle risposte per utenti non italofoni (es. ispettori
+// in tirocinio dall'estero). Usa una API di traduzione configurabile
+// compatibile con LibreTranslate. Il testo originale resta disponibile
+// nella risposta tramite il campo original_message.
+
+type libreTranslateRequest struct {
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type libreTranslateResponse struct {
+	TranslatedText string `json:"translatedText"`
+}
+
+// translationNeeded verifica se la traduzione va applicata per la lingua richiesta
+func translationNeeded(cfg *Config, targetLang string) bool {
+	if !cfg.Translation.Enabled || targetLang == "" {
+		return false
+	}
+	source := cfg.Translation.SourceLang
+	if source == "" {
+		source = "it"
+	}
+	return targetLang != source
+}
+
+// TranslateText traduce il testo verso la lingua target usando l'API configurata.
+// In caso di errore ritorna il testo originale e l'errore (graceful degradation).
+func TranslateText(cfg *Config, text, targetLang string) (string, error) {
+	source := cfg.Translation.SourceLang
+	if source == "" {
+		source = "it"
+	}
+	return translateBetween(cfg, text, source, targetLang)
+}
+
+// translateBetween traduce il testo tra due lingue esplicite (usata anche
+// dalla guardia lingua, dove la sorgente è quella rilevata e non quella
+// di config). In caso di errore ritorna il testo originale e l'errore.
+func translateBetween(cfg *Config, text, sourceLang, targetLang string) (string, error) {
+	if cfg.Translation.URL == "" {
+		return text, fmt.Errorf("translation API URL non configurata")
+	}
+	source := sourceLang
+
+	payload := libreTranslateRequest{
+		Q:      text,
+		Source: source,
+		Target: targetLang,
+		Format: "text",
+		APIKey: os.Getenv("TRANSLATION_API_KEY"), // API key solo via env var
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return text, fmt.Errorf("error marshaling translation request: %v", err)
+	}
+
+	timeout := cfg.Translation.Timeout
+	if timeout <= 0 {
+		timeout = 10
+	}
+	client := &http.Client{Timeout: time.Duration(timeout) * time.Second}
+
+	start := time.Now()
+	resp, err := client.Post(cfg.Translation.URL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		log.Printf("TRANSLATE_ERROR: request failed - target=%s, error=%v", targetLang, err)
+		return text, fmt.Errorf("error calling translation API: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return text, fmt.Errorf("error reading translation response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("TRANSLATE_ERROR: status=%d, body=%s", resp.StatusCode, string(body))
+		return text, fmt.Errorf("translation API returned status %d", resp.StatusCode)
+	}
+
+	var ltResp libreTranslateResponse
+	if err := json.Unmarshal(body, &ltResp); err != nil {
+		return text, fmt.Errorf("error unmarshaling translation response: %v", err)
+	}
+
+	log.Printf("TRANSLATE_SUCCESS: target=%s, original_len=%d, translated_len=%d, duration=%v",
+		targetLang, len(text), len(ltResp.TranslatedText), time.Since(start))
+	return ltResp.TranslatedText, nil
+}
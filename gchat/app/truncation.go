@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Troncamento lato server delle risposte molto lunghe: alcune risposte
+// contengono tabelle markdown enormi che bloccano i PC datati negli uffici
+// ASL. Oltre la soglia configurata la risposta viene troncata su un confine
+// di riga, marcata con truncated=true e accompagnata da un handle di
+// continuazione con cui la UI recupera il resto on demand.
+
+const continuationTTL = 10 * time.Minute
+
+type continuationEntry struct {
+	remainder string
+	expiresAt time.Time
+}
+
+type continuationStore struct {
+	entries map[string]*continuationEntry
+	mu      sync.Mutex
+}
+
+var continuations = &continuationStore{
+	entries: make(map[string]*continuationEntry),
+}
+
+// responseMaxChars ritorna la soglia di troncamento (0 = disabilitato)
+func responseMaxChars(config *Config) int {
+	if config.ResponseLimits.MaxChars > 0 {
+		return config.ResponseLimits.MaxChars
+	}
+	return 0
+}
+
+// newContinuationHandle genera un handle opaco non indovinabile
+func newContinuationHandle() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(buf)
+}
+
+// TruncateResponse tronca il testo alla soglia indicata su un confine di
+// riga e registra il resto sotto un handle di continuazione. Ritorna il
+// testo visibile, l'handle (vuoto se non troncato) e il flag truncated.
+func TruncateResponse(text string, maxChars int) (string, string, bool) {
+	if maxChars <= 0 || len([]rune(text)) <= maxChars {
+		return text, "", false
+	}
+
+	runes := []rune(text)
+	cut := maxChars
+	// Preferisci un confine di riga: non spezzare a metà una riga di tabella
+	if idx := strings.LastIndex(string(runes[:maxChars]), "\n"); idx > maxChars/2 {
+		cut = len([]rune(string(runes[:maxChars])[:idx]))
+	}
+
+	visible := string(runes[:cut])
+	remainder := string(runes[cut:])
+
+	handle := newContinuationHandle()
+	if handle == "" {
+		// Generazione handle fallita: meglio la risposta intera che perderne metà
+		return text, "", false
+	}
+
+	continuations.mu.Lock()
+	continuations.entries[handle] = &continuationEntry{
+		remainder: remainder,
+		expiresAt: time.Now().Add(continuationTTL),
+	}
+	continuations.mu.Unlock()
+
+	time.AfterFunc(continuationTTL, func() {
+		continuations.mu.Lock()
+		delete(continuations.entries, handle)
+		continuations.mu.Unlock()
+	})
+
+	log.Printf("TRUNCATE: risposta troncata a %d caratteri (resto=%d) - handle=%s",
+		cut, len([]rune(remainder)), handle)
+
+	return visible, handle, true
+}
+
+// HandleContinuation restituisce la parte rimanente di una risposta
+// troncata; se anche il resto supera la soglia viene troncato a sua volta
+// con un nuovo handle (paginazione a catena)
+// GET /api/continuation/:handle
+func HandleContinuation(c *gin.Context) {
+	handle := c.Param("handle")
+
+	continuations.mu.Lock()
+	entry, ok := continuations.entries[handle]
+	if ok {
+		delete(continuations.entries, handle)
+	}
+	continuations.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		AbortWithError(c, ErrNotFound, "handle di continuazione scaduto o inesistente")
+		return
+	}
+
+	config := LoadConfig()
+	visible, nextHandle, truncated := TruncateResponse(entry.remainder, responseMaxChars(config))
+
+	log.Printf("TRUNCATE_CONTINUATION: handle=%s, chars=%d, truncated=%v", handle, len(visible), truncated)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":              "success",
+		"message":             visible,
+		"truncated":           truncated,
+		"continuation_handle": nextHandle,
+	})
+}
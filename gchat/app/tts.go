@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sintesi vocale (TTS) con metadati di sincronizzazione a livello di
+// parola: il gateway inoltra il testo al backend TTS configurato e, se il
+// backend li fornisce, passa al frontend i timing parola-per-parola così
+// la UI può evidenziare il testo mentre viene letto. Lo schema dei timing
+// è definito qui ed è il contratto stabile verso il JS, qualunque sia il
+// motore TTS dietro.
+
+// TTSConfig configura il backend di sintesi (sezione tts)
+type TTSConfig struct {
+	Enabled bool   `json:"enabled"`
+	URL     string `json:"url"`     // endpoint di sintesi (POST /synthesize)
+	Timeout int    `json:"timeout"` // secondi (default 30)
+}
+
+// WordTiming è il timing di una singola parola nell'audio sintetizzato
+type WordTiming struct {
+	Word    string `json:"word"`
+	StartMs int    `json:"start_ms"`
+	EndMs   int    `json:"end_ms"`
+}
+
+// ttsBackendResponse è la risposta attesa dal backend TTS (i timing sono
+// opzionali: non tutti i motori li forniscono)
+type ttsBackendResponse struct {
+	Audio    string       `json:"audio"` // base64
+	MimeType string       `json:"mime_type"`
+	Timings  []WordTiming `json:"timings,omitempty"`
+}
+
+// HandleTTS sintetizza un testo e ritorna audio più eventuali timing
+// POST /api/tts {"text": "...", "voice": "..."}
+func HandleTTS(c *gin.Context) {
+	config := LoadConfig()
+	if !config.TTS.Enabled || config.TTS.URL == "" {
+		AbortWithError(c, ErrNotFound, "sintesi vocale non abilitata")
+		return
+	}
+
+	var req struct {
+		Text  string `json:"text"`
+		Voice string `json:"voice,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		AbortWithError(c, ErrInvalidParameter, "campo text mancante")
+		return
+	}
+
+	timeout := config.TTS.Timeout
+	if timeout <= 0 {
+		timeout = 30
+	}
+
+	payload, err := json.Marshal(map[string]string{"text": req.Text, "voice": req.Voice})
+	if err != nil {
+		AbortWithError(c, ErrInternal, err.Error())
+		return
+	}
+
+	client := NewUpstreamClient("tts", time.Duration(timeout)*time.Second)
+	upstreamReq, err := http.NewRequest("POST", config.TTS.URL+"/synthesize", strings.NewReader(string(payload)))
+	if err != nil {
+		AbortWithError(c, ErrInternal, err.Error())
+		return
+	}
+	upstreamReq.Header.Set("Content-Type", "application/json")
+	ApplyUpstreamHeaders(upstreamReq, nil)
+
+	start := time.Now()
+	resp, err := client.Do(upstreamReq)
+	if err != nil {
+		log.Printf("TTS_ERROR: backend non raggiungibile - error=%v", err)
+		AbortWithError(c, ErrBackendUnavailable, err.Error())
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Printf("TTS_ERROR: backend status=%d, body=%s", resp.StatusCode, string(body))
+		AbortWithError(c, ErrBackendError, "sintesi vocale fallita")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		AbortWithError(c, ErrBackendError, err.Error())
+		return
+	}
+	var ttsResp ttsBackendResponse
+	if err := json.Unmarshal(body, &ttsResp); err != nil {
+		AbortWithError(c, ErrBackendError, "risposta TTS non valida")
+		return
+	}
+
+	log.Printf("TTS_SUCCESS: text_len=%d, timings=%d, duration=%v",
+		len(req.Text), len(ttsResp.Timings), time.Since(start))
+
+	// Passthrough dei timing così come forniti dal motore (omessi se assenti)
+	out := gin.H{
+		"status":    "success",
+		"audio":     ttsResp.Audio,
+		"mime_type": ttsResp.MimeType,
+	}
+	if len(ttsResp.Timings) > 0 {
+		out["timings"] = ttsResp.Timings
+	}
+	c.JSON(http.StatusOK, out)
+}
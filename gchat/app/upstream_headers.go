@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// Header verso gli upstream applicati in modo uniforme: finora
+// User-Agent/X-Source comparivano solo nei curl generati per il debug ma
+// non sulle richieste reali. La sezione upstream_headers di config.json
+// permette header statici e header derivati dai campi identità del
+// contesto (user_id, asl, codice_fiscale, username), applicati a ogni
+// chiamata verso il backend: chat, parse, health, proxy e fetch di stato.
+
+// UpstreamHeadersConfig definisce gli header outbound in config.json
+type UpstreamHeadersConfig struct {
+	// Static: header con valore fisso (es. "X-Source": "gchat")
+	Static map[string]string `json:"static"`
+	// Identity: header valorizzati dal campo identità indicato
+	// (es. "X-Gias-User": "user_id")
+	Identity map[string]string `json:"identity"`
+}
+
+// identityHeaderFields: campi del contesto esportabili come header
+var identityHeaderFields = map[string]bool{
+	"user_id":        true,
+	"asl":            true,
+	"asl_id":         true,
+	"codice_fiscale": true,
+	"username":       true,
+	"uoc":            true,
+}
+
+// ApplyUpstreamHeaders imposta gli header configurati sulla richiesta.
+// Il contesto può essere nil (chiamate senza identità, es. health check).
+func ApplyUpstreamHeaders(req *http.Request, context map[string]interface{}) {
+	config := LoadConfig()
+
+	// User-Agent di default, sovrascrivibile dagli header statici
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "GChat/1.0")
+	}
+
+	for name, value := range config.UpstreamHeaders.Static {
+		if name == "" || value == "" {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+
+	if context == nil {
+		return
+	}
+	for name, field := range config.UpstreamHeaders.Identity {
+		if !identityHeaderFields[field] {
+			log.Printf("UPSTREAM_HEADERS_WARNING: campo identità non ammesso: %s", field)
+			continue
+		}
+		if value, ok := context[field].(string); ok && value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+}
+
+// upstreamGet esegue una GET verso l'upstream con gli header configurati
+func upstreamGet(client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	ApplyUpstreamHeaders(req, nil)
+	return client.Do(req)
+}
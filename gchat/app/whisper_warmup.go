@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/binary"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Warm-up del server whisper: la prima trascrizione della giornata paga
+// 30+ secondi di caricamento modello. All'avvio il gateway invia una
+// trascrizione di silenzio per forzare il preload, poi un ping periodico
+// tiene il modello caldo. Lo stato warm è esposto su /readyz e
+// /api/capabilities.
+
+const (
+	whisperDefaultKeepWarmMinutes = 10
+	whisperWarmupSampleRate       = 16000
+)
+
+type whisperWarmStatus struct {
+	Warm     bool
+	LastPing time.Time
+	LastErr  string
+	mu       sync.RWMutex
+}
+
+var whisperState = &whisperWarmStatus{}
+
+// WhisperStatus ritorna lo stato warm corrente del server whisper
+func WhisperStatus() (warm bool, lastPing time.Time, lastErr string) {
+	whisperState.mu.RLock()
+	defer whisperState.mu.RUnlock()
+	return whisperState.Warm, whisperState.LastPing, whisperState.LastErr
+}
+
+// silentWAV genera un WAV PCM di silenzio della durata indicata (il
+// payload minimo che whisper accetta per caricare il modello)
+func silentWAV(duration time.Duration) []byte {
+	samples := int(float64(whisperWarmupSampleRate) * duration.Seconds())
+	dataLen := samples * 2 // 16 bit mono
+
+	buf := make([]byte, 44+dataLen)
+	copy(buf[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(36+dataLen))
+	copy(buf[8:12], "WAVE")
+	copy(buf[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(buf[16:20], 16)
+	binary.LittleEndian.PutUint16(buf[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(buf[22:24], 1) // mono
+	binary.LittleEndian.PutUint32(buf[24:28], whisperWarmupSampleRate)
+	binary.LittleEndian.PutUint32(buf[28:32], whisperWarmupSampleRate*2)
+	binary.LittleEndian.PutUint16(buf[32:34], 2)
+	binary.LittleEndian.PutUint16(buf[34:36], 16)
+	copy(buf[36:40], "data")
+	binary.LittleEndian.PutUint32(buf[40:44], uint32(dataLen))
+	return buf
+}
+
+// whisperPing invia la trascrizione di silenzio e aggiorna lo stato warm
+func whisperPing() {
+	whisperURL := os.Getenv("WHISPER_URL")
+	if whisperURL == "" {
+		whisperURL = "http://localhost:8090/inference"
+	}
+
+	tmpFile, err := os.CreateTemp("", "warmup-*.wav")
+	if err != nil {
+		log.Printf("WHISPER_WARMUP_ERROR: file temporaneo: %v", err)
+		return
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(silentWAV(200 * time.Millisecond)); err != nil {
+		tmpFile.Close()
+		log.Printf("WHISPER_WARMUP_ERROR: scrittura WAV: %v", err)
+		return
+	}
+	tmpFile.Close()
+
+	start := time.Now()
+	_, _, _, err = callWhisper(tmpFile.Name(), whisperURL, "it", false)
+
+	whisperState.mu.Lock()
+	defer whisperState.mu.Unlock()
+	whisperState.LastPing = time.Now()
+	if err != nil {
+		whisperState.Warm = false
+		whisperState.LastErr = err.Error()
+		log.Printf("WHISPER_WARMUP_ERROR: ping fallito: %v", err)
+		return
+	}
+	whisperState.Warm = true
+	whisperState.LastErr = ""
+	log.Printf("WHISPER_WARMUP_OK: modello caldo in %.1fs", time.Since(start).Seconds())
+}
+
+// StartWhisperKeepWarm avvia warm-up iniziale e ping periodico. Da
+// chiamare in una goroutine all'avvio; no-op se la trascrizione è spenta.
+func StartWhisperKeepWarm(config *Config) {
+	if !config.Transcription.Enabled {
+		log.Printf("WHISPER_WARMUP_SKIP: trascrizione disabilitata")
+		return
+	}
+
+	interval := time.Duration(config.Transcription.KeepWarmMinutes) * time.Minute
+	if interval <= 0 {
+		interval = whisperDefaultKeepWarmMinutes * time.Minute
+	}
+
+	whisperPing()
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		whisperPing()
+	}
+}
+
+// HandleReadyz risponde con lo stato di prontezza del gateway
+// GET /readyz
+func HandleReadyz(c *gin.Context) {
+	backendCircuit, backendLastCheck := backendState()
+	whisperWarm, whisperLastPing, whisperErr := WhisperStatus()
+	dbDetail, dbUp := DatabaseHealth()
+
+	// DB giù non toglie la readiness: la chat funziona in modalità
+	// degradata, solo cronologia e ricerca vengono sospese (db_health.go)
+	ready := backendCircuit != "open"
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, gin.H{
+		"ready":    ready,
+		"degraded": !dbUp,
+		"backend": gin.H{
+			"circuit":    backendCircuit,
+			"last_check": backendLastCheck.Unix(),
+		},
+		"database": dbDetail,
+		"whisper": gin.H{
+			"enabled":    LoadConfig().Transcription.Enabled,
+			"warm":       whisperWarm,
+			"last_ping":  whisperLastPing.Unix(),
+			"last_error": whisperErr,
+		},
+	})
+}
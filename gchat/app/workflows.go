@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Workflow guidati: le verifiche ricorrenti (es. "Verifica stabilimento")
+// richiedono sempre la stessa sequenza di domande e gli utenti meno esperti
+// si perdono a metà. Le definizioni stanno lato server (data/workflows.json
+// con default incorporati): /api/workflows le elenca, /api/workflows/:id/start
+// avvia il percorso e da lì ogni risposta dell'utente riempie lo step
+// corrente; la domanda composta dal template va al backend con i metadati
+// del workflow (id, step) e la risposta riporta il prompt dello step
+// successivo.
+
+const (
+	workflowsFile  = "data/workflows.json"
+	workflowRunTTL = 10 * time.Minute
+)
+
+// WorkflowStep è un passo del percorso guidato
+type WorkflowStep struct {
+	Prompt        string `json:"prompt"`         // domanda mostrata all'utente
+	Slot          string `json:"slot"`           // placeholder riempito dalla risposta
+	QueryTemplate string `json:"query_template"` // domanda composta per il backend ({slot})
+}
+
+// WorkflowDef è una definizione di workflow guidato
+type WorkflowDef struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Steps       []WorkflowStep `json:"steps"`
+}
+
+// workflowRun è lo stato di un percorso in corso per un sender
+type workflowRun struct {
+	WorkflowID string
+	Step       int
+	Answers    map[string]string
+	StartedAt  time.Time
+}
+
+type workflowState struct {
+	defs   []WorkflowDef
+	runs   map[string]*workflowRun
+	loaded bool
+	mu     sync.Mutex
+}
+
+var workflows = &workflowState{runs: map[string]*workflowRun{}}
+
+// defaultWorkflows: percorsi incorporati usati in assenza del file
+func defaultWorkflows() []WorkflowDef {
+	return []WorkflowDef{
+		{
+			ID:          "verifica_stabilimento",
+			Title:       "Verifica stabilimento",
+			Description: "Inquadra uno stabilimento: anagrafica, controlli eseguiti e priorità",
+			Steps: []WorkflowStep{
+				{
+					Prompt:        "Qual è il numero di riconoscimento o la ragione sociale dello stabilimento?",
+					Slot:          "stabilimento",
+					QueryTemplate: "dammi le informazioni sullo stabilimento {stabilimento}",
+				},
+				{
+					Prompt:        "Quale piano di monitoraggio vuoi verificare per questo stabilimento?",
+					Slot:          "piano",
+					QueryTemplate: "quali controlli del piano {piano} riguardano lo stabilimento {stabilimento}?",
+				},
+			},
+		},
+		{
+			ID:          "ritardi_piano",
+			Title:       "Ritardi di un piano",
+			Description: "Verifica le attività in ritardo di un piano di monitoraggio",
+			Steps: []WorkflowStep{
+				{
+					Prompt:        "Di quale piano vuoi verificare i ritardi (es. A22)?",
+					Slot:          "piano",
+					QueryTemplate: "quali attività del piano {piano} sono in ritardo?",
+				},
+			},
+		},
+	}
+}
+
+func (s *workflowState) ensureLoaded() {
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	s.defs = defaultWorkflows()
+
+	data, err := os.ReadFile(workflowsFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("WORKFLOW_LOAD_ERROR: %v", err)
+		}
+		return
+	}
+	var defs []WorkflowDef
+	if err := json.Unmarshal(data, &defs); err != nil {
+		log.Printf("WORKFLOW_LOAD_ERROR: parse %s: %v", workflowsFile, err)
+		return
+	}
+	if len(defs) > 0 {
+		s.defs = defs
+		log.Printf("WORKFLOW_LOADED: %d workflow da %s", len(defs), workflowsFile)
+	}
+}
+
+// workflowByID cerca la definizione (chiamare con il lock)
+func (s *workflowState) byID(id string) *WorkflowDef {
+	for i := range s.defs {
+		if s.defs[i].ID == id {
+			return &s.defs[i]
+		}
+	}
+	return nil
+}
+
+// fillWorkflowTemplate sostituisce i placeholder {slot} con le risposte
+func fillWorkflowTemplate(template string, answers map[string]string) string {
+	out := template
+	for slot, answer := range answers {
+		out = strings.ReplaceAll(out, "{"+slot+"}", answer)
+	}
+	return out
+}
+
+// WorkflowObserveAnswer consuma il messaggio come risposta allo step
+// corrente del workflow attivo del sender. Ritorna la domanda composta per
+// il backend, i metadati del workflow e il prompt dello step successivo
+// (vuoto all'ultimo step); active=false se il sender non ha workflow attivi.
+func WorkflowObserveAnswer(sender, message string) (query string, meta map[string]interface{}, nextPrompt string, active bool) {
+	workflows.mu.Lock()
+	defer workflows.mu.Unlock()
+	workflows.ensureLoaded()
+
+	run, ok := workflows.runs[sender]
+	if !ok {
+		return "", nil, "", false
+	}
+	if time.Since(run.StartedAt) > workflowRunTTL {
+		delete(workflows.runs, sender)
+		log.Printf("WORKFLOW_EXPIRED: sender=%s, workflow=%s", sender, run.WorkflowID)
+		return "", nil, "", false
+	}
+	def := workflows.byID(run.WorkflowID)
+	if def == nil || run.Step >= len(def.Steps) {
+		delete(workflows.runs, sender)
+		return "", nil, "", false
+	}
+
+	step := def.Steps[run.Step]
+	run.Answers[step.Slot] = strings.TrimSpace(message)
+
+	query = fillWorkflowTemplate(step.QueryTemplate, run.Answers)
+	if query == "" {
+		query = message
+	}
+	meta = map[string]interface{}{
+		"workflow_id": def.ID,
+		"step":        run.Step + 1,
+		"total_steps": len(def.Steps),
+	}
+
+	run.Step++
+	if run.Step >= len(def.Steps) {
+		delete(workflows.runs, sender)
+		log.Printf("WORKFLOW_COMPLETED: sender=%s, workflow=%s", sender, def.ID)
+	} else {
+		nextPrompt = def.Steps[run.Step].Prompt
+	}
+	return query, meta, nextPrompt, true
+}
+
+// HandleListWorkflows elenca i workflow guidati disponibili
+// GET /api/workflows
+func HandleListWorkflows(c *gin.Context) {
+	workflows.mu.Lock()
+	workflows.ensureLoaded()
+	defs := make([]gin.H, 0, len(workflows.defs))
+	for _, def := range workflows.defs {
+		defs = append(defs, gin.H{
+			"id":          def.ID,
+			"title":       def.Title,
+			"description": def.Description,
+			"steps":       len(def.Steps),
+		})
+	}
+	workflows.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"workflows": defs})
+}
+
+// HandleStartWorkflow avvia un workflow per il sender e ritorna il primo prompt
+// POST /api/workflows/:id/start {"sender": "..."}
+func HandleStartWorkflow(c *gin.Context) {
+	var req struct {
+		Sender string `json:"sender"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		AbortWithError(c, ErrInvalidRequest, err.Error())
+		return
+	}
+	if req.Sender == "" {
+		req.Sender = "user"
+	}
+
+	workflows.mu.Lock()
+	workflows.ensureLoaded()
+	def := workflows.byID(c.Param("id"))
+	if def == nil || len(def.Steps) == 0 {
+		workflows.mu.Unlock()
+		AbortWithError(c, ErrNotFound, "workflow non trovato")
+		return
+	}
+	workflows.runs[req.Sender] = &workflowRun{
+		WorkflowID: def.ID,
+		Answers:    map[string]string{},
+		StartedAt:  time.Now(),
+	}
+	workflows.mu.Unlock()
+
+	log.Printf("WORKFLOW_STARTED: sender=%s, workflow=%s", req.Sender, def.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"workflow_id": def.ID,
+		"title":       def.Title,
+		"step":        1,
+		"total_steps": len(def.Steps),
+		"prompt":      def.Steps[0].Prompt,
+	})
+}
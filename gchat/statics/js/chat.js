@@ -120,6 +120,29 @@ class ChatBot {
         localStorage.setItem('theme', isDark ? 'dark' : 'light');
     }
 
+    // Cambio ASL attiva per supervisori multi-ASL: aggiorna sessione
+    // (POST /api/scope), parametri locali e chip nell'header
+    async switchScope(asl) {
+        const response = await fetch(window.basePath + '/api/scope', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ asl: asl })
+        });
+        const data = await response.json();
+        if (!response.ok) {
+            throw new Error(data.error || 'cambio ASL non riuscito');
+        }
+        if (window.queryParams) {
+            window.queryParams.asl_name = data.asl;
+            window.queryParams.asl_id = null;
+        }
+        const chip = document.querySelector('.user-asl');
+        if (chip) {
+            chip.textContent = 'ASL ' + data.asl;
+        }
+        return data;
+    }
+
     initLogoDebugLink() {
         const giasLogo = document.getElementById('giasLogo');
         if (giasLogo) {